@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/thilakshekharshriyan/playflow/internal/api"
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+	"github.com/thilakshekharshriyan/playflow/internal/platform/config"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logger, err := platform.NewLogger(cfg.Log)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+	logger.Info("Loaded configuration", zap.Any("config", cfg.Redacted()))
+
+	timeout := cfg.API.Timeout
+
+	db, err := platform.NewDatabase(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	idempotencyStore := idempotency.NewPostgresStore(db)
+	if cfg.Redis.URL != "" {
+		redisOpts, err := redis.ParseURL(cfg.Redis.URL)
+		if err != nil {
+			logger.Fatal("Invalid REDIS_URL", zap.Error(err))
+		}
+		redisOpts.MaxRetries = cfg.Redis.MaxRetries
+		redisOpts.PoolSize = cfg.Redis.PoolSize
+		redisClient := redis.NewClient(redisOpts)
+		defer redisClient.Close()
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			logger.Warn("Redis unavailable, idempotency falls back to Postgres", zap.Error(err))
+		}
+		idempotencyStore = idempotency.NewTieredStore(idempotency.NewRedisStore(redisClient), idempotencyStore)
+	}
+
+	paymentsSvc := payments.NewService(payments.NewPostgresRepository(db))
+	handler := api.NewServer(paymentsSvc, logger, api.Config{
+		Timeout: timeout,
+		// Requests cannot outlive the API timeout, so a reservation older than
+		// twice that was abandoned.
+		Idempotency: idempotency.NewMiddleware(idempotencyStore, cfg.Idempotency.TTL,
+			idempotency.WithLockTimeout(2*timeout),
+			idempotency.WithIgnoredFields(cfg.Idempotency.IgnoredFields...),
+		),
+	})
+
+	srv := &http.Server{
+		Addr:              cfg.API.Addr(),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout + 5*time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("API server listening", zap.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+			cancel()
+		}
+	}()
+
+	err = platform.WaitForShutdown(ctx, cfg.API.ShutdownTimeout, func() error {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.API.ShutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	})
+	select {
+	case serveErr := <-serveErr:
+		err = errors.Join(serveErr, err)
+	default:
+	}
+	if err != nil {
+		logger.Error("API server stopped with error", zap.Error(err))
+		logger.Sync()
+		os.Exit(1)
+	}
+	logger.Info("API server stopped")
+}
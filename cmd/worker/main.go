@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+	"github.com/thilakshekharshriyan/playflow/internal/platform/config"
+	"github.com/thilakshekharshriyan/playflow/internal/worker"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logger, err := platform.NewLogger(cfg.Log)
+	if err != nil {
+		panic(err)
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+	logger.Info("Loaded configuration", zap.Any("config", cfg.Redacted()))
+
+	pruneConfig := worker.PruneConfig{
+		Interval:             cfg.Worker.PruneInterval,
+		BatchSize:            cfg.Worker.PruneBatchSize,
+		IdempotencyRetention: cfg.Worker.IdempotencyRetention,
+		InboxRetention:       cfg.Worker.InboxRetention,
+		MaxFailures:          cfg.Worker.PruneMaxFailures,
+	}
+
+	db, err := platform.NewDatabase(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	defer db.Close()
+
+	pruner := worker.NewPruner(db, pruneConfig, logger, worker.WithPruneMetrics(newPruneCounters()))
+
+	metricsServer := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Metrics.PrometheusPort),
+		Handler: expvar.Handler(),
+	}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server failed", zap.Error(err))
+		}
+	}()
+
+	// A pruner failure cancels ctx, which ends the wait below like a signal
+	// does, so the worker exits instead of idling without pruning.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() {
+		err := pruner.Run(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		if err != nil {
+			logger.Error("Pruner stopped", zap.Error(err))
+			cancel()
+		}
+		runErr <- err
+	}()
+
+	err = platform.WaitForShutdown(ctx, cfg.Worker.ShutdownTimeout, func() error {
+		cancel()
+		err := <-runErr
+		metricsServer.Close()
+		return err
+	})
+	removed := pruner.Removed()
+	logger.Info("Worker stopped",
+		zap.Int64("pruned_idempotency_records", removed.IdempotencyRecords),
+		zap.Int64("pruned_inbox_events", removed.InboxEvents),
+	)
+	if err != nil {
+		logger.Error("Worker failed", zap.Error(err))
+		logger.Sync()
+		os.Exit(1)
+	}
+}
+
+// pruneCounters publishes the pruner's running totals as the expvar map
+// "pruner", served by the metrics server.
+type pruneCounters struct {
+	vars *expvar.Map
+}
+
+func newPruneCounters() pruneCounters {
+	return pruneCounters{vars: expvar.NewMap("pruner")}
+}
+
+func (c pruneCounters) Pruned(result worker.PruneResult) {
+	c.vars.Add("idempotency_records_removed", result.IdempotencyRecords)
+	c.vars.Add("inbox_events_removed", result.InboxEvents)
+	c.vars.Add("passes", 1)
+}
+
+func (c pruneCounters) Failed(error) {
+	c.vars.Add("failures", 1)
+}
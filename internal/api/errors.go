@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+)
+
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Param     string            `json:"param,omitempty"`
+	Retryable bool              `json:"retryable"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// invalidRequest reports a problem with the request itself, found before it
+// reaches the payments service.
+func invalidRequest(param, message string) error {
+	return platform.InvalidParam(payments.ErrInvalidRequest, param, message)
+}
+
+// writeError maps err onto the API error model and writes it. Server errors
+// are logged with their cause, which is never sent to the client. Retryable
+// errors are flagged so the idempotency middleware does not store them.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := payments.ToAPIError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		s.logger.Error("Request failed",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("correlation_id", platform.GetCorrelationID(r.Context())),
+			zap.String("code", apiErr.Code),
+			zap.Error(err),
+		)
+	}
+	if apiErr.Retryable {
+		w.Header().Set(idempotency.RetryableHeader, "true")
+	}
+	writeJSON(w, apiErr.Status, errorBody{Error: errorDetail{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Param:     apiErr.Param,
+		Retryable: apiErr.Retryable,
+		Details:   apiErr.Details,
+	}})
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+)
+
+type intentResponse struct {
+	ID                string    `json:"id"`
+	MerchantID        string    `json:"merchant_id"`
+	Amount            int64     `json:"amount"`
+	Currency          string    `json:"currency"`
+	Status            string    `json:"status"`
+	Version           int64     `json:"version"`
+	Provider          string    `json:"provider,omitempty"`
+	ProviderPaymentID string    `json:"provider_payment_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func newIntentResponse(intent *payments.PaymentIntent) intentResponse {
+	return intentResponse{
+		ID:                intent.ID,
+		MerchantID:        intent.MerchantID,
+		Amount:            intent.Amount,
+		Currency:          intent.Currency,
+		Status:            strings.ToLower(string(intent.State)),
+		Version:           intent.Version,
+		Provider:          intent.SelectedProvider,
+		ProviderPaymentID: intent.ProviderPaymentID,
+		CreatedAt:         intent.CreatedAt,
+		UpdatedAt:         intent.UpdatedAt,
+	}
+}
+
+type listResponse struct {
+	Data []intentResponse `json:"data"`
+}
+
+type createIntentBody struct {
+	MerchantID string `json:"merchant_id"`
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+}
+
+type amountBody struct {
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+type cancelBody struct {
+	Reason string `json:"reason"`
+}
+
+// decodeBody decodes an optional JSON request body into v. An empty body
+// leaves v untouched.
+func decodeBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return invalidRequest("", "request body too large")
+	}
+	return invalidRequest("", "malformed JSON body")
+}
+
+// loadIntent fetches the intent named in the path. Intents owned by other
+// merchants than the request's are reported as not found.
+func (s *Server) loadIntent(w http.ResponseWriter, r *http.Request) (*payments.PaymentIntent, bool) {
+	intent, err := s.payments.GetIntent(r.Context(), r.PathValue("id"))
+	if err == nil && intent.MerchantID != platform.GetMerchantID(r.Context()) {
+		err = payments.ErrIntentNotFound
+	}
+	if err != nil {
+		s.writeError(w, r, err)
+		return nil, false
+	}
+	return intent, true
+}
+
+func (s *Server) handleCreateIntent(w http.ResponseWriter, r *http.Request) {
+	var body createIntentBody
+	if err := decodeBody(r, &body); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	merchantID := platform.GetMerchantID(r.Context())
+	if body.MerchantID != "" && body.MerchantID != merchantID {
+		s.writeError(w, r, invalidRequest("merchant_id", "merchant_id does not match "+MerchantIDHeader))
+		return
+	}
+
+	intent, err := s.payments.CreateIntent(r.Context(), payments.CreateIntentRequest{
+		MerchantID:     merchantID,
+		Amount:         body.Amount,
+		Currency:       body.Currency,
+		IdempotencyKey: r.Header.Get(IdempotencyKeyHeader),
+	})
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newIntentResponse(intent))
+}
+
+func (s *Server) handleListIntents(w http.ResponseWriter, r *http.Request) {
+	merchantID := platform.GetMerchantID(r.Context())
+
+	var limit int
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			s.writeError(w, r, invalidRequest("limit", "limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	intents, err := s.payments.ListIntents(r.Context(), merchantID, limit)
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+
+	resp := listResponse{Data: make([]intentResponse, 0, len(intents))}
+	for _, intent := range intents {
+		resp.Data = append(resp.Data, newIntentResponse(intent))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetIntent(w http.ResponseWriter, r *http.Request) {
+	intent, ok := s.loadIntent(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newIntentResponse(intent))
+}
+
+func (s *Server) handleAuthorizeIntent(w http.ResponseWriter, r *http.Request) {
+	intent, ok := s.loadIntent(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := s.payments.AuthorizeIntent(r.Context(), payments.AuthorizeRequest{
+		IntentID:       intent.ID,
+		IdempotencyKey: r.Header.Get(IdempotencyKeyHeader),
+	})
+	s.writeIntent(w, r, updated, err)
+}
+
+func (s *Server) handleCaptureIntent(w http.ResponseWriter, r *http.Request) {
+	var body amountBody
+	if err := decodeBody(r, &body); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	intent, ok := s.loadIntent(w, r)
+	if !ok {
+		return
+	}
+	if body.Amount == 0 {
+		body.Amount = intent.Amount
+	}
+
+	updated, err := s.payments.CaptureIntent(r.Context(), payments.CaptureRequest{
+		IntentID:       intent.ID,
+		Amount:         body.Amount,
+		IdempotencyKey: r.Header.Get(IdempotencyKeyHeader),
+	})
+	s.writeIntent(w, r, updated, err)
+}
+
+func (s *Server) handleRefundIntent(w http.ResponseWriter, r *http.Request) {
+	var body amountBody
+	if err := decodeBody(r, &body); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	intent, ok := s.loadIntent(w, r)
+	if !ok {
+		return
+	}
+	if body.Amount == 0 {
+		body.Amount = intent.Amount
+	}
+
+	updated, err := s.payments.RefundIntent(r.Context(), payments.RefundRequest{
+		IntentID:       intent.ID,
+		Amount:         body.Amount,
+		Reason:         body.Reason,
+		IdempotencyKey: r.Header.Get(IdempotencyKeyHeader),
+	})
+	s.writeIntent(w, r, updated, err)
+}
+
+func (s *Server) handleCancelIntent(w http.ResponseWriter, r *http.Request) {
+	var body cancelBody
+	if err := decodeBody(r, &body); err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	intent, ok := s.loadIntent(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := s.payments.CancelIntent(r.Context(), payments.CancelRequest{
+		IntentID:       intent.ID,
+		Reason:         body.Reason,
+		IdempotencyKey: r.Header.Get(IdempotencyKeyHeader),
+	})
+	s.writeIntent(w, r, updated, err)
+}
+
+func (s *Server) writeIntent(w http.ResponseWriter, r *http.Request, intent *payments.PaymentIntent, err error) {
+	if err != nil {
+		s.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newIntentResponse(intent))
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+)
+
+const (
+	MerchantIDHeader     = "X-Merchant-ID"
+	CorrelationIDHeader  = "X-Correlation-ID"
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxBodyBytes = 1 << 20
+)
+
+type Config struct {
+	// Timeout bounds the time each request may spend in a handler.
+	Timeout time.Duration
+	// Idempotency, when set, stores and replays responses to requests that
+	// carry an Idempotency-Key header.
+	Idempotency *idempotency.Middleware
+}
+
+type Server struct {
+	payments payments.Service
+	logger   *zap.Logger
+	config   Config
+	handler  http.Handler
+}
+
+func NewServer(paymentsSvc payments.Service, logger *zap.Logger, config Config) *Server {
+	s := &Server{
+		payments: paymentsSvc,
+		logger:   logger,
+		config:   config,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("POST /v1/payment_intents", s.requireMerchant(s.handleCreateIntent))
+	mux.HandleFunc("GET /v1/payment_intents", s.requireMerchant(s.handleListIntents))
+	mux.HandleFunc("GET /v1/payment_intents/{id}", s.requireMerchant(s.handleGetIntent))
+	mux.HandleFunc("POST /v1/payment_intents/{id}/authorize", s.requireMerchant(s.handleAuthorizeIntent))
+	mux.HandleFunc("POST /v1/payment_intents/{id}/capture", s.requireMerchant(s.handleCaptureIntent))
+	mux.HandleFunc("POST /v1/payment_intents/{id}/refund", s.requireMerchant(s.handleRefundIntent))
+	mux.HandleFunc("POST /v1/payment_intents/{id}/cancel", s.requireMerchant(s.handleCancelIntent))
+	mux.HandleFunc("/", s.handleNotFound)
+
+	var handler http.Handler = mux
+	if config.Idempotency != nil {
+		handler = config.Idempotency.Handler(handler)
+	}
+	s.handler = s.withRequestContext(handler)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// withRequestContext attaches the correlation and merchant IDs from the
+// request headers to the context, echoes the correlation ID back and applies
+// the per-request timeout.
+func (s *Server) withRequestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := platform.WithCorrelationID(r.Context(), r.Header.Get(CorrelationIDHeader))
+		if merchantID := r.Header.Get(MerchantIDHeader); merchantID != "" {
+			ctx = platform.WithMerchantID(ctx, merchantID)
+		}
+		if s.config.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+			defer cancel()
+		}
+
+		w.Header().Set(CorrelationIDHeader, platform.GetCorrelationID(ctx))
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireMerchant rejects requests without the X-Merchant-ID header. The
+// header is the only source of merchant scope; body and query values are
+// never trusted on their own.
+func (s *Server) requireMerchant(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if platform.GetMerchantID(r.Context()) == "" {
+			s.writeError(w, r, invalidRequest("", MerchantIDHeader+" header is required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	s.writeError(w, r, &platform.Error{
+		Code:    platform.CodeNotFound,
+		Message: "no route for " + r.Method + " " + r.URL.Path,
+		Status:  http.StatusNotFound,
+	})
+}
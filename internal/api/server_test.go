@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+)
+
+func newTestServer() *Server {
+	svc := payments.NewService(payments.NewMemoryRepository())
+	return NewServer(svc, zap.NewNop(), Config{})
+}
+
+func do(t *testing.T, srv http.Handler, method, path, merchantID, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if merchantID != "" {
+		req.Header.Set(MerchantIDHeader, merchantID)
+	}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var decoded map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		t.Fatalf("%s %s returned non-JSON body %q", method, path, rec.Body.String())
+	}
+	return rec, decoded
+}
+
+func errorCode(body map[string]any) string {
+	detail, _ := body["error"].(map[string]any)
+	code, _ := detail["code"].(string)
+	return code
+}
+
+func TestServer_PaymentIntentLifecycle(t *testing.T) {
+	srv := newTestServer()
+
+	rec, created := do(t, srv, "POST", "/v1/payment_intents", "merchant_1", `{"amount": 10000, "currency": "USD"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", rec.Code, created)
+	}
+	if created["status"] != "created" || created["merchant_id"] != "merchant_1" {
+		t.Errorf("Unexpected intent %v", created)
+	}
+	if rec.Header().Get(CorrelationIDHeader) == "" {
+		t.Error("Expected a correlation ID header")
+	}
+	id := created["id"].(string)
+
+	steps := []struct {
+		path   string
+		body   string
+		status string
+	}{
+		{"/authorize", "", "authorized"},
+		{"/capture", `{"amount": 7500}`, "captured"},
+		{"/refund", `{"reason": "customer_request"}`, "refunded"},
+	}
+	for _, step := range steps {
+		rec, intent := do(t, srv, "POST", "/v1/payment_intents/"+id+step.path, "merchant_1", step.body)
+		if rec.Code != http.StatusOK || intent["status"] != step.status {
+			t.Fatalf("%s: expected 200 %s, got %d: %v", step.path, step.status, rec.Code, intent)
+		}
+	}
+
+	rec, got := do(t, srv, "GET", "/v1/payment_intents/"+id, "merchant_1", "")
+	if rec.Code != http.StatusOK || got["status"] != "refunded" || got["version"] != float64(3) {
+		t.Errorf("Unexpected intent %d: %v", rec.Code, got)
+	}
+}
+
+func TestServer_CancelAndList(t *testing.T) {
+	srv := newTestServer()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		_, created := do(t, srv, "POST", "/v1/payment_intents", "merchant_1", `{"amount": 500, "currency": "USD"}`)
+		ids = append(ids, created["id"].(string))
+	}
+	do(t, srv, "POST", "/v1/payment_intents", "merchant_2", `{"amount": 500, "currency": "USD"}`)
+
+	rec, canceled := do(t, srv, "POST", "/v1/payment_intents/"+ids[0]+"/cancel", "merchant_1", "")
+	if rec.Code != http.StatusOK || canceled["status"] != "canceled" {
+		t.Fatalf("Expected canceled intent, got %d: %v", rec.Code, canceled)
+	}
+
+	rec, body := do(t, srv, "POST", "/v1/payment_intents/"+ids[0]+"/authorize", "merchant_1", "")
+	if rec.Code != http.StatusConflict || errorCode(body) != payments.CodeInvalidStateTransition {
+		t.Errorf("Expected 409 %s, got %d: %v", payments.CodeInvalidStateTransition, rec.Code, body)
+	}
+
+	rec, list := do(t, srv, "GET", "/v1/payment_intents?limit=2", "merchant_1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", rec.Code, list)
+	}
+	if data := list["data"].([]any); len(data) != 2 {
+		t.Errorf("Expected 2 intents, got %d", len(data))
+	}
+
+	rec, list = do(t, srv, "GET", "/v1/payment_intents", "merchant_2", "")
+	if data := list["data"].([]any); rec.Code != http.StatusOK || len(data) != 1 {
+		t.Errorf("Expected 1 intent for merchant_2, got %d: %v", rec.Code, list)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	srv := newTestServer()
+	_, created := do(t, srv, "POST", "/v1/payment_intents", "merchant_1", `{"amount": 500, "currency": "USD"}`)
+	id := created["id"].(string)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		merchantID string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"malformed JSON", "POST", "/v1/payment_intents", "merchant_1", `{"amount":`, http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"zero amount", "POST", "/v1/payment_intents", "merchant_1", `{"amount": 0, "currency": "USD"}`, http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"merchant mismatch", "POST", "/v1/payment_intents", "merchant_1", `{"merchant_id": "merchant_2", "amount": 5, "currency": "USD"}`, http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"unknown intent", "GET", "/v1/payment_intents/pi_missing", "merchant_1", "", http.StatusNotFound, platform.CodeNotFound},
+		{"other merchant's intent", "GET", "/v1/payment_intents/" + id, "merchant_2", "", http.StatusNotFound, platform.CodeNotFound},
+		{"capture before authorize", "POST", "/v1/payment_intents/" + id + "/capture", "merchant_1", "", http.StatusConflict, payments.CodeInvalidStateTransition},
+		{"list without merchant", "GET", "/v1/payment_intents", "", "", http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"list by query merchant", "GET", "/v1/payment_intents?merchant_id=merchant_1", "", "", http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"create without merchant", "POST", "/v1/payment_intents", "", `{"merchant_id": "merchant_1", "amount": 5, "currency": "USD"}`, http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"get without merchant", "GET", "/v1/payment_intents/" + id, "", "", http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"capture without merchant", "POST", "/v1/payment_intents/" + id + "/capture", "", "", http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"invalid limit", "GET", "/v1/payment_intents?limit=abc", "merchant_1", "", http.StatusBadRequest, platform.CodeInvalidRequest},
+		{"unknown route", "GET", "/v2/payment_intents", "", "", http.StatusNotFound, platform.CodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, body := do(t, srv, tt.method, tt.path, tt.merchantID, tt.body)
+			if rec.Code != tt.wantStatus || errorCode(body) != tt.wantCode {
+				t.Errorf("Expected %d %s, got %d: %v", tt.wantStatus, tt.wantCode, rec.Code, body)
+			}
+		})
+	}
+}
+
+func TestServer_ErrorDetails(t *testing.T) {
+	srv := newTestServer()
+
+	rec, body := do(t, srv, "POST", "/v1/payment_intents", "merchant_1", `{"amount": -5, "currency": "USD"}`)
+	detail, _ := body["error"].(map[string]any)
+	if rec.Code != http.StatusBadRequest || detail["param"] != "amount" || detail["retryable"] != false {
+		t.Errorf("Expected a non-retryable error on amount, got %d: %v", rec.Code, body)
+	}
+	if detail["message"] != "amount must be positive" {
+		t.Errorf("Expected the validation message, got %v", detail["message"])
+	}
+	if rec.Header().Get(idempotency.RetryableHeader) != "" {
+		t.Error("Expected a non-retryable error not to be flagged for retry")
+	}
+
+	rec, body = do(t, srv, "GET", "/v1/payment_intents/pi_missing", "merchant_1", "")
+	detail, _ = body["error"].(map[string]any)
+	if _, ok := detail["param"]; ok || rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a 404 without param, got %d: %v", rec.Code, body)
+	}
+}
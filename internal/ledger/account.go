@@ -0,0 +1,181 @@
+package ledger
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrAccountExists            = errors.New("account already exists")
+	ErrAccountCodeExists        = errors.New("account code already exists")
+	ErrInvalidParent            = errors.New("invalid parent account")
+	ErrAccountFrozen            = errors.New("account is frozen and accepts credits only")
+	ErrAccountClosed            = errors.New("account is closed")
+	ErrAccountBalanceNotZero    = errors.New("account balance must be zero to close")
+	ErrInvalidAccountTransition = errors.New("invalid account status transition")
+)
+
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "ACTIVE"
+	AccountStatusFrozen AccountStatus = "FROZEN"
+	AccountStatusClosed AccountStatus = "CLOSED"
+)
+
+type AccountStatusTransition struct {
+	From AccountStatus
+	To   AccountStatus
+}
+
+var allowedAccountTransitions = map[AccountStatusTransition]bool{
+	{From: AccountStatusActive, To: AccountStatusFrozen}: true,
+	{From: AccountStatusFrozen, To: AccountStatusActive}: true,
+	{From: AccountStatusActive, To: AccountStatusClosed}: true,
+	{From: AccountStatusFrozen, To: AccountStatusClosed}: true,
+	{From: AccountStatusClosed, To: AccountStatusActive}: true,
+}
+
+func ValidateAccountTransition(from, to AccountStatus) error {
+	if !allowedAccountTransitions[AccountStatusTransition{From: from, To: to}] {
+		return ErrInvalidAccountTransition
+	}
+	return nil
+}
+
+// AccountStatusChange is the audit record of a status transition.
+type AccountStatusChange struct {
+	ID         int64
+	AccountID  string
+	FromStatus AccountStatus
+	ToStatus   AccountStatus
+	Reason     string
+	ChangedBy  string
+	CreatedAt  time.Time
+}
+
+type AccountStatusRequest struct {
+	AccountID string
+	Reason    string
+	ChangedBy string
+}
+
+func (req AccountStatusRequest) Validate() error {
+	if req.AccountID == "" {
+		return invalidParam("account_id", "account ID is required")
+	}
+	if req.Reason == "" {
+		return invalidParam("reason", "reason is required")
+	}
+	if req.ChangedBy == "" {
+		return invalidParam("changed_by", "changed by is required")
+	}
+	return nil
+}
+
+// checkAccountTransition validates change against the account's current
+// status. A non-empty FromStatus must match it. Only an account with no posted
+// balance and no open holds may be closed.
+func checkAccountTransition(account *Account, change *AccountStatusChange, balance *Balance) error {
+	if change.FromStatus != "" && change.FromStatus != account.Status {
+		return ErrInvalidAccountTransition
+	}
+	if err := ValidateAccountTransition(account.Status, change.ToStatus); err != nil {
+		return err
+	}
+	if change.ToStatus == AccountStatusClosed && (balance.Posted != 0 || balance.PendingDebits != 0 || balance.PendingCredits != 0) {
+		return ErrAccountBalanceNotZero
+	}
+	return nil
+}
+
+// checkEntryAllowed applies the account's status to a single entry: frozen
+// accounts take credits (negative amounts) only, closed accounts take nothing.
+func checkEntryAllowed(account *Account, entry EntryRequest) error {
+	switch account.Status {
+	case AccountStatusClosed:
+		return ErrAccountClosed
+	case AccountStatusFrozen:
+		if entry.Amount > 0 {
+			return ErrAccountFrozen
+		}
+	}
+	return nil
+}
+
+func (t AccountType) Valid() bool {
+	_, ok := accountTypeOrder[t]
+	return ok
+}
+
+func (a *Account) Validate() error {
+	if a.ID == "" {
+		return invalidParam("account_id", "account ID is required")
+	}
+	if a.Name == "" {
+		return invalidParam("name", "account name is required")
+	}
+	if !a.Type.Valid() {
+		return invalidParam("type", "invalid account type")
+	}
+	if a.Currency == "" {
+		return ErrInvalidCurrency
+	}
+	if a.ParentID == a.ID {
+		return ErrInvalidParent
+	}
+	return validateConstraint(a.BalanceConstraint, a.CreditLimit)
+}
+
+// validateParent keeps a subtree rollup meaningful: children share their
+// parent's type and currency. A rollup is a single Balance, summed in minor
+// units of one currency and signed by one account type's normal side, so a
+// child in another currency or of another type would be added in as if it
+// were the parent's. Accounts in different currencies belong under separate
+// parents, one per currency.
+func validateParent(child, parent *Account) error {
+	if parent.Type != child.Type || parent.Currency != child.Currency {
+		return ErrInvalidParent
+	}
+	return nil
+}
+
+type AccountNode struct {
+	*Account
+	Children []*AccountNode
+}
+
+// buildAccountTree nests accounts under their parents. Accounts whose parent
+// is not in the list become roots. Siblings are ordered by code, then ID.
+func buildAccountTree(accounts []*Account) []*AccountNode {
+	nodes := make(map[string]*AccountNode, len(accounts))
+	for _, account := range accounts {
+		nodes[account.ID] = &AccountNode{Account: account}
+	}
+
+	var roots []*AccountNode
+	for _, account := range accounts {
+		node := nodes[account.ID]
+		if parent, ok := nodes[account.ParentID]; ok && account.ParentID != "" {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortAccountNodes(roots)
+	return roots
+}
+
+func sortAccountNodes(nodes []*AccountNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Code != nodes[j].Code {
+			return nodes[i].Code < nodes[j].Code
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	for _, node := range nodes {
+		sortAccountNodes(node.Children)
+	}
+}
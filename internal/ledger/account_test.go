@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"testing"
+)
+
+func TestAccount_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		account Account
+		wantErr bool
+	}{
+		{"valid", Account{ID: "acc_1", Name: "Cash", Type: AccountTypeAsset, Currency: "USD"}, false},
+		{"missing ID", Account{Name: "Cash", Type: AccountTypeAsset, Currency: "USD"}, true},
+		{"unknown type", Account{ID: "acc_1", Name: "Cash", Type: "EQUITY", Currency: "USD"}, true},
+		{"missing currency", Account{ID: "acc_1", Name: "Cash", Type: AccountTypeAsset}, true},
+		{"own parent", Account{ID: "acc_1", Name: "Cash", Type: AccountTypeAsset, Currency: "USD", ParentID: "acc_1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.account.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateParent(t *testing.T) {
+	parent := &Account{ID: "acc_receivables", Type: AccountTypeAsset, Currency: "USD"}
+
+	if err := validateParent(&Account{Type: AccountTypeAsset, Currency: "USD"}, parent); err != nil {
+		t.Errorf("Expected matching child to be accepted, got %v", err)
+	}
+	if err := validateParent(&Account{Type: AccountTypeLiability, Currency: "USD"}, parent); err != ErrInvalidParent {
+		t.Errorf("Expected ErrInvalidParent for type mismatch, got %v", err)
+	}
+	if err := validateParent(&Account{Type: AccountTypeAsset, Currency: "EUR"}, parent); err != ErrInvalidParent {
+		t.Errorf("Expected ErrInvalidParent for currency mismatch, got %v", err)
+	}
+}
+
+func TestBuildAccountTree(t *testing.T) {
+	accounts := []*Account{
+		{ID: "acc_merchant_2", Code: "1210", ParentID: "acc_receivables"},
+		{ID: "acc_assets", Code: "1000"},
+		{ID: "acc_merchant_1", Code: "1200", ParentID: "acc_receivables"},
+		{ID: "acc_receivables", Code: "1100", ParentID: "acc_assets"},
+		{ID: "acc_liabilities", Code: "2000"},
+		{ID: "acc_orphan", Code: "9000", ParentID: "acc_missing"},
+	}
+
+	roots := buildAccountTree(accounts)
+
+	if len(roots) != 3 {
+		t.Fatalf("Expected 3 roots, got %d", len(roots))
+	}
+	if roots[0].ID != "acc_assets" || roots[1].ID != "acc_liabilities" || roots[2].ID != "acc_orphan" {
+		t.Errorf("Expected roots ordered by code, got %s, %s, %s", roots[0].ID, roots[1].ID, roots[2].ID)
+	}
+
+	receivables := roots[0].Children
+	if len(receivables) != 1 || receivables[0].ID != "acc_receivables" {
+		t.Fatalf("Expected receivables under assets, got %v", receivables)
+	}
+	merchants := receivables[0].Children
+	if len(merchants) != 2 || merchants[0].ID != "acc_merchant_1" || merchants[1].ID != "acc_merchant_2" {
+		t.Errorf("Expected merchants ordered by code under receivables")
+	}
+}
+
+func TestCheckAccountTransition(t *testing.T) {
+	zero := &Balance{}
+	funded := &Balance{Posted: -500}
+	held := &Balance{PendingDebits: 100}
+
+	tests := []struct {
+		name    string
+		status  AccountStatus
+		change  AccountStatusChange
+		balance *Balance
+		wantErr error
+	}{
+		{"freeze active", AccountStatusActive, AccountStatusChange{ToStatus: AccountStatusFrozen}, funded, nil},
+		{"unfreeze frozen", AccountStatusFrozen, AccountStatusChange{FromStatus: AccountStatusFrozen, ToStatus: AccountStatusActive}, funded, nil},
+		{"unfreeze closed", AccountStatusClosed, AccountStatusChange{FromStatus: AccountStatusFrozen, ToStatus: AccountStatusActive}, zero, ErrInvalidAccountTransition},
+		{"close empty", AccountStatusFrozen, AccountStatusChange{ToStatus: AccountStatusClosed}, zero, nil},
+		{"close funded", AccountStatusActive, AccountStatusChange{ToStatus: AccountStatusClosed}, funded, ErrAccountBalanceNotZero},
+		{"close with holds", AccountStatusActive, AccountStatusChange{ToStatus: AccountStatusClosed}, held, ErrAccountBalanceNotZero},
+		{"freeze closed", AccountStatusClosed, AccountStatusChange{ToStatus: AccountStatusFrozen}, zero, ErrInvalidAccountTransition},
+		{"reopen closed", AccountStatusClosed, AccountStatusChange{FromStatus: AccountStatusClosed, ToStatus: AccountStatusActive}, zero, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &Account{ID: "acc_1", Status: tt.status}
+			if err := checkAccountTransition(account, &tt.change, tt.balance); err != tt.wantErr {
+				t.Errorf("checkAccountTransition() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckEntryAllowed(t *testing.T) {
+	debit := EntryRequest{AccountID: "acc_1", Amount: 100}
+	credit := EntryRequest{AccountID: "acc_1", Amount: -100}
+
+	tests := []struct {
+		name    string
+		status  AccountStatus
+		entry   EntryRequest
+		wantErr error
+	}{
+		{"active debit", AccountStatusActive, debit, nil},
+		{"active credit", AccountStatusActive, credit, nil},
+		{"frozen debit", AccountStatusFrozen, debit, ErrAccountFrozen},
+		{"frozen credit", AccountStatusFrozen, credit, nil},
+		{"closed credit", AccountStatusClosed, credit, ErrAccountClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &Account{ID: "acc_1", Status: tt.status}
+			if err := checkEntryAllowed(account, tt.entry); err != tt.wantErr {
+				t.Errorf("checkEntryAllowed() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostResult reports the outcome of one request in a batch. Err is nil for
+// postings that were written or, with Replayed set, already existed.
+type PostResult struct {
+	TransactionID string
+	Replayed      bool
+	Err           error
+}
+
+// batchState is what the database said before a batch was planned, read
+// under the locks the batch holds until it commits.
+type batchState struct {
+	head          *ChainHead
+	now           time.Time
+	requestHashes map[string]string
+	periods       []*AccountingPeriod
+	accounts      map[string]*Account
+	available     map[string]int64
+}
+
+type postingPlan struct {
+	results       []PostResult
+	transactions  []*Transaction
+	requestHashes []string
+	entries       []*LedgerEntry
+	head          ChainHead
+}
+
+// planPostings decides every posting of a batch in order, as if each were
+// posted on its own: later postings see the chain head, balances and
+// transaction IDs left by earlier accepted ones. Rejected postings leave no
+// trace, so one bad item does not fail the batch.
+func planPostings(postings []posting, state batchState) *postingPlan {
+	plan := &postingPlan{
+		results: make([]PostResult, len(postings)),
+		head:    *state.head,
+	}
+
+	periodsByID := make(map[string]*AccountingPeriod, len(state.periods))
+	for _, period := range state.periods {
+		periodsByID[period.ID] = period
+	}
+	hashes := make(map[string]string, len(state.requestHashes)+len(postings))
+	for id, hash := range state.requestHashes {
+		hashes[id] = hash
+	}
+	available := make(map[string]int64, len(state.available))
+	for id, balance := range state.available {
+		available[id] = balance
+	}
+
+	for i, p := range postings {
+		result := &plan.results[i]
+		result.TransactionID = p.req.TransactionID
+
+		requestHash := p.hash()
+		if stored, ok := hashes[p.req.TransactionID]; ok {
+			if stored != requestHash {
+				result.Err = ErrTransactionConflict
+			} else {
+				result.Replayed = true
+			}
+			continue
+		}
+
+		effectiveAt := effectiveTime(p.req.EffectiveAt, state.now)
+		var period *AccountingPeriod
+		if p.req.AdjustmentPeriodID != "" {
+			period = periodsByID[p.req.AdjustmentPeriodID]
+		} else {
+			period = regularPeriodAt(state.periods, effectiveAt)
+		}
+		if err := checkPostingPeriod(period, p.req.AdjustmentPeriodID, effectiveAt); err != nil {
+			result.Err = err
+			continue
+		}
+
+		if err := checkPostingAccounts(p, state.accounts); err != nil {
+			result.Err = err
+			continue
+		}
+
+		deltas := p.availableDeltas(state.accounts)
+		if err := checkPostingConstraints(deltas, state.accounts, available); err != nil {
+			result.Err = err
+			continue
+		}
+		for accountID, delta := range deltas {
+			if _, ok := available[accountID]; ok {
+				available[accountID] += delta
+			}
+		}
+
+		txn, entries := buildTransaction(p, state.now, effectiveAt, period)
+		newChainLink(&plan.head, txn, entries)
+		plan.head = ChainHead{Sequence: txn.Sequence, Hash: txn.Hash}
+
+		plan.transactions = append(plan.transactions, txn)
+		plan.requestHashes = append(plan.requestHashes, requestHash)
+		plan.entries = append(plan.entries, entries...)
+		hashes[txn.ID] = requestHash
+	}
+
+	return plan
+}
+
+func regularPeriodAt(periods []*AccountingPeriod, at time.Time) *AccountingPeriod {
+	for _, period := range periods {
+		if period.Kind == PeriodKindRegular && period.Contains(at) {
+			return period
+		}
+	}
+	return nil
+}
+
+func checkPostingAccounts(p posting, accounts map[string]*Account) error {
+	for _, entry := range p.req.Entries {
+		account, ok := accounts[entry.AccountID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, entry.AccountID)
+		}
+		if err := checkEntryAllowed(account, entry); err != nil {
+			return fmt.Errorf("%w: %s", err, entry.AccountID)
+		}
+	}
+	return nil
+}
+
+// checkPostingConstraints checks constrained accounts in ID order so that the
+// reported account does not depend on map iteration.
+func checkPostingConstraints(deltas map[string]int64, accounts map[string]*Account, available map[string]int64) error {
+	accountIDs := make([]string, 0, len(deltas))
+	for accountID := range deltas {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Strings(accountIDs)
+
+	for _, accountID := range accountIDs {
+		balance, ok := available[accountID]
+		if !ok {
+			continue
+		}
+		if err := checkBalanceConstraint(accounts[accountID], balance, deltas[accountID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildTransaction(p posting, now, effectiveAt time.Time, period *AccountingPeriod) (*Transaction, []*LedgerEntry) {
+	txn := &Transaction{
+		ID:                   p.req.TransactionID,
+		Description:          p.req.Description,
+		Status:               p.status,
+		PendingTransactionID: p.pendingID,
+		Metadata:             p.req.Metadata,
+		References:           sortReferences(p.req.References),
+		CreatedAt:            now,
+		EffectiveAt:          effectiveAt,
+	}
+	if period != nil {
+		txn.PeriodID = period.ID
+	}
+
+	entries := make([]*LedgerEntry, len(p.req.Entries))
+	for i, entry := range p.req.Entries {
+		entries[i] = &LedgerEntry{
+			ID:            uuid.New().String(),
+			TransactionID: txn.ID,
+			EntryIndex:    i,
+			AccountID:     entry.AccountID,
+			Amount:        entry.Amount,
+			Currency:      entry.Currency,
+			Memo:          entry.Memo,
+			CreatedAt:     now,
+			EffectiveAt:   effectiveAt,
+		}
+	}
+	return txn, entries
+}
@@ -0,0 +1,157 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func transfer(id, debit, credit string, amount int64) posting {
+	return newPosting(PostTransactionRequest{
+		TransactionID: id,
+		Description:   "Transfer",
+		Entries: []EntryRequest{
+			{AccountID: debit, Amount: amount, Currency: "USD"},
+			{AccountID: credit, Amount: -amount, Currency: "USD"},
+		},
+	})
+}
+
+func testBatchState() batchState {
+	return batchState{
+		head: &ChainHead{Sequence: 7, Hash: "prev"},
+		now:  time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC),
+		accounts: map[string]*Account{
+			"acc_cash":   {ID: "acc_cash", Type: AccountTypeAsset, Status: AccountStatusActive},
+			"acc_wallet": {ID: "acc_wallet", Type: AccountTypeLiability, Status: AccountStatusActive, BalanceConstraint: BalanceConstraintNoOverdraft},
+			"acc_frozen": {ID: "acc_frozen", Type: AccountTypeLiability, Status: AccountStatusFrozen},
+		},
+		available: map[string]int64{"acc_wallet": -500},
+	}
+}
+
+func TestPlanPostings_Replays(t *testing.T) {
+	existing := transfer("txn_existing", "acc_cash", "acc_wallet", 100)
+	state := testBatchState()
+	state.requestHashes = map[string]string{"txn_existing": existing.hash()}
+
+	plan := planPostings([]posting{
+		existing,
+		transfer("txn_existing", "acc_cash", "acc_wallet", 200),
+		transfer("txn_new", "acc_cash", "acc_wallet", 100),
+		transfer("txn_new", "acc_cash", "acc_wallet", 100),
+		transfer("txn_new", "acc_cash", "acc_wallet", 150),
+	}, state)
+
+	want := []struct {
+		replayed bool
+		err      error
+	}{
+		{true, nil},
+		{false, ErrTransactionConflict},
+		{false, nil},
+		{true, nil},
+		{false, ErrTransactionConflict},
+	}
+	for i, w := range want {
+		result := plan.results[i]
+		if result.Replayed != w.replayed || result.Err != w.err {
+			t.Errorf("result %d = %+v, want replayed %v err %v", i, result, w.replayed, w.err)
+		}
+	}
+	if len(plan.transactions) != 1 || plan.transactions[0].ID != "txn_new" {
+		t.Errorf("Expected only txn_new to be written, got %d transactions", len(plan.transactions))
+	}
+}
+
+func TestPlanPostings_RejectsItems(t *testing.T) {
+	state := testBatchState()
+	state.periods = []*AccountingPeriod{{
+		ID:       "per_feb",
+		Kind:     PeriodKindRegular,
+		StartsAt: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Status:   PeriodStatusClosed,
+	}}
+
+	backdated := transfer("txn_backdated", "acc_cash", "acc_wallet", 100)
+	backdated.req.EffectiveAt = time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	plan := planPostings([]posting{
+		backdated,
+		transfer("txn_frozen", "acc_frozen", "acc_cash", 100),
+		transfer("txn_credit_frozen", "acc_cash", "acc_frozen", 100),
+		transfer("txn_missing", "acc_missing", "acc_cash", 100),
+		transfer("txn_ok", "acc_cash", "acc_wallet", 100),
+	}, state)
+
+	if plan.results[0].Err != ErrPeriodClosed {
+		t.Errorf("Expected ErrPeriodClosed, got %v", plan.results[0].Err)
+	}
+	if !errors.Is(plan.results[1].Err, ErrAccountFrozen) {
+		t.Errorf("Expected ErrAccountFrozen, got %v", plan.results[1].Err)
+	}
+	if plan.results[2].Err != nil {
+		t.Errorf("Expected credit to frozen account to be allowed, got %v", plan.results[2].Err)
+	}
+	if !errors.Is(plan.results[3].Err, ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got %v", plan.results[3].Err)
+	}
+	if plan.results[4].Err != nil {
+		t.Errorf("Expected valid posting to be accepted, got %v", plan.results[4].Err)
+	}
+	if len(plan.transactions) != 2 {
+		t.Errorf("Expected 2 transactions, got %d", len(plan.transactions))
+	}
+}
+
+func TestPlanPostings_RunningConstraint(t *testing.T) {
+	// The wallet holds 500; each withdrawal sees what earlier ones left.
+	plan := planPostings([]posting{
+		transfer("txn_1", "acc_wallet", "acc_cash", 300),
+		transfer("txn_2", "acc_wallet", "acc_cash", 300),
+		transfer("txn_3", "acc_cash", "acc_wallet", 100),
+		transfer("txn_4", "acc_wallet", "acc_cash", 300),
+	}, testBatchState())
+
+	for i, wantErr := range []bool{false, true, false, false} {
+		err := plan.results[i].Err
+		if (err != nil) != wantErr {
+			t.Errorf("result %d error = %v, wantErr %v", i, err, wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+			t.Errorf("result %d: expected ErrInsufficientBalance, got %v", i, err)
+		}
+	}
+}
+
+func TestPlanPostings_Chain(t *testing.T) {
+	state := testBatchState()
+	plan := planPostings([]posting{
+		transfer("txn_1", "acc_cash", "acc_wallet", 100),
+		transfer("txn_2", "acc_frozen", "acc_cash", 100),
+		transfer("txn_3", "acc_cash", "acc_wallet", 100),
+	}, state)
+
+	if len(plan.transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(plan.transactions))
+	}
+	if plan.transactions[0].PrevHash != "prev" || plan.transactions[0].Sequence != 8 {
+		t.Errorf("Expected batch to continue from the head, got %+v", plan.transactions[0])
+	}
+
+	links := make([]*ChainLink, len(plan.transactions))
+	for i, txn := range plan.transactions {
+		links[i] = &ChainLink{Transaction: txn, Entries: plan.entries[i*2 : i*2+2]}
+	}
+	verifier := newChainVerifier()
+	verifier.sequence, verifier.hash = state.head.Sequence, state.head.Hash
+	verifier.verify(links)
+	result := verifier.finish(&plan.head, 0)
+	if !result.Valid() {
+		t.Errorf("Expected valid chain, got issues %v", result.Issues)
+	}
+	if plan.head.Sequence != 9 {
+		t.Errorf("Expected head at sequence 9, got %d", plan.head.Sequence)
+	}
+}
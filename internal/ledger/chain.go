@@ -0,0 +1,187 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const chainBatchSize = 1000
+
+// ChainHead is the latest link of the transaction hash chain. Anchoring the
+// head hash outside the database makes rewrites of the whole chain evident.
+type ChainHead struct {
+	Sequence int64
+	Hash     string
+}
+
+type ChainLink struct {
+	Transaction *Transaction
+	Entries     []*LedgerEntry
+}
+
+type ChainIssue struct {
+	Sequence      int64
+	TransactionID string
+	Reason        string
+}
+
+type ChainVerification struct {
+	Verified     int64
+	Unchained    int64
+	HeadSequence int64
+	HeadHash     string
+	Issues       []ChainIssue
+}
+
+func (v *ChainVerification) Valid() bool {
+	return len(v.Issues) == 0 && v.Unchained == 0
+}
+
+// chainTime normalizes timestamps to what Postgres stores so that hashes
+// computed before the insert match hashes recomputed from the stored row.
+func chainTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func computeChainHash(link *ChainLink) string {
+	type entryRecord struct {
+		ID        string `json:"id"`
+		Index     int    `json:"index"`
+		AccountID string `json:"account_id"`
+		Amount    int64  `json:"amount"`
+		Currency  string `json:"currency"`
+		Memo      string `json:"memo,omitempty"`
+		// Entries carry their own timestamps, which queries filter on. They
+		// are recorded only where they differ from the transaction's, so
+		// editing either column breaks the link.
+		CreatedAt   string `json:"created_at,omitempty"`
+		EffectiveAt string `json:"effective_at,omitempty"`
+	}
+	txn := link.Transaction
+	record := struct {
+		Sequence             int64               `json:"sequence"`
+		PrevHash             string              `json:"prev_hash"`
+		ID                   string              `json:"id"`
+		Description          string              `json:"description"`
+		CreatedAt            string              `json:"created_at"`
+		Entries              []entryRecord       `json:"entries"`
+		Status               TransactionStatus   `json:"status,omitempty"`
+		PendingTransactionID string              `json:"pending_transaction_id,omitempty"`
+		PeriodID             string              `json:"period_id,omitempty"`
+		EffectiveAt          string              `json:"effective_at,omitempty"`
+		Metadata             map[string]string   `json:"metadata,omitempty"`
+		References           []ExternalReference `json:"references,omitempty"`
+	}{
+		Sequence:             txn.Sequence,
+		PrevHash:             txn.PrevHash,
+		ID:                   txn.ID,
+		Description:          txn.Description,
+		CreatedAt:            chainTime(txn.CreatedAt).Format(time.RFC3339Nano),
+		PendingTransactionID: txn.PendingTransactionID,
+		PeriodID:             txn.PeriodID,
+		Metadata:             txn.Metadata,
+		References:           sortReferences(txn.References),
+	}
+	if txn.Status != TransactionStatusPosted {
+		record.Status = txn.Status
+	}
+	effectiveAt := txn.CreatedAt
+	if !txn.EffectiveAt.IsZero() {
+		effectiveAt = txn.EffectiveAt
+	}
+	record.EffectiveAt = chainTimeDiff(effectiveAt, txn.CreatedAt)
+	for _, entry := range link.Entries {
+		record.Entries = append(record.Entries, entryRecord{
+			ID:          entry.ID,
+			Index:       entry.EntryIndex,
+			AccountID:   entry.AccountID,
+			Amount:      entry.Amount,
+			Currency:    entry.Currency,
+			Memo:        entry.Memo,
+			CreatedAt:   chainTimeDiff(entry.CreatedAt, txn.CreatedAt),
+			EffectiveAt: chainTimeDiff(entry.EffectiveAt, effectiveAt),
+		})
+	}
+
+	data, _ := json.Marshal(record)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// chainTimeDiff formats t for the hashed record, or returns "" when it
+// matches ref and so is already covered by the record.
+func chainTimeDiff(t, ref time.Time) string {
+	if chainTime(t).Equal(chainTime(ref)) {
+		return ""
+	}
+	return chainTime(t).Format(time.RFC3339Nano)
+}
+
+func newChainLink(head *ChainHead, txn *Transaction, entries []*LedgerEntry) *ChainLink {
+	txn.Sequence = head.Sequence + 1
+	txn.PrevHash = head.Hash
+	link := &ChainLink{Transaction: txn, Entries: entries}
+	txn.Hash = computeChainHash(link)
+	return link
+}
+
+type chainVerifier struct {
+	result   *ChainVerification
+	sequence int64
+	hash     string
+}
+
+func newChainVerifier() *chainVerifier {
+	return &chainVerifier{result: &ChainVerification{}}
+}
+
+func (v *chainVerifier) verify(links []*ChainLink) {
+	for _, link := range links {
+		txn := link.Transaction
+		expected := v.sequence + 1
+
+		if txn.Sequence != expected {
+			v.issue(txn, fmt.Sprintf("sequence gap: expected %d, found %d", expected, txn.Sequence))
+		}
+		if txn.PrevHash != v.hash {
+			v.issue(txn, "previous hash does not match preceding transaction")
+		}
+		if computeChainHash(link) != txn.Hash {
+			v.issue(txn, "transaction or entries modified after posting")
+		}
+
+		v.sequence = txn.Sequence
+		v.hash = txn.Hash
+		v.result.Verified++
+	}
+}
+
+func (v *chainVerifier) finish(head *ChainHead, unchained int64) *ChainVerification {
+	if head.Sequence != v.sequence {
+		v.result.Issues = append(v.result.Issues, ChainIssue{
+			Sequence: head.Sequence,
+			Reason:   fmt.Sprintf("chain head at sequence %d but last transaction is %d", head.Sequence, v.sequence),
+		})
+	} else if head.Hash != v.hash {
+		v.result.Issues = append(v.result.Issues, ChainIssue{
+			Sequence: head.Sequence,
+			Reason:   "chain head hash does not match last transaction",
+		})
+	}
+
+	v.result.Unchained = unchained
+	v.result.HeadSequence = head.Sequence
+	v.result.HeadHash = head.Hash
+	return v.result
+}
+
+func (v *chainVerifier) issue(txn *Transaction, reason string) {
+	v.result.Issues = append(v.result.Issues, ChainIssue{
+		Sequence:      txn.Sequence,
+		TransactionID: txn.ID,
+		Reason:        reason,
+	})
+}
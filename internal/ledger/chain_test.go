@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func buildChain(n int) (*ChainHead, []*ChainLink) {
+	head := &ChainHead{}
+	var links []*ChainLink
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("txn_%d", i)
+		now := time.Now()
+		txn := &Transaction{ID: id, Description: "Payment", CreatedAt: now, EffectiveAt: now}
+		entries := []*LedgerEntry{
+			{ID: id + "_0", TransactionID: id, EntryIndex: 0, AccountID: "acc_1", Amount: 100, Currency: "USD", CreatedAt: now, EffectiveAt: now},
+			{ID: id + "_1", TransactionID: id, EntryIndex: 1, AccountID: "acc_2", Amount: -100, Currency: "USD", CreatedAt: now, EffectiveAt: now},
+		}
+		link := newChainLink(head, txn, entries)
+		head = &ChainHead{Sequence: txn.Sequence, Hash: txn.Hash}
+		links = append(links, link)
+	}
+	return head, links
+}
+
+func TestChainVerifier(t *testing.T) {
+	t.Run("intact chain", func(t *testing.T) {
+		head, links := buildChain(3)
+		verifier := newChainVerifier()
+		verifier.verify(links)
+		result := verifier.finish(head, 0)
+
+		if !result.Valid() {
+			t.Errorf("Expected valid chain, got issues %v", result.Issues)
+		}
+		if result.Verified != 3 {
+			t.Errorf("Expected 3 verified transactions, got %d", result.Verified)
+		}
+	})
+
+	t.Run("edited amount", func(t *testing.T) {
+		head, links := buildChain(3)
+		links[1].Entries[0].Amount = 1
+
+		verifier := newChainVerifier()
+		verifier.verify(links)
+		result := verifier.finish(head, 0)
+
+		if len(result.Issues) != 1 || result.Issues[0].TransactionID != "txn_1" {
+			t.Errorf("Expected one issue on txn_1, got %v", result.Issues)
+		}
+	})
+
+	t.Run("edited entry times", func(t *testing.T) {
+		head, links := buildChain(3)
+		links[0].Entries[1].EffectiveAt = links[0].Entries[1].EffectiveAt.AddDate(0, -1, 0)
+		links[2].Entries[0].CreatedAt = links[2].Entries[0].CreatedAt.Add(time.Hour)
+
+		verifier := newChainVerifier()
+		verifier.verify(links)
+		result := verifier.finish(head, 0)
+
+		if len(result.Issues) != 2 || result.Issues[0].TransactionID != "txn_0" || result.Issues[1].TransactionID != "txn_2" {
+			t.Errorf("Expected issues on txn_0 and txn_2, got %v", result.Issues)
+		}
+	})
+
+	t.Run("deleted entry", func(t *testing.T) {
+		head, links := buildChain(3)
+		links[2].Entries = links[2].Entries[:1]
+
+		verifier := newChainVerifier()
+		verifier.verify(links)
+		if verifier.finish(head, 0).Valid() {
+			t.Error("Expected deleted entry to be detected")
+		}
+	})
+
+	t.Run("deleted transaction", func(t *testing.T) {
+		head, links := buildChain(3)
+		links = append(links[:1], links[2:]...)
+
+		verifier := newChainVerifier()
+		verifier.verify(links)
+		result := verifier.finish(head, 0)
+
+		if len(result.Issues) != 2 {
+			t.Errorf("Expected sequence gap and hash mismatch, got %v", result.Issues)
+		}
+	})
+
+	t.Run("deleted last transaction", func(t *testing.T) {
+		head, links := buildChain(3)
+
+		verifier := newChainVerifier()
+		verifier.verify(links[:2])
+		if verifier.finish(head, 0).Valid() {
+			t.Error("Expected truncated chain to be detected")
+		}
+	})
+
+	t.Run("unchained transactions", func(t *testing.T) {
+		head, links := buildChain(1)
+
+		verifier := newChainVerifier()
+		verifier.verify(links)
+		if verifier.finish(head, 2).Valid() {
+			t.Error("Expected unchained transactions to invalidate the chain")
+		}
+	})
+}
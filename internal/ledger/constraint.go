@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrInvalidConstraint   = errors.New("invalid balance constraint")
+)
+
+// BalanceConstraint bounds how far an account's available balance may fall
+// on its normal side. NO_OVERDRAFT keeps it at or above zero; CREDIT_LIMIT
+// lets it go down to -CreditLimit.
+type BalanceConstraint string
+
+const (
+	BalanceConstraintNone        BalanceConstraint = "NONE"
+	BalanceConstraintNoOverdraft BalanceConstraint = "NO_OVERDRAFT"
+	BalanceConstraintCreditLimit BalanceConstraint = "CREDIT_LIMIT"
+)
+
+func (c BalanceConstraint) Valid() bool {
+	switch c {
+	case BalanceConstraintNone, BalanceConstraintNoOverdraft, BalanceConstraintCreditLimit:
+		return true
+	}
+	return false
+}
+
+// InsufficientBalanceError names the account a posting would have taken past
+// its constraint. It matches ErrInsufficientBalance with errors.Is.
+type InsufficientBalanceError struct {
+	AccountID string
+	Available int64
+	Limit     int64
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("insufficient balance in account %s: available %d, limit %d", e.AccountID, e.Available, e.Limit)
+}
+
+func (e *InsufficientBalanceError) Is(target error) bool {
+	return target == ErrInsufficientBalance
+}
+
+// ErrorDetails names the account in API errors, whose message stays the
+// static text of ErrInsufficientBalance.
+func (e *InsufficientBalanceError) ErrorDetails() map[string]string {
+	return map[string]string{"account_id": e.AccountID}
+}
+
+// validateConstraint treats the empty constraint as NONE.
+func validateConstraint(constraint BalanceConstraint, creditLimit int64) error {
+	if constraint == "" {
+		constraint = BalanceConstraintNone
+	}
+	if !constraint.Valid() || creditLimit < 0 {
+		return ErrInvalidConstraint
+	}
+	if creditLimit != 0 && constraint != BalanceConstraintCreditLimit {
+		return ErrInvalidConstraint
+	}
+	return nil
+}
+
+// checkBalanceConstraint checks a posting that moves the account's available
+// balance (debit-positive, as in Balance) by delta. Postings that do not
+// reduce the normal-side balance are always allowed so that an account
+// already past its limit can still be topped up.
+func checkBalanceConstraint(account *Account, available, delta int64) error {
+	if account.BalanceConstraint == BalanceConstraintNone || account.BalanceConstraint == "" {
+		return nil
+	}
+
+	after := available + delta
+	if !account.Type.IsDebitNormal() {
+		after = -after
+		delta = -delta
+	}
+	if delta >= 0 {
+		return nil
+	}
+
+	var limit int64
+	if account.BalanceConstraint == BalanceConstraintCreditLimit {
+		limit = account.CreditLimit
+	}
+	if after < -limit {
+		return &InsufficientBalanceError{AccountID: account.ID, Available: after, Limit: limit}
+	}
+	return nil
+}
+
+// availabilityDelta is how one entry moves its account's available balance,
+// following Balance.computeAvailable: posted amounts count in full, pending
+// amounts only when they move the account away from its normal side.
+func availabilityDelta(accountType AccountType, status TransactionStatus, amount int64) int64 {
+	switch status {
+	case TransactionStatusPosted:
+		return amount
+	case TransactionStatusPending:
+		if accountType.IsDebitNormal() && amount < 0 {
+			return amount
+		}
+		if !accountType.IsDebitNormal() && amount > 0 {
+			return amount
+		}
+	}
+	return 0
+}
+
+// availableDeltas nets a posting's effect on available balances per account,
+// including the holds it releases when it commits or voids a pending
+// transaction. Accounts missing from accounts are skipped.
+func (p posting) availableDeltas(accounts map[string]*Account) map[string]int64 {
+	deltas := make(map[string]int64)
+	for _, entry := range p.req.Entries {
+		if account, ok := accounts[entry.AccountID]; ok {
+			deltas[entry.AccountID] += availabilityDelta(account.Type, p.status, entry.Amount)
+		}
+	}
+	for _, held := range p.released {
+		if account, ok := accounts[held.AccountID]; ok {
+			deltas[held.AccountID] -= availabilityDelta(account.Type, TransactionStatusPending, held.Amount)
+		}
+	}
+	return deltas
+}
@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateConstraint(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraint  BalanceConstraint
+		creditLimit int64
+		wantErr     bool
+	}{
+		{"unset", "", 0, false},
+		{"none", BalanceConstraintNone, 0, false},
+		{"no overdraft", BalanceConstraintNoOverdraft, 0, false},
+		{"credit limit", BalanceConstraintCreditLimit, 50000, false},
+		{"limit without credit constraint", BalanceConstraintNoOverdraft, 100, true},
+		{"negative limit", BalanceConstraintCreditLimit, -1, true},
+		{"unknown", "MINIMUM", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConstraint(tt.constraint, tt.creditLimit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConstraint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckBalanceConstraint(t *testing.T) {
+	wallet := &Account{ID: "acc_wallet", Type: AccountTypeLiability, BalanceConstraint: BalanceConstraintNoOverdraft}
+	cash := &Account{ID: "acc_cash", Type: AccountTypeAsset, BalanceConstraint: BalanceConstraintCreditLimit, CreditLimit: 1000}
+	unconstrained := &Account{ID: "acc_other", Type: AccountTypeLiability, BalanceConstraint: BalanceConstraintNone}
+
+	tests := []struct {
+		name      string
+		account   *Account
+		available int64
+		delta     int64
+		wantErr   bool
+	}{
+		{"liability stays in credit", wallet, -500, 400, false},
+		{"liability drained to zero", wallet, -500, 500, false},
+		{"liability overdrawn", wallet, -500, 501, true},
+		{"liability topped up while overdrawn", wallet, 300, -100, false},
+		{"asset within credit limit", cash, 500, -1500, false},
+		{"asset past credit limit", cash, 500, -1501, true},
+		{"unconstrained", unconstrained, 0, 1000000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBalanceConstraint(tt.account, tt.available, tt.delta)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkBalanceConstraint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if !errors.Is(err, ErrInsufficientBalance) {
+				t.Errorf("Expected ErrInsufficientBalance, got %v", err)
+			}
+			var balanceErr *InsufficientBalanceError
+			if !errors.As(err, &balanceErr) || balanceErr.AccountID != tt.account.ID {
+				t.Errorf("Expected error naming %s, got %v", tt.account.ID, err)
+			}
+		})
+	}
+}
+
+func TestAvailableDeltas(t *testing.T) {
+	accounts := map[string]*Account{
+		"acc_cash":   {ID: "acc_cash", Type: AccountTypeAsset},
+		"acc_wallet": {ID: "acc_wallet", Type: AccountTypeLiability},
+	}
+
+	hold := newPosting(PostTransactionRequest{
+		Pending: true,
+		Entries: []EntryRequest{
+			{AccountID: "acc_wallet", Amount: 300},
+			{AccountID: "acc_cash", Amount: -300},
+		},
+	})
+	deltas := hold.availableDeltas(accounts)
+	if deltas["acc_wallet"] != 300 || deltas["acc_cash"] != -300 {
+		t.Errorf("Expected a hold to reduce both sides, got %v", deltas)
+	}
+
+	released := []*LedgerEntry{
+		{AccountID: "acc_wallet", Amount: 300},
+		{AccountID: "acc_cash", Amount: -300},
+	}
+	commit := posting{
+		req: PostTransactionRequest{Entries: []EntryRequest{
+			{AccountID: "acc_wallet", Amount: 200},
+			{AccountID: "acc_cash", Amount: -200},
+			{AccountID: "acc_missing", Amount: 0},
+		}},
+		status:   TransactionStatusPosted,
+		released: released,
+	}
+	deltas = commit.availableDeltas(accounts)
+	if deltas["acc_wallet"] != -100 || deltas["acc_cash"] != 100 {
+		t.Errorf("Expected a partial commit to release the remainder, got %v", deltas)
+	}
+	if _, ok := deltas["acc_missing"]; ok {
+		t.Error("Expected unknown accounts to be skipped")
+	}
+
+	void := posting{status: TransactionStatusVoided, released: released}
+	deltas = void.availableDeltas(accounts)
+	if deltas["acc_wallet"] != -300 || deltas["acc_cash"] != 300 {
+		t.Errorf("Expected a void to release the hold, got %v", deltas)
+	}
+}
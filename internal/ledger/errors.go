@@ -0,0 +1,70 @@
+package ledger
+
+import (
+	"net/http"
+
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+)
+
+const (
+	CodeUnbalancedTransaction  = "unbalanced_transaction"
+	CodeInsufficientBalance    = "insufficient_balance"
+	CodeAccountFrozen          = "account_frozen"
+	CodeAccountClosed          = "account_closed"
+	CodePeriodClosed           = "period_closed"
+	CodeInvalidStateTransition = "invalid_state_transition"
+)
+
+// errorMappings is checked in order. ErrRetriesExhausted comes first because
+// it wraps the error of the last attempt.
+var errorMappings = []platform.ErrorMapping{
+	{Err: ErrRetriesExhausted, Code: platform.CodeConflict, Status: http.StatusConflict, Retryable: true},
+
+	{Err: ErrInvalidRequest, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest},
+	{Err: ErrUnbalancedTransaction, Code: CodeUnbalancedTransaction, Status: http.StatusBadRequest, Param: "entries"},
+	{Err: ErrInvalidAmount, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "amount"},
+	{Err: ErrInvalidCurrency, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "currency"},
+	{Err: ErrInvalidParent, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "parent_id"},
+	{Err: ErrInvalidMetadata, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "metadata"},
+	{Err: ErrInvalidReference, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "references"},
+	{Err: ErrInvalidCursor, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "cursor"},
+	{Err: ErrInvalidQuery, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest},
+	{Err: ErrInvalidPeriod, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest},
+	{Err: ErrInvalidAdjustmentPeriod, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "adjustment_period_id"},
+	{Err: ErrInvalidConstraint, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest},
+	{Err: ErrInvalidTemplate, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest},
+	{Err: ErrInvalidTemplateParams, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "params"},
+	{Err: ErrCommitExceedsPending, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest, Param: "entries"},
+
+	{Err: ErrAccountNotFound, Code: platform.CodeNotFound, Status: http.StatusNotFound},
+	{Err: ErrTransactionNotFound, Code: platform.CodeNotFound, Status: http.StatusNotFound},
+	{Err: ErrPeriodNotFound, Code: platform.CodeNotFound, Status: http.StatusNotFound},
+	{Err: ErrTemplateNotFound, Code: platform.CodeNotFound, Status: http.StatusNotFound},
+
+	{Err: ErrAccountExists, Code: platform.CodeConflict, Status: http.StatusConflict},
+	{Err: ErrAccountCodeExists, Code: platform.CodeConflict, Status: http.StatusConflict, Param: "code"},
+	{Err: ErrTransactionConflict, Code: platform.CodeConflict, Status: http.StatusConflict, Param: "transaction_id"},
+	{Err: ErrTemplateExists, Code: platform.CodeConflict, Status: http.StatusConflict},
+	{Err: ErrPeriodOverlap, Code: platform.CodeConflict, Status: http.StatusConflict},
+	{Err: ErrOpenAdjustmentPeriods, Code: platform.CodeConflict, Status: http.StatusConflict},
+	{Err: ErrTransactionNotPending, Code: platform.CodeConflict, Status: http.StatusConflict, Param: "pending_transaction_id"},
+	{Err: ErrPendingAlreadyResolved, Code: platform.CodeConflict, Status: http.StatusConflict, Param: "pending_transaction_id"},
+	{Err: ErrAccountBalanceNotZero, Code: platform.CodeConflict, Status: http.StatusConflict},
+	{Err: ErrInvalidAccountTransition, Code: CodeInvalidStateTransition, Status: http.StatusConflict},
+	{Err: ErrInvalidPeriodTransition, Code: CodeInvalidStateTransition, Status: http.StatusConflict},
+
+	{Err: ErrInsufficientBalance, Code: CodeInsufficientBalance, Status: http.StatusUnprocessableEntity},
+	{Err: ErrAccountFrozen, Code: CodeAccountFrozen, Status: http.StatusUnprocessableEntity},
+	{Err: ErrAccountClosed, Code: CodeAccountClosed, Status: http.StatusUnprocessableEntity},
+	{Err: ErrPeriodClosed, Code: CodePeriodClosed, Status: http.StatusUnprocessableEntity, Param: "effective_at"},
+}
+
+// ToAPIError maps a ledger failure onto the API error model.
+func ToAPIError(err error) *platform.Error {
+	return platform.MapError(err, errorMappings)
+}
+
+// invalidParam reports a bad request parameter as ErrInvalidRequest.
+func invalidParam(param, message string) error {
+	return platform.InvalidParam(ErrInvalidRequest, param, message)
+}
@@ -0,0 +1,77 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+)
+
+func TestToAPIError_MapsEverySentinel(t *testing.T) {
+	sentinels := []error{
+		ErrUnbalancedTransaction, ErrInvalidAmount, ErrInvalidCurrency, ErrAccountNotFound,
+		ErrTransactionNotFound, ErrTransactionConflict, ErrInvalidRequest,
+		ErrAccountExists, ErrAccountCodeExists, ErrInvalidParent, ErrAccountFrozen, ErrAccountClosed,
+		ErrAccountBalanceNotZero, ErrInvalidAccountTransition,
+		ErrInsufficientBalance, ErrInvalidConstraint,
+		ErrInvalidMetadata, ErrInvalidReference,
+		ErrTransactionNotPending, ErrPendingAlreadyResolved, ErrCommitExceedsPending,
+		ErrPeriodNotFound, ErrPeriodOverlap, ErrPeriodClosed, ErrInvalidPeriodTransition,
+		ErrInvalidAdjustmentPeriod, ErrOpenAdjustmentPeriods,
+		ErrInvalidCursor, ErrInvalidQuery, ErrInvalidPeriod, ErrRetriesExhausted,
+		ErrTemplateNotFound, ErrTemplateExists, ErrInvalidTemplate, ErrInvalidTemplateParams,
+	}
+	for _, sentinel := range sentinels {
+		apiErr := ToAPIError(fmt.Errorf("failed to post transaction: %w", sentinel))
+		if apiErr.Code == platform.CodeInternal || apiErr.Status >= http.StatusInternalServerError {
+			t.Errorf("%v: expected a client error, got %s %d", sentinel, apiErr.Code, apiErr.Status)
+		}
+		if !errors.Is(apiErr, sentinel) {
+			t.Errorf("%v: expected the API error to wrap the sentinel", sentinel)
+		}
+		if apiErr.Message != sentinel.Error() {
+			t.Errorf("%v: expected the sentinel message without the wrapping context, got %q", sentinel, apiErr.Message)
+		}
+	}
+}
+
+func TestToAPIError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      string
+		wantStatus    int
+		wantParam     string
+		wantRetryable bool
+	}{
+		{"validation", PostTransactionRequest{TransactionID: "txn_1"}.Validate(), platform.CodeInvalidRequest, http.StatusBadRequest, "description", false},
+		{"unbalanced", ErrUnbalancedTransaction, CodeUnbalancedTransaction, http.StatusBadRequest, "entries", false},
+		{"insufficient balance", fmt.Errorf("%w: acc_1", ErrInsufficientBalance), CodeInsufficientBalance, http.StatusUnprocessableEntity, "", false},
+		{"retries exhausted", fmt.Errorf("%w after 3 attempts: %w", ErrRetriesExhausted, ErrAccountNotFound), platform.CodeConflict, http.StatusConflict, "", true},
+		{"timeout", fmt.Errorf("failed to post transaction: %w", context.DeadlineExceeded), platform.CodeTimeout, http.StatusServiceUnavailable, "", true},
+		{"unknown", errors.New("connection reset"), platform.CodeInternal, http.StatusInternalServerError, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := ToAPIError(tt.err)
+			if apiErr.Code != tt.wantCode || apiErr.Status != tt.wantStatus || apiErr.Param != tt.wantParam || apiErr.Retryable != tt.wantRetryable {
+				t.Errorf("Expected %s %d param=%q retryable=%v, got %+v", tt.wantCode, tt.wantStatus, tt.wantParam, tt.wantRetryable, apiErr)
+			}
+		})
+	}
+
+	if apiErr := ToAPIError(errors.New("pq: password authentication failed")); apiErr.Message != "internal server error" {
+		t.Errorf("Expected internal details to be hidden, got %q", apiErr.Message)
+	}
+	balanceErr := fmt.Errorf("failed to post transaction: %w", &InsufficientBalanceError{AccountID: "acc_1", Available: -10})
+	apiErr := ToAPIError(balanceErr)
+	if apiErr.Message != ErrInsufficientBalance.Error() || apiErr.Details["account_id"] != "acc_1" {
+		t.Errorf("Expected the static message with account_id acc_1 in details, got %+v", apiErr)
+	}
+	if err := (AccountStatusRequest{}).Validate(); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected validation errors to match ErrInvalidRequest, got %v", err)
+	}
+}
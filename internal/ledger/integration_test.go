@@ -6,9 +6,13 @@ package ledger_test
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/thilakshekharshriyan/playflow/internal/ledger"
+	"github.com/thilakshekharshriyan/playflow/internal/ledger/ledgertest"
 	"github.com/thilakshekharshriyan/playflow/internal/platform"
 	"github.com/thilakshekharshriyan/playflow/internal/testutil"
 )
@@ -175,11 +179,19 @@ func TestLedger_EndToEnd(t *testing.T) {
 
 		_, originalEntries, _ := svc.GetTransaction(ctx, txnReq.TransactionID)
 
-		txnReq.Description = "Modified description"
 		err := svc.PostTransaction(ctx, txnReq)
+		if err != nil {
+			t.Errorf("Expected identical replay to succeed, got %v", err)
+		}
+
+		txnReq.Description = "Modified description"
+		err = svc.PostTransaction(ctx, txnReq)
 		if err == nil {
 			t.Error("Expected error when posting duplicate transaction ID")
 		}
+		if !errors.Is(err, ledger.ErrTransactionConflict) {
+			t.Errorf("Expected ErrTransactionConflict, got %v", err)
+		}
 
 		_, currentEntries, _ := svc.GetTransaction(ctx, txnReq.TransactionID)
 
@@ -331,3 +343,1271 @@ func TestLedger_ValidationRules(t *testing.T) {
 		}
 	})
 }
+
+func TestLedger_Reports(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	start := time.Now().Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		txnReq := ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Payment with fee",
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: 10000, Currency: "USD"},
+				{AccountID: "acc_merchant_payable", Amount: -9700, Currency: "USD"},
+				{AccountID: "acc_platform_fee", Amount: -300, Currency: "USD"},
+			},
+		}
+		if err := svc.PostTransaction(ctx, txnReq); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+	}
+	end := time.Now().Add(time.Minute)
+
+	t.Run("Trial Balance Balances", func(t *testing.T) {
+		report, err := svc.GetTrialBalance(ctx, ledger.ReportPeriod{From: start, To: end})
+		if err != nil {
+			t.Fatalf("Failed to get trial balance: %v", err)
+		}
+		if len(report.Currencies) != 1 {
+			t.Fatalf("Expected 1 currency section, got %d", len(report.Currencies))
+		}
+		usd := report.Currencies[0]
+		if usd.TotalDebits != 30000 || usd.TotalCredits != 30000 {
+			t.Errorf("Expected debits and credits of 30000, got %d and %d", usd.TotalDebits, usd.TotalCredits)
+		}
+		if !report.Balanced() {
+			t.Error("Expected trial balance to balance")
+		}
+	})
+
+	t.Run("Balance Sheet", func(t *testing.T) {
+		report, err := svc.GetBalanceSheet(ctx, end, ledger.TimeBasisBooking)
+		if err != nil {
+			t.Fatalf("Failed to get balance sheet: %v", err)
+		}
+		usd := report.Currencies[0]
+		if usd.TotalAssets != 30000 {
+			t.Errorf("Expected total assets 30000, got %d", usd.TotalAssets)
+		}
+		if usd.TotalLiabilities != 29100 {
+			t.Errorf("Expected total liabilities 29100, got %d", usd.TotalLiabilities)
+		}
+		if !usd.Balanced {
+			t.Error("Expected balance sheet to balance")
+		}
+	})
+
+	t.Run("Income Statement", func(t *testing.T) {
+		report, err := svc.GetIncomeStatement(ctx, ledger.ReportPeriod{From: start, To: end})
+		if err != nil {
+			t.Fatalf("Failed to get income statement: %v", err)
+		}
+		if report.Currencies[0].NetIncome != 900 {
+			t.Errorf("Expected net income 900, got %d", report.Currencies[0].NetIncome)
+		}
+	})
+
+	t.Run("Empty Period", func(t *testing.T) {
+		report, err := svc.GetIncomeStatement(ctx, ledger.ReportPeriod{From: end, To: end.Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("Failed to get income statement: %v", err)
+		}
+		if len(report.Currencies) != 0 {
+			t.Errorf("Expected no currency sections, got %d", len(report.Currencies))
+		}
+	})
+
+	t.Run("Reject Reversed Period", func(t *testing.T) {
+		_, err := svc.GetTrialBalance(ctx, ledger.ReportPeriod{From: end, To: start})
+		if !errors.Is(err, ledger.ErrInvalidPeriod) {
+			t.Errorf("Expected ErrInvalidPeriod, got %v", err)
+		}
+	})
+}
+
+func TestLedger_PaginatedQueries(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		description := "Capture"
+		if i%2 == 1 {
+			description = "Refund"
+		}
+		txnReq := ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   description,
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: int64(100 * (i + 1)), Currency: "USD"},
+				{AccountID: "acc_merchant_payable", Amount: -int64(100 * (i + 1)), Currency: "USD"},
+			},
+		}
+		if err := svc.PostTransaction(ctx, txnReq); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+	}
+
+	t.Run("Walk Entries By Account", func(t *testing.T) {
+		seen := make(map[string]bool)
+		query := ledger.EntryQuery{AccountID: "acc_customer_cash", Limit: 3}
+		pages := 0
+		for {
+			page, err := svc.ListEntries(ctx, query)
+			if err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+			pages++
+			for _, entry := range page.Entries {
+				if seen[entry.ID] {
+					t.Errorf("Entry %s returned twice", entry.ID)
+				}
+				seen[entry.ID] = true
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		if len(seen) != 7 {
+			t.Errorf("Expected 7 entries, got %d", len(seen))
+		}
+		if pages != 3 {
+			t.Errorf("Expected 3 pages, got %d", pages)
+		}
+	})
+
+	t.Run("Filter Entries By Amount Range", func(t *testing.T) {
+		low, high := int64(200), int64(400)
+		page, err := svc.ListEntries(ctx, ledger.EntryQuery{MinAmount: &low, MaxAmount: &high})
+		if err != nil {
+			t.Fatalf("Failed to list entries: %v", err)
+		}
+		if len(page.Entries) != 3 {
+			t.Errorf("Expected 3 entries, got %d", len(page.Entries))
+		}
+	})
+
+	t.Run("Filter Transactions By Description", func(t *testing.T) {
+		page, err := svc.ListTransactions(ctx, ledger.TransactionQuery{Description: "refund"})
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if len(page.Transactions) != 3 {
+			t.Errorf("Expected 3 transactions, got %d", len(page.Transactions))
+		}
+	})
+
+	t.Run("Filter Transactions By Entry", func(t *testing.T) {
+		low := int64(600)
+		page, err := svc.ListTransactions(ctx, ledger.TransactionQuery{
+			AccountID: "acc_customer_cash",
+			Currency:  "USD",
+			MinAmount: &low,
+		})
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if len(page.Transactions) != 2 {
+			t.Errorf("Expected 2 transactions, got %d", len(page.Transactions))
+		}
+		if page.NextCursor != "" {
+			t.Error("Expected no next cursor on the last page")
+		}
+	})
+}
+
+func TestLedger_HashChain(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		txnReq := ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Chained payment",
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: 1000, Currency: "USD"},
+				{AccountID: "acc_merchant_payable", Amount: -1000, Currency: "USD"},
+			},
+		}
+		if err := svc.PostTransaction(ctx, txnReq); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+		ids = append(ids, txnReq.TransactionID)
+	}
+
+	t.Run("Intact Chain Verifies", func(t *testing.T) {
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if !result.Valid() {
+			t.Errorf("Expected valid chain, got issues %v", result.Issues)
+		}
+		if result.Verified != 5 || result.HeadSequence != 5 {
+			t.Errorf("Expected 5 verified transactions at head 5, got %d at %d", result.Verified, result.HeadSequence)
+		}
+
+		txn, _, err := svc.GetTransaction(ctx, ids[4])
+		if err != nil {
+			t.Fatalf("Failed to get transaction: %v", err)
+		}
+		if txn.Hash != result.HeadHash {
+			t.Error("Expected head hash to match last transaction hash")
+		}
+	})
+
+	t.Run("Edited Amount Is Detected", func(t *testing.T) {
+		_, err := testDB.DB.ExecContext(ctx, `UPDATE ledger_entries SET amount = 1 WHERE transaction_id = $1 AND entry_index = 0`, ids[2])
+		if err != nil {
+			t.Fatalf("Failed to tamper with entry: %v", err)
+		}
+
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if result.Valid() {
+			t.Fatal("Expected tampered chain to be invalid")
+		}
+		if result.Issues[0].TransactionID != ids[2] {
+			t.Errorf("Expected issue on %s, got %s", ids[2], result.Issues[0].TransactionID)
+		}
+	})
+
+	t.Run("Edited Entry Effective Time Is Detected", func(t *testing.T) {
+		_, err := testDB.DB.ExecContext(ctx, `UPDATE ledger_entries SET effective_at = effective_at - INTERVAL '40 days' WHERE transaction_id = $1`, ids[1])
+		if err != nil {
+			t.Fatalf("Failed to tamper with entry: %v", err)
+		}
+
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		found := false
+		for _, issue := range result.Issues {
+			if issue.TransactionID == ids[1] {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected issue on %s, got %v", ids[1], result.Issues)
+		}
+	})
+
+	t.Run("Deleted Transaction Is Detected", func(t *testing.T) {
+		testDB.DB.ExecContext(ctx, `DELETE FROM ledger_entries WHERE transaction_id = $1`, ids[3])
+		testDB.DB.ExecContext(ctx, `DELETE FROM transactions WHERE id = $1`, ids[3])
+
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		found := false
+		for _, issue := range result.Issues {
+			if issue.TransactionID == ids[4] {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected gap before %s to be reported, got %v", ids[4], result.Issues)
+		}
+	})
+}
+
+func TestLedger_PendingPostings(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	hold := func(t *testing.T, amount int64) string {
+		txnReq := ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Card authorization hold",
+			Pending:       true,
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_merchant_payable", Amount: amount, Currency: "USD"},
+				{AccountID: "acc_platform_fee", Amount: -amount, Currency: "USD"},
+			},
+		}
+		if err := svc.PostTransaction(ctx, txnReq); err != nil {
+			t.Fatalf("Failed to post pending transaction: %v", err)
+		}
+		return txnReq.TransactionID
+	}
+
+	t.Run("Pending Affects Available Not Posted", func(t *testing.T) {
+		testDB.Truncate(t, "ledger_entries", "transactions")
+		hold(t, 4000)
+
+		balance, err := svc.GetBalance(ctx, "acc_merchant_payable")
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if balance.Posted != 0 {
+			t.Errorf("Expected posted balance 0, got %d", balance.Posted)
+		}
+		if balance.PendingDebits != 4000 {
+			t.Errorf("Expected pending debits 4000, got %d", balance.PendingDebits)
+		}
+		if balance.Available != 4000 {
+			t.Errorf("Expected available 4000, got %d", balance.Available)
+		}
+	})
+
+	t.Run("Commit For Smaller Amount", func(t *testing.T) {
+		testDB.Truncate(t, "ledger_entries", "transactions")
+		pendingID := hold(t, 4000)
+
+		err := svc.CommitPending(ctx, ledger.CommitPendingRequest{
+			TransactionID:        platform.GenerateID("txn"),
+			PendingTransactionID: pendingID,
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_merchant_payable", Amount: 2500, Currency: "USD"},
+				{AccountID: "acc_platform_fee", Amount: -2500, Currency: "USD"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit pending transaction: %v", err)
+		}
+
+		balance, _ := svc.GetBalance(ctx, "acc_merchant_payable")
+		if balance.Posted != 2500 {
+			t.Errorf("Expected posted balance 2500, got %d", balance.Posted)
+		}
+		if balance.PendingDebits != 0 {
+			t.Errorf("Expected hold to be released, got pending debits %d", balance.PendingDebits)
+		}
+	})
+
+	t.Run("Void Releases Hold", func(t *testing.T) {
+		testDB.Truncate(t, "ledger_entries", "transactions")
+		pendingID := hold(t, 4000)
+
+		voidReq := ledger.VoidPendingRequest{
+			TransactionID:        platform.GenerateID("txn"),
+			PendingTransactionID: pendingID,
+		}
+		if err := svc.VoidPending(ctx, voidReq); err != nil {
+			t.Fatalf("Failed to void pending transaction: %v", err)
+		}
+		if err := svc.VoidPending(ctx, voidReq); err != nil {
+			t.Errorf("Expected void replay to succeed, got %v", err)
+		}
+
+		balance, _ := svc.GetBalance(ctx, "acc_merchant_payable")
+		if balance.Posted != 0 || balance.Available != 0 {
+			t.Errorf("Expected zero balances after void, got posted %d available %d", balance.Posted, balance.Available)
+		}
+
+		err := svc.CommitPending(ctx, ledger.CommitPendingRequest{
+			TransactionID:        platform.GenerateID("txn"),
+			PendingTransactionID: pendingID,
+		})
+		if !errors.Is(err, ledger.ErrPendingAlreadyResolved) {
+			t.Errorf("Expected ErrPendingAlreadyResolved, got %v", err)
+		}
+	})
+
+	t.Run("Cannot Commit Posted Transaction", func(t *testing.T) {
+		txnReq := ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Settled payment",
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: 100, Currency: "USD"},
+				{AccountID: "acc_merchant_payable", Amount: -100, Currency: "USD"},
+			},
+		}
+		svc.PostTransaction(ctx, txnReq)
+
+		err := svc.CommitPending(ctx, ledger.CommitPendingRequest{
+			TransactionID:        platform.GenerateID("txn"),
+			PendingTransactionID: txnReq.TransactionID,
+		})
+		if !errors.Is(err, ledger.ErrTransactionNotPending) {
+			t.Errorf("Expected ErrTransactionNotPending, got %v", err)
+		}
+	})
+
+	t.Run("Chain Covers Pending Lifecycle", func(t *testing.T) {
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if !result.Valid() {
+			t.Errorf("Expected valid chain, got issues %v", result.Issues)
+		}
+	})
+}
+
+func TestLedger_ChartOfAccounts(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	accounts := []*ledger.Account{
+		{ID: "acc_assets", Code: "1000", Name: "Assets", Type: ledger.AccountTypeAsset, Currency: "USD"},
+		{ID: "acc_receivables", Code: "1100", Name: "Receivables", Type: ledger.AccountTypeAsset, Currency: "USD", ParentID: "acc_assets"},
+		{ID: "acc_merchant_123", Code: "1101", Name: "Merchant 123", Type: ledger.AccountTypeAsset, Currency: "USD", ParentID: "acc_receivables"},
+		{ID: "acc_merchant_456", Code: "1102", Name: "Merchant 456", Type: ledger.AccountTypeAsset, Currency: "USD", ParentID: "acc_receivables"},
+	}
+	for _, account := range accounts {
+		if err := svc.CreateAccount(ctx, account); err != nil {
+			t.Fatalf("Failed to create account %s: %v", account.ID, err)
+		}
+	}
+
+	t.Run("Reject Invalid Parent", func(t *testing.T) {
+		err := svc.CreateAccount(ctx, &ledger.Account{
+			ID: "acc_bad_child", Name: "Bad", Type: ledger.AccountTypeLiability, Currency: "USD", ParentID: "acc_assets",
+		})
+		if !errors.Is(err, ledger.ErrInvalidParent) {
+			t.Errorf("Expected ErrInvalidParent, got %v", err)
+		}
+
+		err = svc.CreateAccount(ctx, &ledger.Account{
+			ID: "acc_dangling", Name: "Dangling", Type: ledger.AccountTypeAsset, Currency: "USD", ParentID: "acc_missing",
+		})
+		if !errors.Is(err, ledger.ErrInvalidParent) {
+			t.Errorf("Expected ErrInvalidParent, got %v", err)
+		}
+	})
+
+	t.Run("Reject Duplicate Code", func(t *testing.T) {
+		err := svc.CreateAccount(ctx, &ledger.Account{
+			ID: "acc_other", Code: "1101", Name: "Other", Type: ledger.AccountTypeAsset, Currency: "USD",
+		})
+		if !errors.Is(err, ledger.ErrAccountCodeExists) {
+			t.Errorf("Expected ErrAccountCodeExists, got %v", err)
+		}
+	})
+
+	t.Run("List Accounts As Tree", func(t *testing.T) {
+		roots, err := svc.ListAccounts(ctx)
+		if err != nil {
+			t.Fatalf("Failed to list accounts: %v", err)
+		}
+
+		var assets *ledger.AccountNode
+		for _, root := range roots {
+			if root.ID == "acc_assets" {
+				assets = root
+			}
+		}
+		if assets == nil {
+			t.Fatal("Expected acc_assets to be a root")
+		}
+		if len(assets.Children) != 1 || len(assets.Children[0].Children) != 2 {
+			t.Errorf("Expected Assets > Receivables > 2 merchants")
+		}
+	})
+
+	t.Run("Rollup Balance", func(t *testing.T) {
+		for _, accountID := range []string{"acc_merchant_123", "acc_merchant_456"} {
+			txnReq := ledger.PostTransactionRequest{
+				TransactionID: platform.GenerateID("txn"),
+				Description:   "Merchant receivable",
+				Entries: []ledger.EntryRequest{
+					{AccountID: accountID, Amount: 2500, Currency: "USD"},
+					{AccountID: "acc_platform_fee", Amount: -2500, Currency: "USD"},
+				},
+			}
+			if err := svc.PostTransaction(ctx, txnReq); err != nil {
+				t.Fatalf("Failed to post transaction: %v", err)
+			}
+		}
+
+		rollup, err := svc.GetRollupBalance(ctx, "acc_assets")
+		if err != nil {
+			t.Fatalf("Failed to get rollup balance: %v", err)
+		}
+		if rollup.Posted != 5000 {
+			t.Errorf("Expected rollup balance 5000, got %d", rollup.Posted)
+		}
+
+		own, err := svc.GetBalance(ctx, "acc_assets")
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if own.Posted != 0 {
+			t.Errorf("Expected own balance 0, got %d", own.Posted)
+		}
+	})
+}
+
+func TestLedger_AccountingPeriods(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	current, err := svc.CreatePeriod(ctx, ledger.CreatePeriodRequest{
+		Name:     "current",
+		StartsAt: now.Add(-24 * time.Hour),
+		EndsAt:   now.Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create period: %v", err)
+	}
+
+	newRequest := func() ledger.PostTransactionRequest {
+		return ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Period posting",
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: 1000, Currency: "USD"},
+				{AccountID: "acc_merchant_payable", Amount: -1000, Currency: "USD"},
+			},
+		}
+	}
+
+	t.Run("Reject Overlapping Period", func(t *testing.T) {
+		_, err := svc.CreatePeriod(ctx, ledger.CreatePeriodRequest{
+			Name:     "overlap",
+			StartsAt: now,
+			EndsAt:   now.Add(48 * time.Hour),
+		})
+		if !errors.Is(err, ledger.ErrPeriodOverlap) {
+			t.Errorf("Expected ErrPeriodOverlap, got %v", err)
+		}
+	})
+
+	open := newRequest()
+	t.Run("Post Into Open Period", func(t *testing.T) {
+		if err := svc.PostTransaction(ctx, open); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+
+		txn, _, err := svc.GetTransaction(ctx, open.TransactionID)
+		if err != nil {
+			t.Fatalf("Failed to get transaction: %v", err)
+		}
+		if txn.PeriodID != current.ID {
+			t.Errorf("Expected period %s, got %s", current.ID, txn.PeriodID)
+		}
+	})
+
+	t.Run("Close Period With Adjustments", func(t *testing.T) {
+		if _, err := svc.ClosePeriod(ctx, current.ID); !errors.Is(err, ledger.ErrInvalidPeriodTransition) {
+			t.Errorf("Expected ErrInvalidPeriodTransition closing an open period, got %v", err)
+		}
+		if _, err := svc.OpenAdjustmentPeriod(ctx, current.ID, ""); !errors.Is(err, ledger.ErrInvalidAdjustmentPeriod) {
+			t.Errorf("Expected ErrInvalidAdjustmentPeriod for an open parent, got %v", err)
+		}
+
+		if _, err := svc.StartPeriodClose(ctx, current.ID); err != nil {
+			t.Fatalf("Failed to start period close: %v", err)
+		}
+
+		if err := svc.PostTransaction(ctx, newRequest()); !errors.Is(err, ledger.ErrPeriodClosed) {
+			t.Errorf("Expected ErrPeriodClosed while closing, got %v", err)
+		}
+		if err := svc.PostTransaction(ctx, open); err != nil {
+			t.Errorf("Expected replay to succeed after close started, got %v", err)
+		}
+
+		adjustment, err := svc.OpenAdjustmentPeriod(ctx, current.ID, "")
+		if err != nil {
+			t.Fatalf("Failed to open adjustment period: %v", err)
+		}
+
+		late := newRequest()
+		late.AdjustmentPeriodID = adjustment.ID
+		if err := svc.PostTransaction(ctx, late); err != nil {
+			t.Fatalf("Failed to post adjustment: %v", err)
+		}
+		txn, _, err := svc.GetTransaction(ctx, late.TransactionID)
+		if err != nil {
+			t.Fatalf("Failed to get transaction: %v", err)
+		}
+		if txn.PeriodID != adjustment.ID {
+			t.Errorf("Expected adjustment period %s, got %s", adjustment.ID, txn.PeriodID)
+		}
+
+		if _, err := svc.ClosePeriod(ctx, current.ID); !errors.Is(err, ledger.ErrOpenAdjustmentPeriods) {
+			t.Errorf("Expected ErrOpenAdjustmentPeriods, got %v", err)
+		}
+
+		if _, err := svc.StartPeriodClose(ctx, adjustment.ID); err != nil {
+			t.Fatalf("Failed to start adjustment close: %v", err)
+		}
+		if _, err := svc.ClosePeriod(ctx, adjustment.ID); err != nil {
+			t.Fatalf("Failed to close adjustment period: %v", err)
+		}
+		closed, err := svc.ClosePeriod(ctx, current.ID)
+		if err != nil {
+			t.Fatalf("Failed to close period: %v", err)
+		}
+		if closed.Status != ledger.PeriodStatusClosed {
+			t.Errorf("Expected CLOSED, got %s", closed.Status)
+		}
+
+		again := newRequest()
+		again.AdjustmentPeriodID = adjustment.ID
+		if err := svc.PostTransaction(ctx, again); !errors.Is(err, ledger.ErrInvalidAdjustmentPeriod) {
+			t.Errorf("Expected ErrInvalidAdjustmentPeriod after close, got %v", err)
+		}
+		if _, err := svc.ReopenPeriod(ctx, current.ID); !errors.Is(err, ledger.ErrInvalidPeriodTransition) {
+			t.Errorf("Expected closed period to stay closed, got %v", err)
+		}
+	})
+
+	t.Run("List Periods", func(t *testing.T) {
+		periods, err := svc.ListPeriods(ctx)
+		if err != nil {
+			t.Fatalf("Failed to list periods: %v", err)
+		}
+		if len(periods) != 2 {
+			t.Fatalf("Expected 2 periods, got %d", len(periods))
+		}
+		if periods[0].Kind != ledger.PeriodKindRegular || periods[1].Kind != ledger.PeriodKindAdjustment {
+			t.Errorf("Expected regular period before its adjustment period")
+		}
+	})
+}
+
+func TestLedger_EffectiveDates(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	yesterday := now.Add(-24 * time.Hour)
+
+	settlement := ledger.PostTransactionRequest{
+		TransactionID: platform.GenerateID("txn"),
+		Description:   "Late acquirer settlement",
+		EffectiveAt:   yesterday,
+		Entries: []ledger.EntryRequest{
+			{AccountID: "acc_customer_cash", Amount: 5000, Currency: "USD"},
+			{AccountID: "acc_merchant_payable", Amount: -5000, Currency: "USD"},
+		},
+	}
+	if err := svc.PostTransaction(ctx, settlement); err != nil {
+		t.Fatalf("Failed to post backdated transaction: %v", err)
+	}
+
+	t.Run("Stores Both Dates", func(t *testing.T) {
+		txn, entries, err := svc.GetTransaction(ctx, settlement.TransactionID)
+		if err != nil {
+			t.Fatalf("Failed to get transaction: %v", err)
+		}
+		if !txn.EffectiveAt.Before(txn.CreatedAt) {
+			t.Errorf("Expected effective date %v before booking time %v", txn.EffectiveAt, txn.CreatedAt)
+		}
+		for _, entry := range entries {
+			if !entry.EffectiveAt.Equal(txn.EffectiveAt) {
+				t.Errorf("Expected entry effective date %v, got %v", txn.EffectiveAt, entry.EffectiveAt)
+			}
+		}
+	})
+
+	t.Run("Reports By Basis", func(t *testing.T) {
+		day := ledger.ReportPeriod{From: yesterday.Add(-time.Hour), To: yesterday.Add(time.Hour)}
+
+		day.Basis = ledger.TimeBasisBooking
+		booked, err := svc.GetTrialBalance(ctx, day)
+		if err != nil {
+			t.Fatalf("Failed to get trial balance: %v", err)
+		}
+		if len(booked.Currencies) != 0 {
+			t.Errorf("Expected nothing booked yesterday, got %d sections", len(booked.Currencies))
+		}
+
+		day.Basis = ledger.TimeBasisEffective
+		effective, err := svc.GetTrialBalance(ctx, day)
+		if err != nil {
+			t.Fatalf("Failed to get trial balance: %v", err)
+		}
+		if len(effective.Currencies) != 1 || effective.Currencies[0].TotalDebits != 5000 {
+			t.Errorf("Expected settlement effective yesterday")
+		}
+	})
+
+	t.Run("Balance As Of", func(t *testing.T) {
+		asOf := yesterday.Add(time.Hour)
+
+		booked, err := svc.QueryBalance(ctx, ledger.BalanceQuery{AccountID: "acc_customer_cash", AsOf: asOf})
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if booked.Posted != 0 {
+			t.Errorf("Expected booked balance 0 as of yesterday, got %d", booked.Posted)
+		}
+
+		effective, err := svc.QueryBalance(ctx, ledger.BalanceQuery{
+			AccountID: "acc_customer_cash",
+			AsOf:      asOf,
+			Basis:     ledger.TimeBasisEffective,
+		})
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if effective.Posted != 5000 {
+			t.Errorf("Expected effective balance 5000 as of yesterday, got %d", effective.Posted)
+		}
+	})
+
+	t.Run("Reject Backdating Into Closed Period", func(t *testing.T) {
+		period, err := svc.CreatePeriod(ctx, ledger.CreatePeriodRequest{
+			Name:     "last week",
+			StartsAt: now.Add(-7 * 24 * time.Hour),
+			EndsAt:   now.Add(-2 * 24 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create period: %v", err)
+		}
+		if _, err := svc.StartPeriodClose(ctx, period.ID); err != nil {
+			t.Fatalf("Failed to start period close: %v", err)
+		}
+
+		late := ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Very late settlement",
+			EffectiveAt:   now.Add(-3 * 24 * time.Hour),
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: 100, Currency: "USD"},
+				{AccountID: "acc_merchant_payable", Amount: -100, Currency: "USD"},
+			},
+		}
+		if err := svc.PostTransaction(ctx, late); !errors.Is(err, ledger.ErrPeriodClosed) {
+			t.Errorf("Expected ErrPeriodClosed, got %v", err)
+		}
+
+		adjustment, err := svc.OpenAdjustmentPeriod(ctx, period.ID, "")
+		if err != nil {
+			t.Fatalf("Failed to open adjustment period: %v", err)
+		}
+		late.AdjustmentPeriodID = adjustment.ID
+		if err := svc.PostTransaction(ctx, late); err != nil {
+			t.Errorf("Expected adjustment posting to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Chain Verifies", func(t *testing.T) {
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if !result.Valid() {
+			t.Errorf("Expected valid chain, got issues %+v", result.Issues)
+		}
+	})
+}
+
+func TestLedger_Annotations(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	capture := ledger.PostTransactionRequest{
+		TransactionID: platform.GenerateID("txn"),
+		Description:   "Payment capture",
+		Metadata:      map[string]string{"merchant_id": "merchant_123", "channel": "web"},
+		References:    []ledger.ExternalReference{{Type: ledger.ReferencePaymentIntent, ID: "pi_abc"}},
+		Entries: []ledger.EntryRequest{
+			{AccountID: "acc_customer_cash", Amount: 10000, Currency: "USD", Memo: "gross"},
+			{AccountID: "acc_merchant_payable", Amount: -9700, Currency: "USD", Memo: "net to merchant"},
+			{AccountID: "acc_platform_fee", Amount: -300, Currency: "USD", Memo: "fee"},
+		},
+	}
+	refund := ledger.PostTransactionRequest{
+		TransactionID: platform.GenerateID("txn"),
+		Description:   "Refund",
+		Metadata:      map[string]string{"merchant_id": "merchant_123"},
+		References: []ledger.ExternalReference{
+			{Type: ledger.ReferencePaymentIntent, ID: "pi_abc"},
+			{Type: ledger.ReferenceRefund, ID: "re_1"},
+		},
+		Entries: []ledger.EntryRequest{
+			{AccountID: "acc_merchant_payable", Amount: 2000, Currency: "USD"},
+			{AccountID: "acc_customer_cash", Amount: -2000, Currency: "USD"},
+		},
+	}
+	for _, req := range []ledger.PostTransactionRequest{capture, refund} {
+		if err := svc.PostTransaction(ctx, req); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+	}
+
+	t.Run("Round Trip", func(t *testing.T) {
+		txn, entries, err := svc.GetTransaction(ctx, capture.TransactionID)
+		if err != nil {
+			t.Fatalf("Failed to get transaction: %v", err)
+		}
+		if txn.Metadata["channel"] != "web" || len(txn.References) != 1 || txn.References[0].ID != "pi_abc" {
+			t.Errorf("Unexpected annotations: %+v %+v", txn.Metadata, txn.References)
+		}
+		if entries[1].Memo != "net to merchant" {
+			t.Errorf("Expected memo to round trip, got %q", entries[1].Memo)
+		}
+	})
+
+	t.Run("Query By Reference", func(t *testing.T) {
+		page, err := svc.ListTransactions(ctx, ledger.TransactionQuery{
+			Reference: &ledger.ExternalReference{Type: ledger.ReferencePaymentIntent, ID: "pi_abc"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if len(page.Transactions) != 2 {
+			t.Errorf("Expected 2 transactions for pi_abc, got %d", len(page.Transactions))
+		}
+
+		page, err = svc.ListTransactions(ctx, ledger.TransactionQuery{
+			Reference: &ledger.ExternalReference{Type: ledger.ReferenceRefund, ID: "re_1"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if len(page.Transactions) != 1 || page.Transactions[0].ID != refund.TransactionID {
+			t.Errorf("Expected only the refund for re_1")
+		}
+	})
+
+	t.Run("Query By Metadata And Memo", func(t *testing.T) {
+		page, err := svc.ListTransactions(ctx, ledger.TransactionQuery{
+			Metadata: map[string]string{"merchant_id": "merchant_123", "channel": "web"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to list transactions: %v", err)
+		}
+		if len(page.Transactions) != 1 || page.Transactions[0].ID != capture.TransactionID {
+			t.Errorf("Expected only the capture to match metadata")
+		}
+
+		entries, err := svc.ListEntries(ctx, ledger.EntryQuery{Memo: "fee"})
+		if err != nil {
+			t.Fatalf("Failed to list entries: %v", err)
+		}
+		if len(entries.Entries) != 1 || entries.Entries[0].AccountID != "acc_platform_fee" {
+			t.Errorf("Expected the fee entry to match memo")
+		}
+	})
+
+	t.Run("Chain Covers Annotations", func(t *testing.T) {
+		result, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if !result.Valid() {
+			t.Fatalf("Expected valid chain, got issues %+v", result.Issues)
+		}
+
+		_, err = testDB.DB.ExecContext(ctx,
+			`UPDATE transactions SET metadata = '{"merchant_id": "merchant_999"}' WHERE id = $1`, refund.TransactionID)
+		if err != nil {
+			t.Fatalf("Failed to tamper with metadata: %v", err)
+		}
+		result, err = svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if result.Valid() {
+			t.Error("Expected tampered metadata to break the chain")
+		}
+	})
+}
+
+func TestLedger_BalanceConstraints(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	if err := svc.SetBalanceConstraint(ctx, "acc_merchant_payable", ledger.BalanceConstraintNoOverdraft, 0); err != nil {
+		t.Fatalf("Failed to set balance constraint: %v", err)
+	}
+	if err := svc.SetBalanceConstraint(ctx, "acc_missing", ledger.BalanceConstraintNoOverdraft, 0); !errors.Is(err, ledger.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+
+	payout := func(amount int64, pending bool) ledger.PostTransactionRequest {
+		return ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Merchant payout",
+			Pending:       pending,
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_merchant_payable", Amount: amount, Currency: "USD"},
+				{AccountID: "acc_customer_cash", Amount: -amount, Currency: "USD"},
+			},
+		}
+	}
+
+	earned := ledger.PostTransactionRequest{
+		TransactionID: platform.GenerateID("txn"),
+		Description:   "Merchant earnings",
+		Entries: []ledger.EntryRequest{
+			{AccountID: "acc_customer_cash", Amount: 1000, Currency: "USD"},
+			{AccountID: "acc_merchant_payable", Amount: -1000, Currency: "USD"},
+		},
+	}
+	if err := svc.PostTransaction(ctx, earned); err != nil {
+		t.Fatalf("Failed to post earnings: %v", err)
+	}
+
+	t.Run("Reject Overdraft", func(t *testing.T) {
+		err := svc.PostTransaction(ctx, payout(1500, false))
+		if !errors.Is(err, ledger.ErrInsufficientBalance) {
+			t.Fatalf("Expected ErrInsufficientBalance, got %v", err)
+		}
+		var balanceErr *ledger.InsufficientBalanceError
+		if !errors.As(err, &balanceErr) || balanceErr.AccountID != "acc_merchant_payable" {
+			t.Errorf("Expected error naming acc_merchant_payable, got %v", err)
+		}
+
+		balance, err := svc.GetAccountBalance(ctx, "acc_merchant_payable")
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if balance != -1000 {
+			t.Errorf("Expected rejected payout to leave balance at -1000, got %d", balance)
+		}
+	})
+
+	t.Run("Holds Reserve Funds", func(t *testing.T) {
+		if err := svc.PostTransaction(ctx, payout(600, true)); err != nil {
+			t.Fatalf("Failed to post pending payout: %v", err)
+		}
+		if err := svc.PostTransaction(ctx, payout(600, false)); !errors.Is(err, ledger.ErrInsufficientBalance) {
+			t.Errorf("Expected held funds to be unavailable, got %v", err)
+		}
+		if err := svc.PostTransaction(ctx, payout(400, false)); err != nil {
+			t.Errorf("Expected payout of the remaining balance to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Credit Limit", func(t *testing.T) {
+		if err := svc.SetBalanceConstraint(ctx, "acc_merchant_payable", ledger.BalanceConstraintCreditLimit, 500); err != nil {
+			t.Fatalf("Failed to set credit limit: %v", err)
+		}
+		if err := svc.PostTransaction(ctx, payout(500, false)); err != nil {
+			t.Errorf("Expected payout within credit limit to succeed, got %v", err)
+		}
+		if err := svc.PostTransaction(ctx, payout(1, false)); !errors.Is(err, ledger.ErrInsufficientBalance) {
+			t.Errorf("Expected payout past credit limit to fail, got %v", err)
+		}
+	})
+}
+
+func TestLedger_AccountLifecycle(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	merchant := &ledger.Account{ID: "acc_merchant_999", Name: "Merchant 999", Type: ledger.AccountTypeLiability, Currency: "USD"}
+	if err := svc.CreateAccount(ctx, merchant); err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	transfer := func(amount int64) ledger.PostTransactionRequest {
+		return ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Merchant movement",
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_customer_cash", Amount: amount, Currency: "USD"},
+				{AccountID: merchant.ID, Amount: -amount, Currency: "USD"},
+			},
+		}
+	}
+	statusRequest := ledger.AccountStatusRequest{AccountID: merchant.ID, Reason: "AML review", ChangedBy: "compliance@payflow"}
+
+	if err := svc.PostTransaction(ctx, transfer(1000)); err != nil {
+		t.Fatalf("Failed to fund account: %v", err)
+	}
+
+	t.Run("Unknown Account", func(t *testing.T) {
+		req := transfer(100)
+		req.Entries[1].AccountID = "acc_missing"
+		if err := svc.PostTransaction(ctx, req); !errors.Is(err, ledger.ErrAccountNotFound) {
+			t.Errorf("Expected ErrAccountNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Frozen Accepts Credits Only", func(t *testing.T) {
+		if _, err := svc.FreezeAccount(ctx, statusRequest); err != nil {
+			t.Fatalf("Failed to freeze account: %v", err)
+		}
+		if err := svc.PostTransaction(ctx, transfer(-500)); !errors.Is(err, ledger.ErrAccountFrozen) {
+			t.Errorf("Expected ErrAccountFrozen for a debit, got %v", err)
+		}
+		if err := svc.PostTransaction(ctx, transfer(200)); err != nil {
+			t.Errorf("Expected credit to a frozen account to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Close Requires Zero Balance", func(t *testing.T) {
+		if _, err := svc.CloseAccount(ctx, statusRequest); !errors.Is(err, ledger.ErrAccountBalanceNotZero) {
+			t.Fatalf("Expected ErrAccountBalanceNotZero, got %v", err)
+		}
+		if _, err := svc.UnfreezeAccount(ctx, statusRequest); err != nil {
+			t.Fatalf("Failed to unfreeze account: %v", err)
+		}
+		if err := svc.PostTransaction(ctx, transfer(-1200)); err != nil {
+			t.Fatalf("Failed to drain account: %v", err)
+		}
+		if _, err := svc.CloseAccount(ctx, statusRequest); err != nil {
+			t.Fatalf("Failed to close account: %v", err)
+		}
+		if err := svc.PostTransaction(ctx, transfer(100)); !errors.Is(err, ledger.ErrAccountClosed) {
+			t.Errorf("Expected ErrAccountClosed, got %v", err)
+		}
+		if _, err := svc.UnfreezeAccount(ctx, statusRequest); !errors.Is(err, ledger.ErrInvalidAccountTransition) {
+			t.Errorf("Expected ErrInvalidAccountTransition unfreezing a closed account, got %v", err)
+		}
+		if _, err := svc.ReopenAccount(ctx, statusRequest); err != nil {
+			t.Errorf("Failed to reopen account: %v", err)
+		}
+	})
+
+	t.Run("Audit Trail", func(t *testing.T) {
+		history, err := svc.GetAccountStatusHistory(ctx, merchant.ID)
+		if err != nil {
+			t.Fatalf("Failed to get status history: %v", err)
+		}
+		want := []ledger.AccountStatus{
+			ledger.AccountStatusFrozen,
+			ledger.AccountStatusActive,
+			ledger.AccountStatusClosed,
+			ledger.AccountStatusActive,
+		}
+		if len(history) != len(want) {
+			t.Fatalf("Expected %d status changes, got %d", len(want), len(history))
+		}
+		for i, change := range history {
+			if change.ToStatus != want[i] || change.ChangedBy != "compliance@payflow" {
+				t.Errorf("Unexpected status change %d: %+v", i, change)
+			}
+		}
+	})
+}
+
+func TestLedger_BatchPosting(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	repo := ledger.NewPostgresRepository(testDB.DB)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	if err := svc.SetBalanceConstraint(ctx, "acc_merchant_payable", ledger.BalanceConstraintNoOverdraft, 0); err != nil {
+		t.Fatalf("Failed to set balance constraint: %v", err)
+	}
+
+	movement := func(amount int64) ledger.PostTransactionRequest {
+		return ledger.PostTransactionRequest{
+			TransactionID: platform.GenerateID("txn"),
+			Description:   "Merchant movement",
+			Entries: []ledger.EntryRequest{
+				{AccountID: "acc_merchant_payable", Amount: amount, Currency: "USD"},
+				{AccountID: "acc_customer_cash", Amount: -amount, Currency: "USD"},
+			},
+		}
+	}
+
+	funded := movement(-1000)
+	unbalanced := movement(100)
+	unbalanced.Entries[1].Amount = -50
+	conflicting := funded
+	conflicting.Description = "Changed"
+
+	reqs := []ledger.PostTransactionRequest{
+		funded,
+		movement(600),
+		unbalanced,
+		movement(600),
+		funded,
+		conflicting,
+		movement(400),
+	}
+	results, err := svc.PostTransactions(ctx, reqs)
+	if err != nil {
+		t.Fatalf("Failed to post batch: %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("Expected %d results, got %d", len(reqs), len(results))
+	}
+
+	t.Run("Per Item Results", func(t *testing.T) {
+		for i, result := range results {
+			if result.TransactionID != reqs[i].TransactionID {
+				t.Errorf("result %d is for %s, want %s", i, result.TransactionID, reqs[i].TransactionID)
+			}
+		}
+		if results[0].Err != nil || results[1].Err != nil || results[6].Err != nil {
+			t.Errorf("Expected valid postings to succeed, got %+v", results)
+		}
+		if !errors.Is(results[2].Err, ledger.ErrUnbalancedTransaction) {
+			t.Errorf("Expected ErrUnbalancedTransaction, got %v", results[2].Err)
+		}
+		if !errors.Is(results[3].Err, ledger.ErrInsufficientBalance) {
+			t.Errorf("Expected ErrInsufficientBalance, got %v", results[3].Err)
+		}
+		if !results[4].Replayed || results[4].Err != nil {
+			t.Errorf("Expected duplicate to replay, got %+v", results[4])
+		}
+		if !errors.Is(results[5].Err, ledger.ErrTransactionConflict) {
+			t.Errorf("Expected ErrTransactionConflict, got %v", results[5].Err)
+		}
+	})
+
+	t.Run("Only Accepted Postings Written", func(t *testing.T) {
+		balance, err := svc.GetAccountBalance(ctx, "acc_merchant_payable")
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if balance != 0 {
+			t.Errorf("Expected balance 0, got %d", balance)
+		}
+		if _, _, err := svc.GetTransaction(ctx, reqs[3].TransactionID); !errors.Is(err, ledger.ErrTransactionNotFound) {
+			t.Errorf("Expected rejected posting to be absent, got %v", err)
+		}
+	})
+
+	t.Run("Replay Whole Batch", func(t *testing.T) {
+		again, err := svc.PostTransactions(ctx, reqs)
+		if err != nil {
+			t.Fatalf("Failed to replay batch: %v", err)
+		}
+		for _, i := range []int{0, 1, 4, 6} {
+			if !again[i].Replayed {
+				t.Errorf("Expected result %d to replay, got %+v", i, again[i])
+			}
+		}
+	})
+
+	t.Run("Chain Intact", func(t *testing.T) {
+		verification, err := svc.VerifyChain(ctx)
+		if err != nil {
+			t.Fatalf("Failed to verify chain: %v", err)
+		}
+		if !verification.Valid() || verification.Verified != 3 {
+			t.Errorf("Expected 3 chained transactions, got %+v", verification)
+		}
+	})
+}
+
+type retryCounter struct {
+	retried   atomic.Int64
+	exhausted atomic.Int64
+}
+
+func (c *retryCounter) Retried(int, error)   { c.retried.Add(1) }
+func (c *retryCounter) Exhausted(int, error) { c.exhausted.Add(1) }
+
+func TestLedger_ConcurrentPostings(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	metrics := &retryCounter{}
+	repo := ledger.NewPostgresRepository(testDB.DB,
+		ledger.WithRetryPolicy(ledger.RetryPolicy{MaxAttempts: 20, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}),
+		ledger.WithRetryMetrics(metrics),
+	)
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	const workers = 16
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- svc.PostTransaction(ctx, ledger.PostTransactionRequest{
+				TransactionID: platform.GenerateID("txn"),
+				Description:   "Hot account payment",
+				Entries: []ledger.EntryRequest{
+					{AccountID: "acc_customer_cash", Amount: 100, Currency: "USD"},
+					{AccountID: "acc_merchant_payable", Amount: -100, Currency: "USD"},
+				},
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected concurrent posting to succeed, got %v", err)
+		}
+	}
+	if metrics.exhausted.Load() != 0 {
+		t.Errorf("Expected no exhausted retries, got %d", metrics.exhausted.Load())
+	}
+	t.Logf("%d serialization retries", metrics.retried.Load())
+
+	balance, err := svc.GetAccountBalance(ctx, "acc_customer_cash")
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance != workers*100 {
+		t.Errorf("Expected balance %d, got %d", workers*100, balance)
+	}
+
+	verification, err := svc.VerifyChain(ctx)
+	if err != nil {
+		t.Fatalf("Failed to verify chain: %v", err)
+	}
+	if !verification.Valid() || verification.Verified != workers {
+		t.Errorf("Expected %d chained transactions, got %+v", workers, verification)
+	}
+}
+
+func TestPostgresRepository_Contract(t *testing.T) {
+	ledgertest.RunRepositoryTests(t, func(t *testing.T) ledger.Repository {
+		testDB := testutil.SetupTestDB(t)
+		t.Cleanup(func() { testDB.Close(t) })
+		testDB.ApplyMigrations(t)
+		testDB.Truncate(t, "accounts")
+		return ledger.NewPostgresRepository(testDB.DB)
+	})
+}
@@ -1,3 +1,15 @@
+// Package ledger implements the double-entry ledger behind PlayFlow payments.
+//
+// Every posted transaction is linked into a single hash chain so that the
+// history can be verified end to end. In Postgres the chain head is one row,
+// ledger_chain_head, and each posting locks it with SELECT ... FOR UPDATE
+// until its transaction commits. Ledger writes are therefore serialized
+// system-wide, whatever accounts they touch: throughput is bounded by one
+// posting transaction at a time, not by row contention on accounts.
+// PostTransactions amortizes the lock over a batch and is the way to post at
+// volume. Chaining per account or per shard, or appending hashes
+// asynchronously after commit, would lift the limit at the cost of a weaker
+// or delayed total order.
 package ledger
 
 import (
@@ -12,6 +24,8 @@ var (
 	ErrInvalidCurrency       = errors.New("invalid currency")
 	ErrAccountNotFound       = errors.New("account not found")
 	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrTransactionConflict   = errors.New("transaction ID already posted with a different request")
+	ErrInvalidRequest        = errors.New("invalid request")
 )
 
 type AccountType string
@@ -24,18 +38,32 @@ const (
 )
 
 type Account struct {
-	ID        string
-	Name      string
-	Type      AccountType
-	Currency  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                string
+	Code              string
+	Name              string
+	Type              AccountType
+	Currency          string
+	ParentID          string
+	Status            AccountStatus
+	BalanceConstraint BalanceConstraint
+	CreditLimit       int64
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 type Transaction struct {
-	ID          string
-	Description string
-	CreatedAt   time.Time
+	ID                   string
+	Description          string
+	Status               TransactionStatus
+	PendingTransactionID string
+	PeriodID             string
+	Metadata             map[string]string
+	References           []ExternalReference
+	Sequence             int64
+	PrevHash             string
+	Hash                 string
+	CreatedAt            time.Time
+	EffectiveAt          time.Time
 }
 
 type LedgerEntry struct {
@@ -45,19 +73,31 @@ type LedgerEntry struct {
 	AccountID     string
 	Amount        int64
 	Currency      string
+	Memo          string
 	CreatedAt     time.Time
+	EffectiveAt   time.Time
 }
 
 type PostTransactionRequest struct {
 	TransactionID string
 	Description   string
 	Entries       []EntryRequest
+	Pending       bool
+	Metadata      map[string]string
+	References    []ExternalReference
+	// EffectiveAt backdates (or postdates) the posting; zero means it takes
+	// effect when booked. The accounting period check uses this date.
+	EffectiveAt time.Time
+	// AdjustmentPeriodID books the posting into an open adjustment period
+	// instead of the regular period containing it.
+	AdjustmentPeriodID string
 }
 
 type EntryRequest struct {
 	AccountID string
 	Amount    int64
 	Currency  string
+	Memo      string
 }
 
 func (req PostTransactionRequest) IsBalanced() bool {
@@ -68,22 +108,28 @@ func (req PostTransactionRequest) IsBalanced() bool {
 	return sum == 0
 }
 
+// Hash fingerprints the request so that a replay of an already posted
+// TransactionID can be told apart from a conflicting reuse of the ID.
+func (req PostTransactionRequest) Hash() string {
+	return newPosting(req).hash()
+}
+
 func (req PostTransactionRequest) Validate() error {
 	if req.TransactionID == "" {
-		return errors.New("transaction ID is required")
+		return invalidParam("transaction_id", "transaction ID is required")
 	}
 	if req.Description == "" {
-		return errors.New("description is required")
+		return invalidParam("description", "description is required")
 	}
 	if len(req.Entries) < 2 {
-		return errors.New("at least two entries required for double-entry")
+		return invalidParam("entries", "at least two entries required for double-entry")
 	}
 	if !req.IsBalanced() {
 		return ErrUnbalancedTransaction
 	}
 	for _, entry := range req.Entries {
 		if entry.AccountID == "" {
-			return errors.New("account ID is required")
+			return invalidParam("account_id", "account ID is required")
 		}
 		if entry.Amount == 0 {
 			return ErrInvalidAmount
@@ -92,21 +138,69 @@ func (req PostTransactionRequest) Validate() error {
 			return ErrInvalidCurrency
 		}
 	}
-	return nil
+	if err := validateMetadata(req.Metadata); err != nil {
+		return err
+	}
+	return validateReferences(req.References)
 }
 
 type Repository interface {
 	CreateAccount(ctx context.Context, account *Account) error
 	GetAccount(ctx context.Context, id string) (*Account, error)
 	ListAccounts(ctx context.Context) ([]*Account, error)
+	UpdateBalanceConstraint(ctx context.Context, accountID string, constraint BalanceConstraint, creditLimit int64) error
+	ChangeAccountStatus(ctx context.Context, change *AccountStatusChange) error
+	ListAccountStatusChanges(ctx context.Context, accountID string) ([]*AccountStatusChange, error)
 	PostTransaction(ctx context.Context, req PostTransactionRequest) error
+	PostTransactions(ctx context.Context, reqs []PostTransactionRequest) ([]PostResult, error)
 	GetTransaction(ctx context.Context, id string) (*Transaction, error)
 	GetEntriesByTransaction(ctx context.Context, transactionID string) ([]*LedgerEntry, error)
 	GetEntriesByAccount(ctx context.Context, accountID string, limit int) ([]*LedgerEntry, error)
+	GetAccountBalances(ctx context.Context, filter BalanceFilter) ([]*AccountBalance, error)
+	ListEntries(ctx context.Context, query EntryQuery) (*EntryPage, error)
+	ListTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error)
+	GetChainHead(ctx context.Context) (*ChainHead, error)
+	ListChainLinks(ctx context.Context, afterSequence int64, limit int) ([]*ChainLink, error)
+	CountUnchainedTransactions(ctx context.Context) (int64, error)
+	CommitPending(ctx context.Context, req CommitPendingRequest) error
+	VoidPending(ctx context.Context, req VoidPendingRequest) error
+	GetBalance(ctx context.Context, query BalanceQuery) (*Balance, error)
+	CreatePeriod(ctx context.Context, period *AccountingPeriod) error
+	GetPeriod(ctx context.Context, id string) (*AccountingPeriod, error)
+	ListPeriods(ctx context.Context) ([]*AccountingPeriod, error)
+	TransitionPeriod(ctx context.Context, id string, to PeriodStatus) (*AccountingPeriod, error)
 }
 
 type Service interface {
 	PostTransaction(ctx context.Context, req PostTransactionRequest) error
+	PostTransactions(ctx context.Context, reqs []PostTransactionRequest) ([]PostResult, error)
+	ExpandTemplate(req TemplateRequest) (PostTransactionRequest, error)
+	PostFromTemplate(ctx context.Context, req TemplateRequest) error
 	GetTransaction(ctx context.Context, id string) (*Transaction, []*LedgerEntry, error)
 	GetAccountBalance(ctx context.Context, accountID string) (int64, error)
+	GetBalance(ctx context.Context, accountID string) (*Balance, error)
+	GetRollupBalance(ctx context.Context, accountID string) (*Balance, error)
+	QueryBalance(ctx context.Context, query BalanceQuery) (*Balance, error)
+	CreateAccount(ctx context.Context, account *Account) error
+	ListAccounts(ctx context.Context) ([]*AccountNode, error)
+	SetBalanceConstraint(ctx context.Context, accountID string, constraint BalanceConstraint, creditLimit int64) error
+	FreezeAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error)
+	UnfreezeAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error)
+	CloseAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error)
+	ReopenAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error)
+	GetAccountStatusHistory(ctx context.Context, accountID string) ([]*AccountStatusChange, error)
+	CommitPending(ctx context.Context, req CommitPendingRequest) error
+	VoidPending(ctx context.Context, req VoidPendingRequest) error
+	CreatePeriod(ctx context.Context, req CreatePeriodRequest) (*AccountingPeriod, error)
+	ListPeriods(ctx context.Context) ([]*AccountingPeriod, error)
+	StartPeriodClose(ctx context.Context, id string) (*AccountingPeriod, error)
+	ReopenPeriod(ctx context.Context, id string) (*AccountingPeriod, error)
+	ClosePeriod(ctx context.Context, id string) (*AccountingPeriod, error)
+	OpenAdjustmentPeriod(ctx context.Context, parentID, name string) (*AccountingPeriod, error)
+	GetTrialBalance(ctx context.Context, period ReportPeriod) (*TrialBalance, error)
+	GetBalanceSheet(ctx context.Context, asOf time.Time, basis TimeBasis) (*BalanceSheet, error)
+	GetIncomeStatement(ctx context.Context, period ReportPeriod) (*IncomeStatement, error)
+	ListEntries(ctx context.Context, query EntryQuery) (*EntryPage, error)
+	ListTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error)
+	VerifyChain(ctx context.Context) (*ChainVerification, error)
 }
@@ -2,6 +2,7 @@ package ledger
 
 import (
 	"testing"
+	"time"
 )
 
 func TestPostTransactionRequest_IsBalanced(t *testing.T) {
@@ -114,3 +115,41 @@ func TestPostTransactionRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestPostTransactionRequest_Hash(t *testing.T) {
+	base := PostTransactionRequest{
+		TransactionID: "txn_123",
+		Description:   "Test",
+		Entries: []EntryRequest{
+			{AccountID: "acc_1", Amount: 100, Currency: "USD"},
+			{AccountID: "acc_2", Amount: -100, Currency: "USD"},
+		},
+	}
+
+	replay := base
+	replay.Entries = append([]EntryRequest(nil), base.Entries...)
+	if base.Hash() != replay.Hash() {
+		t.Error("Expected identical requests to hash equal")
+	}
+
+	changedDescription := base
+	changedDescription.Description = "Other"
+	if base.Hash() == changedDescription.Hash() {
+		t.Error("Expected different description to change hash")
+	}
+
+	changedAmount := base
+	changedAmount.Entries = []EntryRequest{
+		{AccountID: "acc_1", Amount: 200, Currency: "USD"},
+		{AccountID: "acc_2", Amount: -200, Currency: "USD"},
+	}
+	if base.Hash() == changedAmount.Hash() {
+		t.Error("Expected different amounts to change hash")
+	}
+
+	backdated := base
+	backdated.EffectiveAt = time.Date(2025, 3, 31, 23, 0, 0, 0, time.UTC)
+	if base.Hash() == backdated.Hash() {
+		t.Error("Expected effective date to change hash")
+	}
+}
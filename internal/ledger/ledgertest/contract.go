@@ -0,0 +1,567 @@
+// Package ledgertest holds the behavior every ledger.Repository must share,
+// so that the in-memory and Postgres repositories can be held to one
+// contract.
+package ledgertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/thilakshekharshriyan/playflow/internal/ledger"
+)
+
+// RunRepositoryTests runs the contract against repositories built by
+// newRepo. Each subtest gets its own, empty repository.
+func RunRepositoryTests(t *testing.T, newRepo func(t *testing.T) ledger.Repository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo ledger.Repository)
+	}{
+		{"Accounts", testAccounts},
+		{"Postings", testPostings},
+		{"PendingPostings", testPendingPostings},
+		{"Constraints", testConstraints},
+		{"AccountStatus", testAccountStatus},
+		{"BatchPosting", testBatchPosting},
+		{"Pagination", testPagination},
+		{"Chain", testChain},
+		{"Periods", testPeriods},
+		{"TimeZones", testTimeZones},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func createAccounts(t *testing.T, repo ledger.Repository, accounts ...*ledger.Account) {
+	t.Helper()
+	for _, account := range accounts {
+		if err := repo.CreateAccount(context.Background(), account); err != nil {
+			t.Fatalf("Failed to create account %s: %v", account.ID, err)
+		}
+	}
+}
+
+func standardAccounts(t *testing.T, repo ledger.Repository) {
+	createAccounts(t, repo,
+		&ledger.Account{ID: "acc_cash", Code: "1000", Name: "Cash", Type: ledger.AccountTypeAsset, Currency: "USD"},
+		&ledger.Account{ID: "acc_payable", Code: "2000", Name: "Payable", Type: ledger.AccountTypeLiability, Currency: "USD"},
+		&ledger.Account{ID: "acc_fees", Code: "4000", Name: "Fees", Type: ledger.AccountTypeRevenue, Currency: "USD"},
+	)
+}
+
+func transfer(id, debit, credit string, amount int64) ledger.PostTransactionRequest {
+	return ledger.PostTransactionRequest{
+		TransactionID: id,
+		Description:   "Transfer " + id,
+		Entries: []ledger.EntryRequest{
+			{AccountID: debit, Amount: amount, Currency: "USD"},
+			{AccountID: credit, Amount: -amount, Currency: "USD"},
+		},
+	}
+}
+
+func testAccounts(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	account, err := repo.GetAccount(ctx, "acc_payable")
+	if err != nil {
+		t.Fatalf("Failed to get account: %v", err)
+	}
+	if account.Status != ledger.AccountStatusActive || account.BalanceConstraint != ledger.BalanceConstraintNone {
+		t.Errorf("Expected defaults to be applied, got %+v", account)
+	}
+	if _, err := repo.GetAccount(ctx, "acc_missing"); err != ledger.ErrAccountNotFound {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+
+	duplicate := &ledger.Account{ID: "acc_cash", Name: "Cash", Type: ledger.AccountTypeAsset, Currency: "USD"}
+	if err := repo.CreateAccount(ctx, duplicate); err != ledger.ErrAccountExists {
+		t.Errorf("Expected ErrAccountExists, got %v", err)
+	}
+	sameCode := &ledger.Account{ID: "acc_cash_2", Code: "1000", Name: "Cash", Type: ledger.AccountTypeAsset, Currency: "USD"}
+	if err := repo.CreateAccount(ctx, sameCode); err != ledger.ErrAccountCodeExists {
+		t.Errorf("Expected ErrAccountCodeExists, got %v", err)
+	}
+
+	orphan := &ledger.Account{ID: "acc_orphan", Name: "Orphan", Type: ledger.AccountTypeAsset, Currency: "USD", ParentID: "acc_missing"}
+	if err := repo.CreateAccount(ctx, orphan); !errors.Is(err, ledger.ErrInvalidParent) {
+		t.Errorf("Expected ErrInvalidParent for a missing parent, got %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	createAccounts(t, repo, &ledger.Account{ID: "acc_newest", Name: "Newest", Type: ledger.AccountTypeAsset, Currency: "USD"})
+	accounts, err := repo.ListAccounts(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list accounts: %v", err)
+	}
+	if len(accounts) != 4 || accounts[0].ID != "acc_newest" {
+		t.Errorf("Expected 4 accounts, newest first, got %d", len(accounts))
+	}
+
+	err = repo.UpdateBalanceConstraint(ctx, "acc_missing", ledger.BalanceConstraintNoOverdraft, 0)
+	if err != ledger.ErrAccountNotFound {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+
+	account.Name = "Changed"
+	if stored, _ := repo.GetAccount(ctx, "acc_payable"); stored.Name != "Payable" {
+		t.Error("Expected returned accounts not to alias stored state")
+	}
+}
+
+func testPostings(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	req := transfer("txn_1", "acc_cash", "acc_payable", 1000)
+	req.Metadata = map[string]string{"order": "A-1"}
+	req.References = []ledger.ExternalReference{{Type: ledger.ReferencePaymentIntent, ID: "pi_1"}}
+	if err := repo.PostTransaction(ctx, req); err != nil {
+		t.Fatalf("Failed to post transaction: %v", err)
+	}
+	if err := repo.PostTransaction(ctx, req); err != nil {
+		t.Errorf("Expected identical replay to succeed, got %v", err)
+	}
+	changed := req
+	changed.Description = "Changed"
+	if err := repo.PostTransaction(ctx, changed); !errors.Is(err, ledger.ErrTransactionConflict) {
+		t.Errorf("Expected ErrTransactionConflict, got %v", err)
+	}
+	if err := repo.PostTransaction(ctx, transfer("txn_2", "acc_cash", "acc_missing", 10)); !errors.Is(err, ledger.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+	if err := repo.PostTransaction(ctx, ledger.PostTransactionRequest{TransactionID: "txn_3"}); err == nil {
+		t.Error("Expected invalid request to be rejected")
+	}
+
+	txn, err := repo.GetTransaction(ctx, "txn_1")
+	if err != nil {
+		t.Fatalf("Failed to get transaction: %v", err)
+	}
+	if txn.Status != ledger.TransactionStatusPosted || txn.Metadata["order"] != "A-1" || len(txn.References) != 1 {
+		t.Errorf("Unexpected transaction: %+v", txn)
+	}
+	if _, err := repo.GetTransaction(ctx, "txn_missing"); err != ledger.ErrTransactionNotFound {
+		t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+	}
+
+	entries, err := repo.GetEntriesByTransaction(ctx, "txn_1")
+	if err != nil {
+		t.Fatalf("Failed to get entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].EntryIndex != 0 || entries[0].AccountID != "acc_cash" || entries[1].Amount != -1000 {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	balance, err := repo.GetBalance(ctx, ledger.BalanceQuery{AccountID: "acc_payable"})
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance.Posted != -1000 || balance.Available != -1000 {
+		t.Errorf("Unexpected balance: %+v", balance)
+	}
+	if _, err := repo.GetBalance(ctx, ledger.BalanceQuery{AccountID: "acc_missing"}); err != ledger.ErrAccountNotFound {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+
+	balances, err := repo.GetAccountBalances(ctx, ledger.BalanceFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get account balances: %v", err)
+	}
+	if len(balances) != 2 || balances[0].AccountID != "acc_cash" || balances[0].Debits != 1000 || balances[1].Credits != 1000 {
+		t.Errorf("Unexpected account balances: %+v", balances)
+	}
+}
+
+func testPendingPostings(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	if err := repo.PostTransaction(ctx, transfer("txn_fund", "acc_cash", "acc_payable", 1000)); err != nil {
+		t.Fatalf("Failed to fund account: %v", err)
+	}
+	hold := transfer("txn_hold", "acc_payable", "acc_cash", 400)
+	hold.Pending = true
+	if err := repo.PostTransaction(ctx, hold); err != nil {
+		t.Fatalf("Failed to post hold: %v", err)
+	}
+
+	balance, err := repo.GetBalance(ctx, ledger.BalanceQuery{AccountID: "acc_payable"})
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance.Posted != -1000 || balance.PendingDebits != 400 || balance.Available != -600 {
+		t.Errorf("Unexpected balance with hold: %+v", balance)
+	}
+
+	commit := ledger.CommitPendingRequest{TransactionID: "txn_commit", PendingTransactionID: "txn_hold"}
+	if err := repo.CommitPending(ctx, commit); err != nil {
+		t.Fatalf("Failed to commit hold: %v", err)
+	}
+	if err := repo.CommitPending(ctx, commit); err != nil {
+		t.Errorf("Expected commit replay to succeed, got %v", err)
+	}
+	void := ledger.VoidPendingRequest{TransactionID: "txn_void", PendingTransactionID: "txn_hold"}
+	if err := repo.VoidPending(ctx, void); !errors.Is(err, ledger.ErrPendingAlreadyResolved) {
+		t.Errorf("Expected ErrPendingAlreadyResolved, got %v", err)
+	}
+	void.PendingTransactionID = "txn_fund"
+	if err := repo.VoidPending(ctx, void); !errors.Is(err, ledger.ErrTransactionNotPending) {
+		t.Errorf("Expected ErrTransactionNotPending, got %v", err)
+	}
+	void.PendingTransactionID = "txn_missing"
+	if err := repo.VoidPending(ctx, void); !errors.Is(err, ledger.ErrTransactionNotFound) {
+		t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+	}
+
+	balance, err = repo.GetBalance(ctx, ledger.BalanceQuery{AccountID: "acc_payable"})
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance.Posted != -600 || balance.PendingDebits != 0 || balance.Available != -600 {
+		t.Errorf("Unexpected balance after commit: %+v", balance)
+	}
+}
+
+func testConstraints(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	if err := repo.UpdateBalanceConstraint(ctx, "acc_payable", ledger.BalanceConstraintNoOverdraft, 0); err != nil {
+		t.Fatalf("Failed to set balance constraint: %v", err)
+	}
+	if err := repo.PostTransaction(ctx, transfer("txn_fund", "acc_cash", "acc_payable", 500)); err != nil {
+		t.Fatalf("Failed to fund account: %v", err)
+	}
+
+	err := repo.PostTransaction(ctx, transfer("txn_overdraw", "acc_payable", "acc_cash", 501))
+	var balanceErr *ledger.InsufficientBalanceError
+	if !errors.As(err, &balanceErr) || balanceErr.AccountID != "acc_payable" {
+		t.Errorf("Expected InsufficientBalanceError for acc_payable, got %v", err)
+	}
+	if err := repo.PostTransaction(ctx, transfer("txn_drain", "acc_payable", "acc_cash", 500)); err != nil {
+		t.Errorf("Expected draining to zero to succeed, got %v", err)
+	}
+}
+
+func testAccountStatus(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	freeze := &ledger.AccountStatusChange{
+		AccountID:  "acc_payable",
+		FromStatus: ledger.AccountStatusActive,
+		ToStatus:   ledger.AccountStatusFrozen,
+		Reason:     "review",
+		ChangedBy:  "ops",
+	}
+	if err := repo.ChangeAccountStatus(ctx, freeze); err != nil {
+		t.Fatalf("Failed to freeze account: %v", err)
+	}
+	if freeze.ID == 0 || freeze.CreatedAt.IsZero() {
+		t.Errorf("Expected status change to be recorded, got %+v", freeze)
+	}
+	again := *freeze
+	again.ID = 0
+	if err := repo.ChangeAccountStatus(ctx, &again); !errors.Is(err, ledger.ErrInvalidAccountTransition) {
+		t.Errorf("Expected ErrInvalidAccountTransition, got %v", err)
+	}
+	missing := ledger.AccountStatusChange{AccountID: "acc_missing", ToStatus: ledger.AccountStatusFrozen}
+	if err := repo.ChangeAccountStatus(ctx, &missing); err != ledger.ErrAccountNotFound {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+
+	if err := repo.PostTransaction(ctx, transfer("txn_debit", "acc_payable", "acc_cash", 10)); !errors.Is(err, ledger.ErrAccountFrozen) {
+		t.Errorf("Expected ErrAccountFrozen, got %v", err)
+	}
+	if err := repo.PostTransaction(ctx, transfer("txn_credit", "acc_cash", "acc_payable", 10)); err != nil {
+		t.Errorf("Expected credit to a frozen account to succeed, got %v", err)
+	}
+
+	closeAccount := &ledger.AccountStatusChange{AccountID: "acc_payable", ToStatus: ledger.AccountStatusClosed, Reason: "done", ChangedBy: "ops"}
+	if err := repo.ChangeAccountStatus(ctx, closeAccount); !errors.Is(err, ledger.ErrAccountBalanceNotZero) {
+		t.Errorf("Expected ErrAccountBalanceNotZero, got %v", err)
+	}
+
+	changes, err := repo.ListAccountStatusChanges(ctx, "acc_payable")
+	if err != nil {
+		t.Fatalf("Failed to list status changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].ToStatus != ledger.AccountStatusFrozen || changes[0].FromStatus != ledger.AccountStatusActive {
+		t.Errorf("Unexpected status changes: %+v", changes)
+	}
+}
+
+func testBatchPosting(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	reqs := []ledger.PostTransactionRequest{
+		transfer("txn_1", "acc_cash", "acc_payable", 100),
+		{TransactionID: "txn_invalid"},
+		transfer("txn_2", "acc_cash", "acc_missing", 100),
+		transfer("txn_1", "acc_cash", "acc_payable", 100),
+		transfer("txn_3", "acc_payable", "acc_fees", 30),
+	}
+	results, err := repo.PostTransactions(ctx, reqs)
+	if err != nil {
+		t.Fatalf("Failed to post batch: %v", err)
+	}
+	if len(results) != len(reqs) {
+		t.Fatalf("Expected %d results, got %d", len(reqs), len(results))
+	}
+	if results[0].Err != nil || results[4].Err != nil {
+		t.Errorf("Expected valid postings to succeed, got %+v", results)
+	}
+	if results[1].Err == nil {
+		t.Error("Expected invalid request to fail")
+	}
+	if !errors.Is(results[2].Err, ledger.ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got %v", results[2].Err)
+	}
+	if !results[3].Replayed {
+		t.Errorf("Expected duplicate within batch to replay, got %+v", results[3])
+	}
+	if _, err := repo.GetTransaction(ctx, "txn_2"); err != ledger.ErrTransactionNotFound {
+		t.Errorf("Expected rejected posting to be absent, got %v", err)
+	}
+}
+
+func testPagination(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	for i := 0; i < 5; i++ {
+		if err := repo.PostTransaction(ctx, transfer(fmt.Sprintf("txn_%d", i), "acc_cash", "acc_payable", int64(100*(i+1)))); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+	}
+
+	var seen []*ledger.LedgerEntry
+	query := ledger.EntryQuery{AccountID: "acc_cash", Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination did not terminate")
+		}
+		page, err := repo.ListEntries(ctx, query)
+		if err != nil {
+			t.Fatalf("Failed to list entries: %v", err)
+		}
+		seen = append(seen, page.Entries...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if len(seen) != 5 {
+		t.Fatalf("Expected 5 entries across pages, got %d", len(seen))
+	}
+	for i := 1; i < len(seen); i++ {
+		prev, cur := seen[i-1], seen[i]
+		if cur.CreatedAt.Before(prev.CreatedAt) || (cur.CreatedAt.Equal(prev.CreatedAt) && cur.ID <= prev.ID) {
+			t.Errorf("Entries out of (created_at, id) order at %d", i)
+		}
+	}
+
+	minAmount := int64(300)
+	txns, err := repo.ListTransactions(ctx, ledger.TransactionQuery{AccountID: "acc_cash", MinAmount: &minAmount, Description: "TRANSFER"})
+	if err != nil {
+		t.Fatalf("Failed to list transactions: %v", err)
+	}
+	if len(txns.Transactions) != 3 || txns.NextCursor != "" {
+		t.Errorf("Expected 3 matching transactions, got %d", len(txns.Transactions))
+	}
+
+	if _, err := repo.ListEntries(ctx, ledger.EntryQuery{Cursor: "not a cursor"}); err != ledger.ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func testChain(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	for i := 0; i < 3; i++ {
+		if err := repo.PostTransaction(ctx, transfer(fmt.Sprintf("txn_%d", i), "acc_cash", "acc_payable", 100)); err != nil {
+			t.Fatalf("Failed to post transaction: %v", err)
+		}
+	}
+
+	head, err := repo.GetChainHead(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get chain head: %v", err)
+	}
+	if head.Sequence != 3 {
+		t.Errorf("Expected head at sequence 3, got %d", head.Sequence)
+	}
+
+	links, err := repo.ListChainLinks(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("Failed to list chain links: %v", err)
+	}
+	if len(links) != 2 || links[0].Transaction.Sequence != 2 || len(links[0].Entries) != 2 {
+		t.Errorf("Unexpected chain links: %+v", links)
+	}
+
+	result, err := ledger.NewService(repo).VerifyChain(ctx)
+	if err != nil {
+		t.Fatalf("Failed to verify chain: %v", err)
+	}
+	if !result.Valid() || result.Verified != 3 {
+		t.Errorf("Expected 3 verified transactions, got %+v", result)
+	}
+}
+
+func testPeriods(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	now := time.Now()
+	current := &ledger.AccountingPeriod{
+		ID:       "per_current",
+		Name:     "Current",
+		Kind:     ledger.PeriodKindRegular,
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(time.Hour),
+		Status:   ledger.PeriodStatusOpen,
+	}
+	if err := repo.CreatePeriod(ctx, current); err != nil {
+		t.Fatalf("Failed to create period: %v", err)
+	}
+	overlapping := &ledger.AccountingPeriod{
+		ID:       "per_overlap",
+		Name:     "Overlap",
+		Kind:     ledger.PeriodKindRegular,
+		StartsAt: now,
+		EndsAt:   now.Add(2 * time.Hour),
+		Status:   ledger.PeriodStatusOpen,
+	}
+	if err := repo.CreatePeriod(ctx, overlapping); err != ledger.ErrPeriodOverlap {
+		t.Errorf("Expected ErrPeriodOverlap, got %v", err)
+	}
+	if _, err := repo.GetPeriod(ctx, "per_missing"); err != ledger.ErrPeriodNotFound {
+		t.Errorf("Expected ErrPeriodNotFound, got %v", err)
+	}
+	if _, err := repo.TransitionPeriod(ctx, "per_current", ledger.PeriodStatusClosed); !errors.Is(err, ledger.ErrInvalidPeriodTransition) {
+		t.Errorf("Expected ErrInvalidPeriodTransition, got %v", err)
+	}
+
+	period, err := repo.TransitionPeriod(ctx, "per_current", ledger.PeriodStatusClosing)
+	if err != nil {
+		t.Fatalf("Failed to start period close: %v", err)
+	}
+	if period.Status != ledger.PeriodStatusClosing {
+		t.Errorf("Expected CLOSING, got %s", period.Status)
+	}
+	if err := repo.PostTransaction(ctx, transfer("txn_late", "acc_cash", "acc_payable", 100)); !errors.Is(err, ledger.ErrPeriodClosed) {
+		t.Errorf("Expected ErrPeriodClosed, got %v", err)
+	}
+
+	periods, err := repo.ListPeriods(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list periods: %v", err)
+	}
+	if len(periods) != 1 || periods[0].ID != "per_current" {
+		t.Errorf("Unexpected periods: %+v", periods)
+	}
+}
+
+// testTimeZones filters with times in non-UTC locations. They must select
+// the same postings as the equivalent UTC instants.
+func testTimeZones(t *testing.T, repo ledger.Repository) {
+	ctx := context.Background()
+	standardAccounts(t, repo)
+
+	if err := repo.PostTransaction(ctx, transfer("txn_tz", "acc_cash", "acc_payable", 100)); err != nil {
+		t.Fatalf("Failed to post transaction: %v", err)
+	}
+	txn, err := repo.GetTransaction(ctx, "txn_tz")
+	if err != nil {
+		t.Fatalf("Failed to get transaction: %v", err)
+	}
+	east := time.FixedZone("UTC+5", 5*60*60)
+	west := time.FixedZone("UTC-7", -7*60*60)
+	before := txn.CreatedAt.Add(-time.Minute)
+	after := txn.CreatedAt.Add(time.Minute)
+
+	for _, zone := range []*time.Location{east, west} {
+		t.Run(zone.String(), func(t *testing.T) {
+			entries, err := repo.ListEntries(ctx, ledger.EntryQuery{AccountID: "acc_cash", From: before.In(zone), To: after.In(zone)})
+			if err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+			if len(entries.Entries) != 1 {
+				t.Errorf("Expected the entry within [From, To), got %d", len(entries.Entries))
+			}
+			entries, err = repo.ListEntries(ctx, ledger.EntryQuery{AccountID: "acc_cash", From: after.In(zone)})
+			if err != nil {
+				t.Fatalf("Failed to list entries: %v", err)
+			}
+			if len(entries.Entries) != 0 {
+				t.Errorf("Expected no entries after From, got %d", len(entries.Entries))
+			}
+
+			txns, err := repo.ListTransactions(ctx, ledger.TransactionQuery{From: before.In(zone), To: after.In(zone)})
+			if err != nil {
+				t.Fatalf("Failed to list transactions: %v", err)
+			}
+			if len(txns.Transactions) != 1 {
+				t.Errorf("Expected the transaction within [From, To), got %d", len(txns.Transactions))
+			}
+
+			balances, err := repo.GetAccountBalances(ctx, ledger.BalanceFilter{From: before.In(zone), To: after.In(zone)})
+			if err != nil {
+				t.Fatalf("Failed to get account balances: %v", err)
+			}
+			var debits int64
+			for _, balance := range balances {
+				if balance.AccountID == "acc_cash" {
+					debits = balance.Debits
+				}
+			}
+			if debits != 100 {
+				t.Errorf("Expected 100 debited within the period, got %d", debits)
+			}
+
+			for asOf, want := range map[time.Time]int64{before.In(zone): 0, after.In(zone): 100} {
+				balance, err := repo.GetBalance(ctx, ledger.BalanceQuery{AccountID: "acc_cash", AsOf: asOf})
+				if err != nil {
+					t.Fatalf("Failed to get balance: %v", err)
+				}
+				if balance.Posted != want {
+					t.Errorf("Expected %d posted as of %v, got %d", want, asOf, balance.Posted)
+				}
+			}
+		})
+	}
+
+	period := &ledger.AccountingPeriod{
+		ID:       "per_east",
+		Name:     "East",
+		Kind:     ledger.PeriodKindRegular,
+		StartsAt: before.In(east),
+		EndsAt:   after.In(east),
+		Status:   ledger.PeriodStatusOpen,
+	}
+	if err := repo.CreatePeriod(ctx, period); err != nil {
+		t.Fatalf("Failed to create period: %v", err)
+	}
+	overlapping := &ledger.AccountingPeriod{
+		ID:       "per_west",
+		Name:     "West",
+		Kind:     ledger.PeriodKindRegular,
+		StartsAt: txn.CreatedAt.In(west),
+		EndsAt:   after.Add(time.Hour).In(west),
+		Status:   ledger.PeriodStatusOpen,
+	}
+	if err := repo.CreatePeriod(ctx, overlapping); err != ledger.ErrPeriodOverlap {
+		t.Errorf("Expected ErrPeriodOverlap across locations, got %v", err)
+	}
+}
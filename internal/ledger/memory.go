@@ -0,0 +1,739 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryRepository is a Repository kept in process memory. It applies the
+// same planning and checks as the Postgres repository, with one mutex in
+// place of row locks and serializable transactions. Values are copied in and
+// out so that callers cannot change stored state behind its back.
+type memoryRepository struct {
+	mu            sync.RWMutex
+	accounts      map[string]*Account
+	accountOrder  []string
+	statusChanges []*AccountStatusChange
+	transactions  map[string]*memoryTransaction
+	chain         []*memoryTransaction
+	periods       map[string]*AccountingPeriod
+	head          ChainHead
+}
+
+type memoryTransaction struct {
+	txn         *Transaction
+	requestHash string
+	entries     []*LedgerEntry
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		accounts:     make(map[string]*Account),
+		transactions: make(map[string]*memoryTransaction),
+		periods:      make(map[string]*AccountingPeriod),
+	}
+}
+
+func (r *memoryRepository) CreateAccount(ctx context.Context, account *Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[account.ID]; ok {
+		return ErrAccountExists
+	}
+	if account.Code != "" {
+		for _, existing := range r.accounts {
+			if existing.Code == account.Code {
+				return ErrAccountCodeExists
+			}
+		}
+	}
+	if account.ParentID != "" {
+		if _, ok := r.accounts[account.ParentID]; !ok {
+			return fmt.Errorf("failed to create account: %w", ErrInvalidParent)
+		}
+	}
+
+	if account.Status == "" {
+		account.Status = AccountStatusActive
+	}
+	if account.BalanceConstraint == "" {
+		account.BalanceConstraint = BalanceConstraintNone
+	}
+	now := chainTime(time.Now())
+	account.CreatedAt = now
+	account.UpdatedAt = now
+
+	stored := *account
+	r.accounts[account.ID] = &stored
+	r.accountOrder = append(r.accountOrder, account.ID)
+	return nil
+}
+
+func (r *memoryRepository) GetAccount(ctx context.Context, id string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (r *memoryRepository) ListAccounts(ctx context.Context) ([]*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var accounts []*Account
+	for i := len(r.accountOrder) - 1; i >= 0; i-- {
+		copied := *r.accounts[r.accountOrder[i]]
+		accounts = append(accounts, &copied)
+	}
+	return accounts, nil
+}
+
+func (r *memoryRepository) UpdateBalanceConstraint(ctx context.Context, accountID string, constraint BalanceConstraint, creditLimit int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[accountID]
+	if !ok {
+		return ErrAccountNotFound
+	}
+	account.BalanceConstraint = constraint
+	account.CreditLimit = creditLimit
+	account.UpdatedAt = chainTime(time.Now())
+	return nil
+}
+
+func (r *memoryRepository) ChangeAccountStatus(ctx context.Context, change *AccountStatusChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[change.AccountID]
+	if !ok {
+		return ErrAccountNotFound
+	}
+	balance := r.balance(account, BalanceQuery{AccountID: account.ID})
+	if err := checkAccountTransition(account, change, balance); err != nil {
+		return err
+	}
+
+	now := chainTime(time.Now())
+	change.FromStatus = account.Status
+	change.ID = int64(len(r.statusChanges) + 1)
+	change.CreatedAt = now
+	account.Status = change.ToStatus
+	account.UpdatedAt = now
+
+	stored := *change
+	r.statusChanges = append(r.statusChanges, &stored)
+	return nil
+}
+
+func (r *memoryRepository) ListAccountStatusChanges(ctx context.Context, accountID string) ([]*AccountStatusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []*AccountStatusChange
+	for _, change := range r.statusChanges {
+		if change.AccountID == accountID {
+			copied := *change
+			changes = append(changes, &copied)
+		}
+	}
+	return changes, nil
+}
+
+func (r *memoryRepository) PostTransaction(ctx context.Context, req PostTransactionRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insertPostings([]posting{newPosting(req)})[0].Err
+}
+
+func (r *memoryRepository) PostTransactions(ctx context.Context, reqs []PostTransactionRequest) ([]PostResult, error) {
+	results := make([]PostResult, len(reqs))
+	var postings []posting
+	var positions []int
+	for i, req := range reqs {
+		results[i].TransactionID = req.TransactionID
+		if err := req.Validate(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		postings = append(postings, newPosting(req))
+		positions = append(positions, i)
+	}
+	if len(postings) == 0 {
+		return results, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, result := range r.insertPostings(postings) {
+		results[positions[i]] = result
+	}
+	return results, nil
+}
+
+func (r *memoryRepository) CommitPending(ctx context.Context, req CommitPendingRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, err := r.pending(req.PendingTransactionID, req.TransactionID)
+	if err != nil {
+		return err
+	}
+	p, err := commitPosting(req, cloneTransaction(pending.txn), cloneEntries(pending.entries))
+	if err != nil {
+		return err
+	}
+	return r.insertPostings([]posting{p})[0].Err
+}
+
+func (r *memoryRepository) VoidPending(ctx context.Context, req VoidPendingRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, err := r.pending(req.PendingTransactionID, req.TransactionID)
+	if err != nil {
+		return err
+	}
+	p := voidPosting(req, cloneTransaction(pending.txn), cloneEntries(pending.entries))
+	return r.insertPostings([]posting{p})[0].Err
+}
+
+func (r *memoryRepository) pending(pendingID, transactionID string) (*memoryTransaction, error) {
+	pending, ok := r.transactions[pendingID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	if err := checkPending(pending.txn, r.resolution(pendingID), transactionID); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// resolution returns the ID of the transaction that committed or voided
+// pendingID, if any.
+func (r *memoryRepository) resolution(pendingID string) string {
+	for _, stored := range r.chain {
+		if stored.txn.PendingTransactionID == pendingID {
+			return stored.txn.ID
+		}
+	}
+	return ""
+}
+
+// insertPostings must be called with the write lock held.
+func (r *memoryRepository) insertPostings(postings []posting) []PostResult {
+	head := r.head
+	state := batchState{
+		head:          &head,
+		now:           chainTime(time.Now()),
+		requestHashes: make(map[string]string),
+		accounts:      make(map[string]*Account, len(r.accounts)),
+		available:     make(map[string]int64),
+	}
+	for _, p := range postings {
+		if stored, ok := r.transactions[p.req.TransactionID]; ok {
+			state.requestHashes[stored.txn.ID] = stored.requestHash
+		}
+	}
+	for _, period := range r.periods {
+		copied := *period
+		state.periods = append(state.periods, &copied)
+	}
+	for id, account := range r.accounts {
+		copied := *account
+		state.accounts[id] = &copied
+		if account.BalanceConstraint != BalanceConstraintNone {
+			state.available[id] = r.balance(account, BalanceQuery{AccountID: id}).Available
+		}
+	}
+
+	plan := planPostings(postings, state)
+
+	entries := plan.entries
+	for i, txn := range plan.transactions {
+		n := 0
+		for n < len(entries) && entries[n].TransactionID == txn.ID {
+			n++
+		}
+		stored := &memoryTransaction{
+			txn:         cloneTransaction(txn),
+			requestHash: plan.requestHashes[i],
+			entries:     cloneEntries(entries[:n]),
+		}
+		entries = entries[n:]
+		r.transactions[txn.ID] = stored
+		r.chain = append(r.chain, stored)
+	}
+	r.head = plan.head
+	return plan.results
+}
+
+func (r *memoryRepository) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.transactions[id]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	return cloneTransaction(stored.txn), nil
+}
+
+func (r *memoryRepository) GetEntriesByTransaction(ctx context.Context, transactionID string) ([]*LedgerEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.transactions[transactionID]
+	if !ok {
+		return nil, nil
+	}
+	return cloneEntries(stored.entries), nil
+}
+
+func (r *memoryRepository) GetEntriesByAccount(ctx context.Context, accountID string, limit int) ([]*LedgerEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []*LedgerEntry
+	for i := len(r.chain) - 1; i >= 0 && len(entries) < limit; i-- {
+		for _, entry := range r.chain[i].entries {
+			if entry.AccountID == accountID && len(entries) < limit {
+				entries = append(entries, cloneEntry(entry))
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (r *memoryRepository) GetBalance(ctx context.Context, query BalanceQuery) (*Balance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[query.AccountID]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	return r.balance(account, query), nil
+}
+
+// balance mirrors the Postgres balance query: posted entries count in full,
+// pending ones only while no resolving transaction precedes AsOf.
+func (r *memoryRepository) balance(account *Account, query BalanceQuery) *Balance {
+	subtree := map[string]bool{account.ID: true}
+	if query.IncludeDescendants {
+		for added := true; added; {
+			added = false
+			for _, candidate := range r.accounts {
+				if !subtree[candidate.ID] && subtree[candidate.ParentID] && candidate.ParentID != "" {
+					subtree[candidate.ID] = true
+					added = true
+				}
+			}
+		}
+	}
+	before := func(createdAt, effectiveAt time.Time) bool {
+		return query.AsOf.IsZero() || query.Basis.pick(createdAt, effectiveAt).Before(query.AsOf)
+	}
+
+	resolved := make(map[string]bool)
+	for _, stored := range r.chain {
+		txn := stored.txn
+		if txn.PendingTransactionID != "" && before(txn.CreatedAt, txn.EffectiveAt) {
+			resolved[txn.PendingTransactionID] = true
+		}
+	}
+
+	balance := &Balance{AccountID: account.ID, AccountType: account.Type}
+	for _, stored := range r.chain {
+		for _, entry := range stored.entries {
+			if !subtree[entry.AccountID] || !before(entry.CreatedAt, entry.EffectiveAt) {
+				continue
+			}
+			switch stored.txn.Status {
+			case TransactionStatusPosted:
+				balance.Posted += entry.Amount
+			case TransactionStatusPending:
+				if resolved[stored.txn.ID] {
+					continue
+				}
+				if entry.Amount > 0 {
+					balance.PendingDebits += entry.Amount
+				} else {
+					balance.PendingCredits -= entry.Amount
+				}
+			}
+		}
+	}
+	balance.computeAvailable()
+	return balance
+}
+
+func (r *memoryRepository) GetAccountBalances(ctx context.Context, filter BalanceFilter) ([]*AccountBalance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type key struct{ accountID, currency string }
+	totals := make(map[key]*AccountBalance)
+	for _, stored := range r.chain {
+		if stored.txn.Status != TransactionStatusPosted {
+			continue
+		}
+		for _, entry := range stored.entries {
+			at := filter.Basis.pick(entry.CreatedAt, entry.EffectiveAt)
+			if (!filter.From.IsZero() && at.Before(filter.From)) || (!filter.To.IsZero() && !at.Before(filter.To)) {
+				continue
+			}
+			k := key{entry.AccountID, entry.Currency}
+			balance, ok := totals[k]
+			if !ok {
+				account := r.accounts[entry.AccountID]
+				balance = &AccountBalance{
+					AccountID:   account.ID,
+					AccountName: account.Name,
+					AccountType: account.Type,
+					Currency:    entry.Currency,
+				}
+				totals[k] = balance
+			}
+			if entry.Amount > 0 {
+				balance.Debits += entry.Amount
+			} else {
+				balance.Credits -= entry.Amount
+			}
+		}
+	}
+
+	var balances []*AccountBalance
+	for _, balance := range totals {
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Currency != balances[j].Currency {
+			return balances[i].Currency < balances[j].Currency
+		}
+		return balances[i].AccountID < balances[j].AccountID
+	})
+	return balances, nil
+}
+
+func (r *memoryRepository) ListEntries(ctx context.Context, query EntryQuery) (*EntryPage, error) {
+	cursor, err := DecodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageSize(query.Limit)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []*LedgerEntry
+	for _, stored := range r.chain {
+		txn := stored.txn
+		if !matchesTransaction(txn, query.Description, query.Status, query.Metadata, query.Reference) {
+			continue
+		}
+		for _, entry := range stored.entries {
+			at := query.Basis.pick(entry.CreatedAt, entry.EffectiveAt)
+			switch {
+			case query.AccountID != "" && entry.AccountID != query.AccountID,
+				query.Currency != "" && entry.Currency != query.Currency,
+				!query.From.IsZero() && at.Before(query.From),
+				!query.To.IsZero() && !at.Before(query.To),
+				query.MinAmount != nil && entry.Amount < *query.MinAmount,
+				query.MaxAmount != nil && entry.Amount > *query.MaxAmount,
+				query.Memo != "" && !containsFold(entry.Memo, query.Memo),
+				cursor != nil && !cursor.before(entry.CreatedAt, entry.ID):
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return keysetLess(entries[i].CreatedAt, entries[i].ID, entries[j].CreatedAt, entries[j].ID)
+	})
+
+	page := &EntryPage{}
+	for _, entry := range entries {
+		if len(page.Entries) == limit {
+			last := page.Entries[limit-1]
+			page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+			break
+		}
+		page.Entries = append(page.Entries, cloneEntry(entry))
+	}
+	return page, nil
+}
+
+func (r *memoryRepository) ListTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error) {
+	cursor, err := DecodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageSize(query.Limit)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entryFilter := query.AccountID != "" || query.Currency != "" || query.MinAmount != nil || query.MaxAmount != nil
+
+	var transactions []*Transaction
+	for _, stored := range r.chain {
+		txn := stored.txn
+		at := query.Basis.pick(txn.CreatedAt, txn.EffectiveAt)
+		switch {
+		case !query.From.IsZero() && at.Before(query.From),
+			!query.To.IsZero() && !at.Before(query.To),
+			!matchesTransaction(txn, query.Description, query.Status, query.Metadata, query.Reference),
+			cursor != nil && !cursor.before(txn.CreatedAt, txn.ID):
+			continue
+		}
+		if entryFilter && !hasMatchingEntry(stored.entries, query) {
+			continue
+		}
+		transactions = append(transactions, txn)
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return keysetLess(transactions[i].CreatedAt, transactions[i].ID, transactions[j].CreatedAt, transactions[j].ID)
+	})
+
+	page := &TransactionPage{}
+	for _, txn := range transactions {
+		if len(page.Transactions) == limit {
+			last := page.Transactions[limit-1]
+			page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+			break
+		}
+		page.Transactions = append(page.Transactions, cloneTransaction(txn))
+	}
+	return page, nil
+}
+
+func hasMatchingEntry(entries []*LedgerEntry, query TransactionQuery) bool {
+	for _, entry := range entries {
+		switch {
+		case query.AccountID != "" && entry.AccountID != query.AccountID,
+			query.Currency != "" && entry.Currency != query.Currency,
+			query.MinAmount != nil && entry.Amount < *query.MinAmount,
+			query.MaxAmount != nil && entry.Amount > *query.MaxAmount:
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func matchesTransaction(txn *Transaction, description string, status TransactionStatus, metadata map[string]string, ref *ExternalReference) bool {
+	if description != "" && !containsFold(txn.Description, description) {
+		return false
+	}
+	if status != "" && txn.Status != status {
+		return false
+	}
+	for key, value := range metadata {
+		if stored, ok := txn.Metadata[key]; !ok || stored != value {
+			return false
+		}
+	}
+	if ref != nil {
+		for _, candidate := range txn.References {
+			if candidate == *ref {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (c *Cursor) before(createdAt time.Time, id string) bool {
+	return keysetLess(c.CreatedAt, c.ID, createdAt, id)
+}
+
+func keysetLess(aTime time.Time, aID string, bTime time.Time, bID string) bool {
+	if !aTime.Equal(bTime) {
+		return aTime.Before(bTime)
+	}
+	return aID < bID
+}
+
+func (r *memoryRepository) GetChainHead(ctx context.Context) (*ChainHead, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	head := r.head
+	return &head, nil
+}
+
+func (r *memoryRepository) ListChainLinks(ctx context.Context, afterSequence int64, limit int) ([]*ChainLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []*ChainLink
+	for _, stored := range r.chain {
+		if stored.txn.Sequence <= afterSequence {
+			continue
+		}
+		if len(links) == limit {
+			break
+		}
+		links = append(links, &ChainLink{
+			Transaction: cloneTransaction(stored.txn),
+			Entries:     cloneEntries(stored.entries),
+		})
+	}
+	return links, nil
+}
+
+// CountUnchainedTransactions is always zero: every posting made through this
+// repository is chained.
+func (r *memoryRepository) CountUnchainedTransactions(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryRepository) CreatePeriod(ctx context.Context, period *AccountingPeriod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if period.Kind == PeriodKindAdjustment {
+		parent, ok := r.periods[period.ParentID]
+		if !ok {
+			return ErrPeriodNotFound
+		}
+		if err := checkAdjustmentParent(parent); err != nil {
+			return err
+		}
+	} else {
+		for _, existing := range r.periods {
+			if existing.Kind == PeriodKindRegular && existing.StartsAt.Before(period.EndsAt) && existing.EndsAt.After(period.StartsAt) {
+				return ErrPeriodOverlap
+			}
+		}
+	}
+
+	now := chainTime(time.Now())
+	period.CreatedAt = now
+	period.UpdatedAt = now
+
+	stored := *period
+	stored.StartsAt = chainTime(period.StartsAt)
+	stored.EndsAt = chainTime(period.EndsAt)
+	r.periods[period.ID] = &stored
+	return nil
+}
+
+func (r *memoryRepository) GetPeriod(ctx context.Context, id string) (*AccountingPeriod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	period, ok := r.periods[id]
+	if !ok {
+		return nil, ErrPeriodNotFound
+	}
+	copied := *period
+	return &copied, nil
+}
+
+func (r *memoryRepository) ListPeriods(ctx context.Context) ([]*AccountingPeriod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var periods []*AccountingPeriod
+	for _, period := range r.periods {
+		copied := *period
+		periods = append(periods, &copied)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		a, b := periods[i], periods[j]
+		if !a.StartsAt.Equal(b.StartsAt) {
+			return a.StartsAt.Before(b.StartsAt)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return periods, nil
+}
+
+func (r *memoryRepository) TransitionPeriod(ctx context.Context, id string, to PeriodStatus) (*AccountingPeriod, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	period, ok := r.periods[id]
+	if !ok {
+		return nil, ErrPeriodNotFound
+	}
+
+	var adjustments []*AccountingPeriod
+	for _, candidate := range r.periods {
+		if candidate.ParentID == id {
+			adjustments = append(adjustments, candidate)
+		}
+	}
+	if err := checkPeriodTransition(period, to, adjustments); err != nil {
+		return nil, err
+	}
+
+	period.Status = to
+	period.UpdatedAt = chainTime(time.Now())
+	copied := *period
+	return &copied, nil
+}
+
+func cloneTransaction(txn *Transaction) *Transaction {
+	copied := *txn
+	copied.Metadata = nil
+	if len(txn.Metadata) > 0 {
+		copied.Metadata = make(map[string]string, len(txn.Metadata))
+		for key, value := range txn.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	copied.References = append([]ExternalReference(nil), txn.References...)
+	return &copied
+}
+
+func cloneEntry(entry *LedgerEntry) *LedgerEntry {
+	copied := *entry
+	return &copied
+}
+
+func cloneEntries(entries []*LedgerEntry) []*LedgerEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	copied := make([]*LedgerEntry, len(entries))
+	for i, entry := range entries {
+		copied[i] = cloneEntry(entry)
+	}
+	return copied
+}
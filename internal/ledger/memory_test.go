@@ -0,0 +1,108 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/internal/ledger"
+	"github.com/thilakshekharshriyan/playflow/internal/ledger/ledgertest"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	ledgertest.RunRepositoryTests(t, func(t *testing.T) ledger.Repository {
+		return ledger.NewMemoryRepository()
+	})
+}
+
+func TestMemoryRepository_ConcurrentPostings(t *testing.T) {
+	repo := ledger.NewMemoryRepository()
+	svc := ledger.NewService(repo)
+	ctx := context.Background()
+
+	for _, account := range []*ledger.Account{
+		{ID: "acc_cash", Name: "Cash", Type: ledger.AccountTypeAsset, Currency: "USD"},
+		{ID: "acc_payable", Name: "Payable", Type: ledger.AccountTypeLiability, Currency: "USD"},
+	} {
+		if err := svc.CreateAccount(ctx, account); err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	const workers = 32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := svc.PostTransaction(ctx, ledger.PostTransactionRequest{
+				TransactionID: fmt.Sprintf("txn_%d", i),
+				Description:   "Concurrent payment",
+				Entries: []ledger.EntryRequest{
+					{AccountID: "acc_cash", Amount: 100, Currency: "USD"},
+					{AccountID: "acc_payable", Amount: -100, Currency: "USD"},
+				},
+			})
+			if err != nil {
+				t.Errorf("Failed to post transaction: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	balance, err := svc.GetAccountBalance(ctx, "acc_cash")
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance != workers*100 {
+		t.Errorf("Expected balance %d, got %d", workers*100, balance)
+	}
+
+	result, err := svc.VerifyChain(ctx)
+	if err != nil {
+		t.Fatalf("Failed to verify chain: %v", err)
+	}
+	if !result.Valid() || result.Verified != workers {
+		t.Errorf("Expected %d verified transactions, got %+v", workers, result)
+	}
+}
+
+func TestMemoryRepository_PostFromTemplate(t *testing.T) {
+	svc := ledger.NewService(ledger.NewMemoryRepository())
+	ctx := context.Background()
+
+	for _, account := range []*ledger.Account{
+		{ID: "acc_customer_cash", Name: "Customer Cash", Type: ledger.AccountTypeAsset, Currency: "USD"},
+		{ID: "acc_merchant_payable", Name: "Merchant Payable", Type: ledger.AccountTypeLiability, Currency: "USD"},
+		{ID: "acc_platform_fee", Name: "Platform Fees", Type: ledger.AccountTypeRevenue, Currency: "USD"},
+	} {
+		if err := svc.CreateAccount(ctx, account); err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	err := svc.PostFromTemplate(ctx, ledger.TemplateRequest{
+		Template:      "capture_with_fee",
+		TransactionID: "txn_capture",
+		Amounts:       map[string]int64{"amount": 10000, "fee": 300},
+		Params:        map[string]string{"merchant": "m_1", "currency": "USD"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to post from template: %v", err)
+	}
+
+	for account, want := range map[string]int64{
+		"acc_customer_cash":    10000,
+		"acc_merchant_payable": -9700,
+		"acc_platform_fee":     -300,
+	} {
+		balance, err := svc.GetAccountBalance(ctx, account)
+		if err != nil {
+			t.Fatalf("Failed to get balance: %v", err)
+		}
+		if balance != want {
+			t.Errorf("Expected %s balance %d, got %d", account, want, balance)
+		}
+	}
+}
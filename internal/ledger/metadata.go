@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrInvalidMetadata  = errors.New("invalid metadata")
+	ErrInvalidReference = errors.New("invalid external reference")
+)
+
+// ReferenceType names the kind of object outside the ledger that caused a
+// transaction, so ledger lines can be traced back without parsing
+// descriptions.
+type ReferenceType string
+
+const (
+	ReferencePaymentIntent ReferenceType = "payment_intent_id"
+	ReferenceRefund        ReferenceType = "refund_id"
+	ReferencePayout        ReferenceType = "payout_id"
+)
+
+func (t ReferenceType) Valid() bool {
+	switch t {
+	case ReferencePaymentIntent, ReferenceRefund, ReferencePayout:
+		return true
+	}
+	return false
+}
+
+type ExternalReference struct {
+	Type ReferenceType `json:"type"`
+	ID   string        `json:"id"`
+}
+
+func validateMetadata(metadata map[string]string) error {
+	for key := range metadata {
+		if key == "" {
+			return ErrInvalidMetadata
+		}
+	}
+	return nil
+}
+
+func validateReferences(refs []ExternalReference) error {
+	seen := make(map[ExternalReference]bool, len(refs))
+	for _, ref := range refs {
+		if !ref.Type.Valid() || ref.ID == "" || seen[ref] {
+			return ErrInvalidReference
+		}
+		seen[ref] = true
+	}
+	return nil
+}
+
+// sortReferences returns refs in a canonical order so that hashes do not
+// depend on the order the caller listed them in.
+func sortReferences(refs []ExternalReference) []ExternalReference {
+	if len(refs) == 0 {
+		return nil
+	}
+	sorted := append([]ExternalReference(nil), refs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Type != sorted[j].Type {
+			return sorted[i].Type < sorted[j].Type
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
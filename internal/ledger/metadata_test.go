@@ -0,0 +1,103 @@
+package ledger
+
+import (
+	"testing"
+)
+
+func TestValidateReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		refs    []ExternalReference
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", []ExternalReference{{Type: ReferencePaymentIntent, ID: "pi_1"}, {Type: ReferenceRefund, ID: "re_1"}}, false},
+		{"unknown type", []ExternalReference{{Type: "invoice_id", ID: "in_1"}}, true},
+		{"missing ID", []ExternalReference{{Type: ReferencePayout}}, true},
+		{"duplicate", []ExternalReference{{Type: ReferenceRefund, ID: "re_1"}, {Type: ReferenceRefund, ID: "re_1"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReferences(tt.refs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateReferences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := validateMetadata(map[string]string{"": "x"}); err != ErrInvalidMetadata {
+		t.Errorf("Expected ErrInvalidMetadata for empty key, got %v", err)
+	}
+}
+
+func TestPostTransactionRequest_HashAnnotations(t *testing.T) {
+	base := PostTransactionRequest{
+		TransactionID: "txn_123",
+		Description:   "Capture",
+		Entries: []EntryRequest{
+			{AccountID: "acc_1", Amount: 100, Currency: "USD"},
+			{AccountID: "acc_2", Amount: -100, Currency: "USD"},
+		},
+		Metadata: map[string]string{"merchant_id": "m_1", "order_id": "o_1"},
+		References: []ExternalReference{
+			{Type: ReferencePaymentIntent, ID: "pi_1"},
+			{Type: ReferencePayout, ID: "po_1"},
+		},
+	}
+
+	reordered := base
+	reordered.References = []ExternalReference{base.References[1], base.References[0]}
+	if base.Hash() != reordered.Hash() {
+		t.Error("Expected reference order not to change hash")
+	}
+
+	withMemo := base
+	withMemo.Entries = []EntryRequest{
+		{AccountID: "acc_1", Amount: 100, Currency: "USD", Memo: "gross"},
+		{AccountID: "acc_2", Amount: -100, Currency: "USD"},
+	}
+	if base.Hash() == withMemo.Hash() {
+		t.Error("Expected entry memo to change hash")
+	}
+
+	otherMetadata := base
+	otherMetadata.Metadata = map[string]string{"merchant_id": "m_2", "order_id": "o_1"}
+	if base.Hash() == otherMetadata.Hash() {
+		t.Error("Expected metadata to change hash")
+	}
+
+	plain := base
+	plain.Metadata = map[string]string{}
+	plain.References = nil
+	unannotated := plain
+	unannotated.Metadata = nil
+	if plain.Hash() != unannotated.Hash() {
+		t.Error("Expected empty and nil metadata to hash equal")
+	}
+}
+
+func TestCommitPosting_CarriesAnnotations(t *testing.T) {
+	pending := &Transaction{
+		ID:          "txn_auth",
+		Description: "Authorization",
+		Status:      TransactionStatusPending,
+		Metadata:    map[string]string{"merchant_id": "m_1"},
+		References:  []ExternalReference{{Type: ReferencePaymentIntent, ID: "pi_1"}},
+	}
+	entries := []*LedgerEntry{
+		{AccountID: "acc_1", Amount: 100, Currency: "USD", Memo: "hold"},
+		{AccountID: "acc_2", Amount: -100, Currency: "USD"},
+	}
+
+	p, err := commitPosting(CommitPendingRequest{TransactionID: "txn_capture", PendingTransactionID: "txn_auth"}, pending, entries)
+	if err != nil {
+		t.Fatalf("commitPosting() error = %v", err)
+	}
+	if p.req.Metadata["merchant_id"] != "m_1" || len(p.req.References) != 1 {
+		t.Errorf("Expected metadata and references to carry over, got %+v", p.req)
+	}
+	if p.req.Entries[0].Memo != "hold" {
+		t.Errorf("Expected entry memo to carry over, got %q", p.req.Entries[0].Memo)
+	}
+}
@@ -0,0 +1,253 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTransactionNotPending  = errors.New("transaction is not pending")
+	ErrPendingAlreadyResolved = errors.New("pending transaction already committed or voided")
+	ErrCommitExceedsPending   = errors.New("commit entries exceed pending entries")
+)
+
+type TransactionStatus string
+
+const (
+	TransactionStatusPosted  TransactionStatus = "POSTED"
+	TransactionStatusPending TransactionStatus = "PENDING"
+	TransactionStatusVoided  TransactionStatus = "VOIDED"
+)
+
+// Balance separates settled funds from holds. Posted covers POSTED
+// transactions only; PendingDebits and PendingCredits are the magnitudes of
+// unresolved PENDING entries; Available is Posted reduced by the holds that
+// move the account away from its normal side.
+type Balance struct {
+	AccountID      string
+	AccountType    AccountType
+	Posted         int64
+	PendingDebits  int64
+	PendingCredits int64
+	Available      int64
+}
+
+func (b *Balance) computeAvailable() {
+	if b.AccountType.IsDebitNormal() {
+		b.Available = b.Posted - b.PendingCredits
+	} else {
+		b.Available = b.Posted + b.PendingDebits
+	}
+}
+
+// BalanceQuery selects an account balance, optionally rolled up over the
+// account's subtree. A non-zero AsOf counts only entries strictly before it,
+// measured on Basis; a pending hold counts if it was still unresolved then.
+type BalanceQuery struct {
+	AccountID          string
+	IncludeDescendants bool
+	AsOf               time.Time
+	Basis              TimeBasis
+}
+
+type CommitPendingRequest struct {
+	TransactionID        string
+	PendingTransactionID string
+	Description          string
+	Entries              []EntryRequest
+	EffectiveAt          time.Time
+}
+
+func (req CommitPendingRequest) Validate() error {
+	if req.TransactionID == "" {
+		return invalidParam("transaction_id", "transaction ID is required")
+	}
+	if req.PendingTransactionID == "" {
+		return invalidParam("pending_transaction_id", "pending transaction ID is required")
+	}
+	if req.TransactionID == req.PendingTransactionID {
+		return invalidParam("transaction_id", "commit transaction ID must differ from pending transaction ID")
+	}
+	return nil
+}
+
+type VoidPendingRequest struct {
+	TransactionID        string
+	PendingTransactionID string
+	Description          string
+}
+
+func (req VoidPendingRequest) Validate() error {
+	if req.TransactionID == "" {
+		return invalidParam("transaction_id", "transaction ID is required")
+	}
+	if req.PendingTransactionID == "" {
+		return invalidParam("pending_transaction_id", "pending transaction ID is required")
+	}
+	if req.TransactionID == req.PendingTransactionID {
+		return invalidParam("transaction_id", "void transaction ID must differ from pending transaction ID")
+	}
+	return nil
+}
+
+// posting is a transaction ready to be written: a POSTED or PENDING request,
+// or the commit/void record that resolves a pending transaction, in which
+// case released holds the pending entries whose holds it releases.
+type posting struct {
+	req       PostTransactionRequest
+	status    TransactionStatus
+	pendingID string
+	released  []*LedgerEntry
+}
+
+func (p posting) hash() string {
+	type entryFingerprint struct {
+		AccountID string `json:"account_id"`
+		Amount    int64  `json:"amount"`
+		Currency  string `json:"currency"`
+		Memo      string `json:"memo,omitempty"`
+	}
+	fingerprint := struct {
+		TransactionID        string              `json:"transaction_id"`
+		Description          string              `json:"description"`
+		Entries              []entryFingerprint  `json:"entries"`
+		Status               TransactionStatus   `json:"status,omitempty"`
+		PendingTransactionID string              `json:"pending_transaction_id,omitempty"`
+		AdjustmentPeriodID   string              `json:"adjustment_period_id,omitempty"`
+		EffectiveAt          string              `json:"effective_at,omitempty"`
+		Metadata             map[string]string   `json:"metadata,omitempty"`
+		References           []ExternalReference `json:"references,omitempty"`
+	}{
+		TransactionID:        p.req.TransactionID,
+		Description:          p.req.Description,
+		PendingTransactionID: p.pendingID,
+		AdjustmentPeriodID:   p.req.AdjustmentPeriodID,
+		Metadata:             p.req.Metadata,
+		References:           sortReferences(p.req.References),
+	}
+	if p.status != TransactionStatusPosted {
+		fingerprint.Status = p.status
+	}
+	if !p.req.EffectiveAt.IsZero() {
+		fingerprint.EffectiveAt = chainTime(p.req.EffectiveAt).Format(time.RFC3339Nano)
+	}
+	for _, entry := range p.req.Entries {
+		fingerprint.Entries = append(fingerprint.Entries, entryFingerprint{
+			AccountID: entry.AccountID,
+			Amount:    entry.Amount,
+			Currency:  entry.Currency,
+			Memo:      entry.Memo,
+		})
+	}
+
+	data, _ := json.Marshal(fingerprint)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newPosting(req PostTransactionRequest) posting {
+	status := TransactionStatusPosted
+	if req.Pending {
+		status = TransactionStatusPending
+	}
+	return posting{req: req, status: status}
+}
+
+// commitPosting builds the POSTED transaction that settles a pending one.
+// Without explicit entries the pending entries are committed in full;
+// otherwise each entry must match a pending entry's account and currency and
+// may only shrink it. Metadata and references carry over from the pending
+// transaction.
+func commitPosting(req CommitPendingRequest, pending *Transaction, pendingEntries []*LedgerEntry) (posting, error) {
+	description := req.Description
+	if description == "" {
+		description = pending.Description
+	}
+
+	entries := req.Entries
+	if len(entries) == 0 {
+		for _, entry := range pendingEntries {
+			entries = append(entries, EntryRequest{
+				AccountID: entry.AccountID,
+				Amount:    entry.Amount,
+				Currency:  entry.Currency,
+				Memo:      entry.Memo,
+			})
+		}
+	}
+
+	p := posting{
+		req: PostTransactionRequest{
+			TransactionID: req.TransactionID,
+			Description:   description,
+			Entries:       entries,
+			EffectiveAt:   req.EffectiveAt,
+			Metadata:      pending.Metadata,
+			References:    pending.References,
+		},
+		status:    TransactionStatusPosted,
+		pendingID: pending.ID,
+		released:  pendingEntries,
+	}
+	if err := p.req.Validate(); err != nil {
+		return posting{}, err
+	}
+
+	used := make([]bool, len(pendingEntries))
+	for _, entry := range entries {
+		matched := false
+		for i, held := range pendingEntries {
+			if used[i] || held.AccountID != entry.AccountID || held.Currency != entry.Currency {
+				continue
+			}
+			if (held.Amount > 0) != (entry.Amount > 0) || abs(entry.Amount) > abs(held.Amount) {
+				continue
+			}
+			used[i] = true
+			matched = true
+			break
+		}
+		if !matched {
+			return posting{}, ErrCommitExceedsPending
+		}
+	}
+	return p, nil
+}
+
+func voidPosting(req VoidPendingRequest, pending *Transaction, pendingEntries []*LedgerEntry) posting {
+	description := req.Description
+	if description == "" {
+		description = "Void: " + pending.Description
+	}
+	return posting{
+		req: PostTransactionRequest{
+			TransactionID: req.TransactionID,
+			Description:   description,
+			Metadata:      pending.Metadata,
+			References:    pending.References,
+		},
+		status:    TransactionStatusVoided,
+		pendingID: pending.ID,
+		released:  pendingEntries,
+	}
+}
+
+func checkPending(pending *Transaction, resolvedBy, transactionID string) error {
+	if pending.Status != TransactionStatusPending {
+		return ErrTransactionNotPending
+	}
+	if resolvedBy != "" && resolvedBy != transactionID {
+		return ErrPendingAlreadyResolved
+	}
+	return nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"testing"
+)
+
+func TestCommitPosting(t *testing.T) {
+	pending := &Transaction{ID: "txn_hold", Description: "Card hold", Status: TransactionStatusPending}
+	held := []*LedgerEntry{
+		{AccountID: "acc_wallet", Amount: 10000, Currency: "USD"},
+		{AccountID: "acc_merchant", Amount: -10000, Currency: "USD"},
+	}
+
+	tests := []struct {
+		name    string
+		entries []EntryRequest
+		wantErr error
+	}{
+		{"full commit by default", nil, nil},
+		{
+			"partial commit",
+			[]EntryRequest{
+				{AccountID: "acc_wallet", Amount: 7500, Currency: "USD"},
+				{AccountID: "acc_merchant", Amount: -7500, Currency: "USD"},
+			},
+			nil,
+		},
+		{
+			"commit exceeds hold",
+			[]EntryRequest{
+				{AccountID: "acc_wallet", Amount: 12000, Currency: "USD"},
+				{AccountID: "acc_merchant", Amount: -12000, Currency: "USD"},
+			},
+			ErrCommitExceedsPending,
+		},
+		{
+			"commit to other account",
+			[]EntryRequest{
+				{AccountID: "acc_other", Amount: 5000, Currency: "USD"},
+				{AccountID: "acc_merchant", Amount: -5000, Currency: "USD"},
+			},
+			ErrCommitExceedsPending,
+		},
+		{
+			"commit flips sign",
+			[]EntryRequest{
+				{AccountID: "acc_wallet", Amount: -5000, Currency: "USD"},
+				{AccountID: "acc_merchant", Amount: 5000, Currency: "USD"},
+			},
+			ErrCommitExceedsPending,
+		},
+		{
+			"unbalanced commit",
+			[]EntryRequest{
+				{AccountID: "acc_wallet", Amount: 5000, Currency: "USD"},
+				{AccountID: "acc_merchant", Amount: -4000, Currency: "USD"},
+			},
+			ErrUnbalancedTransaction,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CommitPendingRequest{TransactionID: "txn_commit", PendingTransactionID: pending.ID, Entries: tt.entries}
+			p, err := commitPosting(req, pending, held)
+			if err != tt.wantErr {
+				t.Fatalf("commitPosting() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.status != TransactionStatusPosted || p.pendingID != pending.ID {
+				t.Errorf("Expected POSTED commit of %s, got %s of %s", pending.ID, p.status, p.pendingID)
+			}
+			if p.req.Description != pending.Description {
+				t.Errorf("Expected description to default to %q, got %q", pending.Description, p.req.Description)
+			}
+		})
+	}
+}
+
+func TestCheckPending(t *testing.T) {
+	pending := &Transaction{ID: "txn_hold", Status: TransactionStatusPending}
+	posted := &Transaction{ID: "txn_posted", Status: TransactionStatusPosted}
+
+	if err := checkPending(pending, "", "txn_commit"); err != nil {
+		t.Errorf("Expected unresolved pending to be accepted, got %v", err)
+	}
+	if err := checkPending(pending, "txn_commit", "txn_commit"); err != nil {
+		t.Errorf("Expected replay of the resolving transaction to be accepted, got %v", err)
+	}
+	if err := checkPending(pending, "txn_void", "txn_commit"); err != ErrPendingAlreadyResolved {
+		t.Errorf("Expected ErrPendingAlreadyResolved, got %v", err)
+	}
+	if err := checkPending(posted, "", "txn_commit"); err != ErrTransactionNotPending {
+		t.Errorf("Expected ErrTransactionNotPending, got %v", err)
+	}
+}
+
+func TestBalance_Available(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance Balance
+		want    int64
+	}{
+		{"asset reduced by pending credits", Balance{AccountType: AccountTypeAsset, Posted: 10000, PendingDebits: 500, PendingCredits: 3000}, 7000},
+		{"liability reduced by pending debits", Balance{AccountType: AccountTypeLiability, Posted: -10000, PendingDebits: 3000, PendingCredits: 500}, -7000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.balance.computeAvailable()
+			if tt.balance.Available != tt.want {
+				t.Errorf("Available = %d, want %d", tt.balance.Available, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package ledger
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPeriodNotFound          = errors.New("accounting period not found")
+	ErrPeriodOverlap           = errors.New("accounting period overlaps an existing period")
+	ErrPeriodClosed            = errors.New("posting falls in a closed accounting period")
+	ErrInvalidPeriodTransition = errors.New("invalid accounting period transition")
+	ErrInvalidAdjustmentPeriod = errors.New("invalid adjustment period")
+	ErrOpenAdjustmentPeriods   = errors.New("accounting period has open adjustment periods")
+)
+
+type PeriodStatus string
+
+const (
+	PeriodStatusOpen    PeriodStatus = "OPEN"
+	PeriodStatusClosing PeriodStatus = "CLOSING"
+	PeriodStatusClosed  PeriodStatus = "CLOSED"
+)
+
+type PeriodKind string
+
+const (
+	PeriodKindRegular    PeriodKind = "REGULAR"
+	PeriodKindAdjustment PeriodKind = "ADJUSTMENT"
+)
+
+// AccountingPeriod covers [StartsAt, EndsAt). Regular periods never overlap.
+// An adjustment period shares its parent's range and, while OPEN, is the only
+// way to post into that range once the parent has left OPEN.
+type AccountingPeriod struct {
+	ID        string
+	Name      string
+	Kind      PeriodKind
+	ParentID  string
+	StartsAt  time.Time
+	EndsAt    time.Time
+	Status    PeriodStatus
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (p *AccountingPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.StartsAt) && t.Before(p.EndsAt)
+}
+
+type CreatePeriodRequest struct {
+	Name     string
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+func (req CreatePeriodRequest) Validate() error {
+	if req.Name == "" {
+		return invalidParam("name", "period name is required")
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() || !req.EndsAt.After(req.StartsAt) {
+		return ErrInvalidPeriod
+	}
+	return nil
+}
+
+type PeriodTransition struct {
+	From PeriodStatus
+	To   PeriodStatus
+}
+
+var allowedPeriodTransitions = map[PeriodTransition]bool{
+	{From: PeriodStatusOpen, To: PeriodStatusClosing}:   true,
+	{From: PeriodStatusClosing, To: PeriodStatusOpen}:   true,
+	{From: PeriodStatusClosing, To: PeriodStatusClosed}: true,
+}
+
+func ValidatePeriodTransition(from, to PeriodStatus) error {
+	if !allowedPeriodTransitions[PeriodTransition{From: from, To: to}] {
+		return ErrInvalidPeriodTransition
+	}
+	return nil
+}
+
+// checkPostingPeriod decides whether a posting effective at the given time
+// may be booked into period: the adjustment period named by the request, or
+// otherwise the regular period containing the effective date (nil when none
+// is defined).
+func checkPostingPeriod(period *AccountingPeriod, adjustmentPeriodID string, effectiveAt time.Time) error {
+	if adjustmentPeriodID != "" {
+		if period == nil || period.Kind != PeriodKindAdjustment || period.Status != PeriodStatusOpen {
+			return ErrInvalidAdjustmentPeriod
+		}
+		if !period.Contains(effectiveAt) {
+			return ErrInvalidAdjustmentPeriod
+		}
+		return nil
+	}
+	if period != nil && period.Status != PeriodStatusOpen {
+		return ErrPeriodClosed
+	}
+	return nil
+}
+
+// checkPeriodTransition validates moving period to status. A period cannot
+// be closed while any of its adjustment periods is still open, so once a
+// regular period is CLOSED nothing can be posted into its range again.
+func checkPeriodTransition(period *AccountingPeriod, to PeriodStatus, adjustments []*AccountingPeriod) error {
+	if err := ValidatePeriodTransition(period.Status, to); err != nil {
+		return err
+	}
+	if to == PeriodStatusClosed {
+		for _, adjustment := range adjustments {
+			if adjustment.Status != PeriodStatusClosed {
+				return ErrOpenAdjustmentPeriods
+			}
+		}
+	}
+	return nil
+}
+
+// checkAdjustmentParent allows adjustment periods only for regular periods
+// that are being closed.
+func checkAdjustmentParent(parent *AccountingPeriod) error {
+	if parent.Kind != PeriodKindRegular || parent.Status != PeriodStatusClosing {
+		return ErrInvalidAdjustmentPeriod
+	}
+	return nil
+}
+
+func newAdjustmentPeriod(id, name string, parent *AccountingPeriod) *AccountingPeriod {
+	if name == "" {
+		name = parent.Name + " adjustments"
+	}
+	return &AccountingPeriod{
+		ID:       id,
+		Name:     name,
+		Kind:     PeriodKindAdjustment,
+		ParentID: parent.ID,
+		StartsAt: parent.StartsAt,
+		EndsAt:   parent.EndsAt,
+		Status:   PeriodStatusOpen,
+	}
+}
@@ -0,0 +1,172 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreatePeriodRequest_Validate(t *testing.T) {
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		req     CreatePeriodRequest
+		wantErr bool
+	}{
+		{"valid", CreatePeriodRequest{Name: "2025-03", StartsAt: march, EndsAt: april}, false},
+		{"missing name", CreatePeriodRequest{StartsAt: march, EndsAt: april}, true},
+		{"missing start", CreatePeriodRequest{Name: "2025-03", EndsAt: april}, true},
+		{"empty range", CreatePeriodRequest{Name: "2025-03", StartsAt: march, EndsAt: march}, true},
+		{"reversed range", CreatePeriodRequest{Name: "2025-03", StartsAt: april, EndsAt: march}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccountingPeriod_Contains(t *testing.T) {
+	period := &AccountingPeriod{
+		StartsAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	if !period.Contains(period.StartsAt) {
+		t.Error("Expected period to contain its start")
+	}
+	if period.Contains(period.EndsAt) {
+		t.Error("Expected period to exclude its end")
+	}
+	if period.Contains(period.StartsAt.Add(-time.Nanosecond)) {
+		t.Error("Expected period to exclude times before its start")
+	}
+}
+
+func TestValidatePeriodTransition(t *testing.T) {
+	tests := []struct {
+		from    PeriodStatus
+		to      PeriodStatus
+		wantErr bool
+	}{
+		{PeriodStatusOpen, PeriodStatusClosing, false},
+		{PeriodStatusClosing, PeriodStatusOpen, false},
+		{PeriodStatusClosing, PeriodStatusClosed, false},
+		{PeriodStatusOpen, PeriodStatusClosed, true},
+		{PeriodStatusClosed, PeriodStatusOpen, true},
+		{PeriodStatusClosed, PeriodStatusClosing, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := ValidatePeriodTransition(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePeriodTransition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPostingPeriod(t *testing.T) {
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	midMarch := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	open := &AccountingPeriod{ID: "per_open", Kind: PeriodKindRegular, Status: PeriodStatusOpen}
+	closing := &AccountingPeriod{ID: "per_closing", Kind: PeriodKindRegular, Status: PeriodStatusClosing}
+	closed := &AccountingPeriod{ID: "per_closed", Kind: PeriodKindRegular, Status: PeriodStatusClosed}
+	adjustment := &AccountingPeriod{
+		ID: "per_adj", Kind: PeriodKindAdjustment, ParentID: "per_closing",
+		StartsAt: march, EndsAt: april, Status: PeriodStatusOpen,
+	}
+	closedAdjustment := &AccountingPeriod{
+		ID: "per_adj_closed", Kind: PeriodKindAdjustment, ParentID: "per_closing",
+		StartsAt: march, EndsAt: april, Status: PeriodStatusClosed,
+	}
+
+	tests := []struct {
+		name         string
+		period       *AccountingPeriod
+		adjustmentID string
+		effectiveAt  time.Time
+		wantErr      error
+	}{
+		{"no period defined", nil, "", midMarch, nil},
+		{"open period", open, "", midMarch, nil},
+		{"closing period", closing, "", midMarch, ErrPeriodClosed},
+		{"closed period", closed, "", midMarch, ErrPeriodClosed},
+		{"open adjustment period", adjustment, "per_adj", midMarch, nil},
+		{"effective date outside adjustment period", adjustment, "per_adj", april, ErrInvalidAdjustmentPeriod},
+		{"closed adjustment period", closedAdjustment, "per_adj_closed", midMarch, ErrInvalidAdjustmentPeriod},
+		{"unknown adjustment period", nil, "per_missing", midMarch, ErrInvalidAdjustmentPeriod},
+		{"regular period as adjustment", open, "per_open", midMarch, ErrInvalidAdjustmentPeriod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPostingPeriod(tt.period, tt.adjustmentID, tt.effectiveAt); err != tt.wantErr {
+				t.Errorf("checkPostingPeriod() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPeriodTransition_OpenAdjustments(t *testing.T) {
+	period := &AccountingPeriod{ID: "per_march", Kind: PeriodKindRegular, Status: PeriodStatusClosing}
+	adjustments := []*AccountingPeriod{
+		{ID: "per_adj_1", Kind: PeriodKindAdjustment, ParentID: "per_march", Status: PeriodStatusClosed},
+		{ID: "per_adj_2", Kind: PeriodKindAdjustment, ParentID: "per_march", Status: PeriodStatusOpen},
+	}
+
+	if err := checkPeriodTransition(period, PeriodStatusClosed, adjustments); err != ErrOpenAdjustmentPeriods {
+		t.Errorf("Expected ErrOpenAdjustmentPeriods, got %v", err)
+	}
+	if err := checkPeriodTransition(period, PeriodStatusOpen, adjustments); err != nil {
+		t.Errorf("Expected reopen to be allowed, got %v", err)
+	}
+
+	adjustments[1].Status = PeriodStatusClosed
+	if err := checkPeriodTransition(period, PeriodStatusClosed, adjustments); err != nil {
+		t.Errorf("Expected close to be allowed once adjustments are closed, got %v", err)
+	}
+}
+
+func TestNewAdjustmentPeriod(t *testing.T) {
+	parent := &AccountingPeriod{
+		ID:       "per_march",
+		Name:     "2025-03",
+		Kind:     PeriodKindRegular,
+		StartsAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		Status:   PeriodStatusOpen,
+	}
+
+	if err := checkAdjustmentParent(parent); err != ErrInvalidAdjustmentPeriod {
+		t.Errorf("Expected open parent to be rejected, got %v", err)
+	}
+
+	parent.Status = PeriodStatusClosing
+	if err := checkAdjustmentParent(parent); err != nil {
+		t.Fatalf("Expected closing parent to be accepted, got %v", err)
+	}
+
+	adjustment := newAdjustmentPeriod("per_adj", "", parent)
+	if adjustment.Kind != PeriodKindAdjustment || adjustment.ParentID != parent.ID || adjustment.Status != PeriodStatusOpen {
+		t.Errorf("Unexpected adjustment period: %+v", adjustment)
+	}
+	if !adjustment.StartsAt.Equal(parent.StartsAt) || !adjustment.EndsAt.Equal(parent.EndsAt) {
+		t.Error("Expected adjustment period to share its parent's range")
+	}
+	if adjustment.Name != "2025-03 adjustments" {
+		t.Errorf("Expected default name, got %s", adjustment.Name)
+	}
+
+	if err := checkAdjustmentParent(adjustment); err != ErrInvalidAdjustmentPeriod {
+		t.Errorf("Expected adjustment of an adjustment period to be rejected, got %v", err)
+	}
+}
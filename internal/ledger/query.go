@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidQuery  = errors.New("invalid query")
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// Cursor is an opaque keyset position. Results are ordered by
+// (created_at, id) ascending and a page starts strictly after its cursor.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
+
+type EntryQuery struct {
+	AccountID   string
+	Currency    string
+	From        time.Time
+	To          time.Time
+	MinAmount   *int64
+	MaxAmount   *int64
+	Description string
+	Status      TransactionStatus
+	Basis       TimeBasis
+	Memo        string
+	Metadata    map[string]string
+	Reference   *ExternalReference
+	Cursor      string
+	Limit       int
+}
+
+func (q EntryQuery) Validate() error {
+	if err := validateAnnotationFilters(q.Metadata, q.Reference); err != nil {
+		return err
+	}
+	return validateQuery(ReportPeriod{From: q.From, To: q.To, Basis: q.Basis}, q.MinAmount, q.MaxAmount, q.Cursor, q.Limit)
+}
+
+type EntryPage struct {
+	Entries    []*LedgerEntry
+	NextCursor string
+}
+
+// TransactionQuery matches transactions by their own fields and, for the
+// entry-level filters (account, currency, amount), by having at least one
+// entry that satisfies all of them.
+type TransactionQuery struct {
+	AccountID   string
+	Currency    string
+	From        time.Time
+	To          time.Time
+	MinAmount   *int64
+	MaxAmount   *int64
+	Description string
+	Status      TransactionStatus
+	Basis       TimeBasis
+	Metadata    map[string]string
+	Reference   *ExternalReference
+	Cursor      string
+	Limit       int
+}
+
+func (q TransactionQuery) Validate() error {
+	if err := validateAnnotationFilters(q.Metadata, q.Reference); err != nil {
+		return err
+	}
+	return validateQuery(ReportPeriod{From: q.From, To: q.To, Basis: q.Basis}, q.MinAmount, q.MaxAmount, q.Cursor, q.Limit)
+}
+
+type TransactionPage struct {
+	Transactions []*Transaction
+	NextCursor   string
+}
+
+func validateQuery(period ReportPeriod, minAmount, maxAmount *int64, cursor string, limit int) error {
+	if err := period.Validate(); err != nil {
+		return err
+	}
+	if minAmount != nil && maxAmount != nil && *minAmount > *maxAmount {
+		return ErrInvalidQuery
+	}
+	if limit < 0 {
+		return ErrInvalidQuery
+	}
+	if _, err := DecodeCursor(cursor); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateAnnotationFilters(metadata map[string]string, ref *ExternalReference) error {
+	if err := validateMetadata(metadata); err != nil {
+		return err
+	}
+	if ref != nil {
+		return validateReferences([]ExternalReference{*ref})
+	}
+	return nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := Cursor{
+		CreatedAt: time.Date(2024, 3, 15, 10, 30, 0, 123456000, time.UTC),
+		ID:        "entry_abc|def",
+	}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) {
+		t.Errorf("Expected created_at %v, got %v", cursor.CreatedAt, decoded.CreatedAt)
+	}
+	if decoded.ID != cursor.ID {
+		t.Errorf("Expected ID %s, got %s", cursor.ID, decoded.ID)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	tests := []string{"not base64!", "bm9waXBl", "MjAyNC0wMy0xNXw="}
+
+	for _, input := range tests {
+		if _, err := DecodeCursor(input); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want %v", input, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestEntryQuery_Validate(t *testing.T) {
+	low, high := int64(100), int64(500)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		query   EntryQuery
+		wantErr bool
+	}{
+		{"empty", EntryQuery{}, false},
+		{"amount range", EntryQuery{MinAmount: &low, MaxAmount: &high}, false},
+		{"inverted amount range", EntryQuery{MinAmount: &high, MaxAmount: &low}, true},
+		{"inverted time range", EntryQuery{From: now, To: now.Add(-time.Hour)}, true},
+		{"negative limit", EntryQuery{Limit: -1}, true},
+		{"bad cursor", EntryQuery{Cursor: "???"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, DefaultPageSize},
+		{25, 25},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		if got := pageSize(tt.limit); got != tt.want {
+			t.Errorf("pageSize(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package ledger
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidPeriod = errors.New("invalid report period")
+)
+
+// Entry amounts follow the debit-positive convention: a positive amount debits
+// the account and a negative amount credits it.
+func (t AccountType) IsDebitNormal() bool {
+	return t == AccountTypeAsset || t == AccountTypeExpense
+}
+
+type ReportPeriod struct {
+	From  time.Time
+	To    time.Time
+	Basis TimeBasis
+}
+
+func (p ReportPeriod) Validate() error {
+	if !p.From.IsZero() && !p.To.IsZero() && !p.To.After(p.From) {
+		return ErrInvalidPeriod
+	}
+	return p.Basis.Validate()
+}
+
+type BalanceFilter struct {
+	From  time.Time
+	To    time.Time
+	Basis TimeBasis
+}
+
+type AccountBalance struct {
+	AccountID   string
+	AccountName string
+	AccountType AccountType
+	Currency    string
+	Debits      int64
+	Credits     int64
+}
+
+func (b *AccountBalance) Net() int64 {
+	return b.Debits - b.Credits
+}
+
+// NormalBalance returns the balance on the account's normal side, so that a
+// positive value means a debit balance for assets and expenses and a credit
+// balance for liabilities and revenue.
+func (b *AccountBalance) NormalBalance() int64 {
+	if b.AccountType.IsDebitNormal() {
+		return b.Net()
+	}
+	return -b.Net()
+}
+
+type TrialBalance struct {
+	Period     ReportPeriod
+	Currencies []*TrialBalanceSection
+}
+
+func (tb *TrialBalance) Balanced() bool {
+	for _, section := range tb.Currencies {
+		if !section.Balanced {
+			return false
+		}
+	}
+	return true
+}
+
+type TrialBalanceSection struct {
+	Currency     string
+	Lines        []*TrialBalanceLine
+	TotalDebits  int64
+	TotalCredits int64
+	Balanced     bool
+}
+
+type TrialBalanceLine struct {
+	AccountID   string
+	AccountName string
+	AccountType AccountType
+	Debit       int64
+	Credit      int64
+}
+
+type ReportLine struct {
+	AccountID   string
+	AccountName string
+	Amount      int64
+}
+
+type BalanceSheet struct {
+	AsOf       time.Time
+	Basis      TimeBasis
+	Currencies []*BalanceSheetSection
+}
+
+type BalanceSheetSection struct {
+	Currency         string
+	Assets           []*ReportLine
+	Liabilities      []*ReportLine
+	TotalAssets      int64
+	TotalLiabilities int64
+	RetainedEarnings int64
+	TotalEquity      int64
+	Balanced         bool
+}
+
+type IncomeStatement struct {
+	Period     ReportPeriod
+	Currencies []*IncomeStatementSection
+}
+
+type IncomeStatementSection struct {
+	Currency      string
+	Revenue       []*ReportLine
+	Expenses      []*ReportLine
+	TotalRevenue  int64
+	TotalExpenses int64
+	NetIncome     int64
+}
+
+func buildTrialBalance(period ReportPeriod, balances []*AccountBalance) *TrialBalance {
+	report := &TrialBalance{Period: period}
+	sections := make(map[string]*TrialBalanceSection)
+
+	for _, balance := range sortBalances(balances) {
+		section, ok := sections[balance.Currency]
+		if !ok {
+			section = &TrialBalanceSection{Currency: balance.Currency}
+			sections[balance.Currency] = section
+			report.Currencies = append(report.Currencies, section)
+		}
+
+		line := &TrialBalanceLine{
+			AccountID:   balance.AccountID,
+			AccountName: balance.AccountName,
+			AccountType: balance.AccountType,
+		}
+		if net := balance.Net(); net >= 0 {
+			line.Debit = net
+		} else {
+			line.Credit = -net
+		}
+		section.Lines = append(section.Lines, line)
+		section.TotalDebits += line.Debit
+		section.TotalCredits += line.Credit
+	}
+
+	for _, section := range report.Currencies {
+		section.Balanced = section.TotalDebits == section.TotalCredits
+	}
+	return report
+}
+
+func buildBalanceSheet(asOf time.Time, balances []*AccountBalance) *BalanceSheet {
+	report := &BalanceSheet{AsOf: asOf}
+	sections := make(map[string]*BalanceSheetSection)
+
+	for _, balance := range sortBalances(balances) {
+		section, ok := sections[balance.Currency]
+		if !ok {
+			section = &BalanceSheetSection{Currency: balance.Currency}
+			sections[balance.Currency] = section
+			report.Currencies = append(report.Currencies, section)
+		}
+
+		amount := balance.NormalBalance()
+		line := &ReportLine{
+			AccountID:   balance.AccountID,
+			AccountName: balance.AccountName,
+			Amount:      amount,
+		}
+		switch balance.AccountType {
+		case AccountTypeAsset:
+			section.Assets = append(section.Assets, line)
+			section.TotalAssets += amount
+		case AccountTypeLiability:
+			section.Liabilities = append(section.Liabilities, line)
+			section.TotalLiabilities += amount
+		case AccountTypeRevenue:
+			section.RetainedEarnings += amount
+		case AccountTypeExpense:
+			section.RetainedEarnings -= amount
+		}
+	}
+
+	for _, section := range report.Currencies {
+		section.TotalEquity = section.RetainedEarnings
+		section.Balanced = section.TotalAssets == section.TotalLiabilities+section.TotalEquity
+	}
+	return report
+}
+
+func buildIncomeStatement(period ReportPeriod, balances []*AccountBalance) *IncomeStatement {
+	report := &IncomeStatement{Period: period}
+	sections := make(map[string]*IncomeStatementSection)
+
+	for _, balance := range sortBalances(balances) {
+		if balance.AccountType != AccountTypeRevenue && balance.AccountType != AccountTypeExpense {
+			continue
+		}
+
+		section, ok := sections[balance.Currency]
+		if !ok {
+			section = &IncomeStatementSection{Currency: balance.Currency}
+			sections[balance.Currency] = section
+			report.Currencies = append(report.Currencies, section)
+		}
+
+		amount := balance.NormalBalance()
+		line := &ReportLine{
+			AccountID:   balance.AccountID,
+			AccountName: balance.AccountName,
+			Amount:      amount,
+		}
+		if balance.AccountType == AccountTypeRevenue {
+			section.Revenue = append(section.Revenue, line)
+			section.TotalRevenue += amount
+		} else {
+			section.Expenses = append(section.Expenses, line)
+			section.TotalExpenses += amount
+		}
+	}
+
+	for _, section := range report.Currencies {
+		section.NetIncome = section.TotalRevenue - section.TotalExpenses
+	}
+	return report
+}
+
+var accountTypeOrder = map[AccountType]int{
+	AccountTypeAsset:     0,
+	AccountTypeLiability: 1,
+	AccountTypeRevenue:   2,
+	AccountTypeExpense:   3,
+}
+
+func sortBalances(balances []*AccountBalance) []*AccountBalance {
+	sorted := make([]*AccountBalance, len(balances))
+	copy(sorted, balances)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.AccountType != b.AccountType {
+			return accountTypeOrder[a.AccountType] < accountTypeOrder[b.AccountType]
+		}
+		return a.AccountID < b.AccountID
+	})
+	return sorted
+}
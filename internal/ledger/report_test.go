@@ -0,0 +1,110 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func testBalances() []*AccountBalance {
+	return []*AccountBalance{
+		{AccountID: "acc_cash", AccountName: "Cash", AccountType: AccountTypeAsset, Currency: "USD", Debits: 10000, Credits: 2000},
+		{AccountID: "acc_payable", AccountName: "Payable", AccountType: AccountTypeLiability, Currency: "USD", Debits: 0, Credits: 7000},
+		{AccountID: "acc_fees", AccountName: "Fees", AccountType: AccountTypeRevenue, Currency: "USD", Debits: 0, Credits: 1500},
+		{AccountID: "acc_processing", AccountName: "Processing", AccountType: AccountTypeExpense, Currency: "USD", Debits: 500, Credits: 0},
+		{AccountID: "acc_cash_eur", AccountName: "Cash EUR", AccountType: AccountTypeAsset, Currency: "EUR", Debits: 4000, Credits: 0},
+		{AccountID: "acc_fees_eur", AccountName: "Fees EUR", AccountType: AccountTypeRevenue, Currency: "EUR", Debits: 0, Credits: 4000},
+	}
+}
+
+func TestBuildTrialBalance(t *testing.T) {
+	report := buildTrialBalance(ReportPeriod{}, testBalances())
+
+	if len(report.Currencies) != 2 {
+		t.Fatalf("Expected 2 currency sections, got %d", len(report.Currencies))
+	}
+	if report.Currencies[0].Currency != "EUR" || report.Currencies[1].Currency != "USD" {
+		t.Errorf("Expected sections ordered EUR, USD, got %s, %s", report.Currencies[0].Currency, report.Currencies[1].Currency)
+	}
+
+	usd := report.Currencies[1]
+	if usd.TotalDebits != 8500 {
+		t.Errorf("Expected USD total debits 8500, got %d", usd.TotalDebits)
+	}
+	if usd.TotalCredits != 8500 {
+		t.Errorf("Expected USD total credits 8500, got %d", usd.TotalCredits)
+	}
+	if !report.Balanced() {
+		t.Error("Expected trial balance to balance")
+	}
+
+	unbalanced := append(testBalances(), &AccountBalance{
+		AccountID: "acc_tampered", AccountType: AccountTypeAsset, Currency: "USD", Debits: 1,
+	})
+	report = buildTrialBalance(ReportPeriod{}, unbalanced)
+	if report.Balanced() {
+		t.Error("Expected trial balance with stray debit to be unbalanced")
+	}
+}
+
+func TestBuildBalanceSheet(t *testing.T) {
+	report := buildBalanceSheet(time.Now(), testBalances())
+
+	usd := report.Currencies[1]
+	if usd.TotalAssets != 8000 {
+		t.Errorf("Expected total assets 8000, got %d", usd.TotalAssets)
+	}
+	if usd.TotalLiabilities != 7000 {
+		t.Errorf("Expected total liabilities 7000, got %d", usd.TotalLiabilities)
+	}
+	if usd.RetainedEarnings != 1000 {
+		t.Errorf("Expected retained earnings 1000, got %d", usd.RetainedEarnings)
+	}
+	if !usd.Balanced {
+		t.Error("Expected assets to equal liabilities plus equity")
+	}
+}
+
+func TestBuildIncomeStatement(t *testing.T) {
+	report := buildIncomeStatement(ReportPeriod{}, testBalances())
+
+	if len(report.Currencies) != 2 {
+		t.Fatalf("Expected 2 currency sections, got %d", len(report.Currencies))
+	}
+
+	usd := report.Currencies[1]
+	if len(usd.Revenue) != 1 || len(usd.Expenses) != 1 {
+		t.Fatalf("Expected 1 revenue and 1 expense line, got %d and %d", len(usd.Revenue), len(usd.Expenses))
+	}
+	if usd.NetIncome != 1000 {
+		t.Errorf("Expected net income 1000, got %d", usd.NetIncome)
+	}
+
+	eur := report.Currencies[0]
+	if eur.NetIncome != 4000 {
+		t.Errorf("Expected EUR net income 4000, got %d", eur.NetIncome)
+	}
+}
+
+func TestReportPeriod_Validate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		period  ReportPeriod
+		wantErr bool
+	}{
+		{"unbounded", ReportPeriod{}, false},
+		{"open ended", ReportPeriod{From: now}, false},
+		{"bounded", ReportPeriod{From: now, To: now.Add(time.Hour)}, false},
+		{"reversed", ReportPeriod{From: now, To: now.Add(-time.Hour)}, true},
+		{"empty", ReportPeriod{From: now, To: now}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.period.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
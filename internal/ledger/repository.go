@@ -3,34 +3,66 @@ package ledger
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type postgresRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	retry   RetryPolicy
+	metrics RetryMetrics
 }
 
-func NewPostgresRepository(db *sql.DB) Repository {
-	return &postgresRepository{db: db}
+func NewPostgresRepository(db *sql.DB, opts ...RepositoryOption) Repository {
+	r := &postgresRepository{
+		db:      db,
+		retry:   DefaultRetryPolicy,
+		metrics: noopRetryMetrics{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *postgresRepository) CreateAccount(ctx context.Context, account *Account) error {
 	query := `
-		INSERT INTO accounts (id, name, type, currency, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO accounts (id, code, name, type, currency, parent_id, status, balance_constraint, credit_limit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
+	if account.Status == "" {
+		account.Status = AccountStatusActive
+	}
+	if account.BalanceConstraint == "" {
+		account.BalanceConstraint = BalanceConstraintNone
+	}
 	now := time.Now()
 	_, err := r.db.ExecContext(ctx, query,
 		account.ID,
+		sql.NullString{String: account.Code, Valid: account.Code != ""},
 		account.Name,
 		account.Type,
 		account.Currency,
+		sql.NullString{String: account.ParentID, Valid: account.ParentID != ""},
+		account.Status,
+		account.BalanceConstraint,
+		account.CreditLimit,
 		now,
 		now,
 	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		if pqErr.Constraint == "accounts_code_key" {
+			return ErrAccountCodeExists
+		}
+		return ErrAccountExists
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+		return fmt.Errorf("failed to create account: %w", ErrInvalidParent)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create account: %w", err)
 	}
@@ -40,20 +72,8 @@ func (r *postgresRepository) CreateAccount(ctx context.Context, account *Account
 }
 
 func (r *postgresRepository) GetAccount(ctx context.Context, id string) (*Account, error) {
-	query := `
-		SELECT id, name, type, currency, created_at, updated_at
-		FROM accounts
-		WHERE id = $1
-	`
-	account := &Account{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&account.ID,
-		&account.Name,
-		&account.Type,
-		&account.Currency,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-	)
+	query := `SELECT ` + accountColumns + ` FROM accounts a WHERE a.id = $1`
+	account, err := scanAccount(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, ErrAccountNotFound
 	}
@@ -65,9 +85,9 @@ func (r *postgresRepository) GetAccount(ctx context.Context, id string) (*Accoun
 
 func (r *postgresRepository) ListAccounts(ctx context.Context) ([]*Account, error) {
 	query := `
-		SELECT id, name, type, currency, created_at, updated_at
-		FROM accounts
-		ORDER BY created_at DESC
+		SELECT ` + accountColumns + `
+		FROM accounts a
+		ORDER BY a.created_at DESC
 	`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -77,15 +97,7 @@ func (r *postgresRepository) ListAccounts(ctx context.Context) ([]*Account, erro
 
 	var accounts []*Account
 	for rows.Next() {
-		account := &Account{}
-		err := rows.Scan(
-			&account.ID,
-			&account.Name,
-			&account.Type,
-			&account.Currency,
-			&account.CreatedAt,
-			&account.UpdatedAt,
-		)
+		account, err := scanAccount(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan account: %w", err)
 		}
@@ -94,11 +106,208 @@ func (r *postgresRepository) ListAccounts(ctx context.Context) ([]*Account, erro
 	return accounts, nil
 }
 
+func (r *postgresRepository) UpdateBalanceConstraint(ctx context.Context, accountID string, constraint BalanceConstraint, creditLimit int64) error {
+	query := `UPDATE accounts SET balance_constraint = $1, credit_limit = $2, updated_at = $3 WHERE id = $4`
+	result, err := r.db.ExecContext(ctx, query, constraint, creditLimit, time.Now(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update balance constraint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) ChangeAccountStatus(ctx context.Context, change *AccountStatusChange) error {
+	expected := change.FromStatus
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		change.FromStatus = expected
+		query := `SELECT ` + accountColumns + ` FROM accounts a WHERE a.id = $1 FOR UPDATE`
+		account, err := scanAccount(tx.QueryRowContext(ctx, query, change.AccountID))
+		if err == sql.ErrNoRows {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get account: %w", err)
+		}
+
+		balance, err := r.getBalance(ctx, tx, BalanceQuery{AccountID: account.ID})
+		if err != nil {
+			return err
+		}
+		if err := checkAccountTransition(account, change, balance); err != nil {
+			return err
+		}
+		change.FromStatus = account.Status
+
+		_, err = tx.ExecContext(ctx, `UPDATE accounts SET status = $1, updated_at = $2 WHERE id = $3`,
+			change.ToStatus, time.Now(), account.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update account status: %w", err)
+		}
+
+		insertQuery := `
+			INSERT INTO account_status_changes (account_id, from_status, to_status, reason, changed_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`
+		change.CreatedAt = time.Now()
+		err = tx.QueryRowContext(ctx, insertQuery,
+			change.AccountID,
+			change.FromStatus,
+			change.ToStatus,
+			change.Reason,
+			change.ChangedBy,
+			change.CreatedAt,
+		).Scan(&change.ID)
+		if err != nil {
+			return fmt.Errorf("failed to record account status change: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) ListAccountStatusChanges(ctx context.Context, accountID string) ([]*AccountStatusChange, error) {
+	query := `
+		SELECT id, account_id, from_status, to_status, reason, changed_by, created_at
+		FROM account_status_changes
+		WHERE account_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account status changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*AccountStatusChange
+	for rows.Next() {
+		change := &AccountStatusChange{}
+		err := rows.Scan(
+			&change.ID,
+			&change.AccountID,
+			&change.FromStatus,
+			&change.ToStatus,
+			&change.Reason,
+			&change.ChangedBy,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account status change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list account status changes: %w", err)
+	}
+	return changes, nil
+}
+
 func (r *postgresRepository) PostTransaction(ctx context.Context, req PostTransactionRequest) error {
 	if err := req.Validate(); err != nil {
 		return err
 	}
 
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return r.insertPosting(ctx, tx, newPosting(req))
+	})
+}
+
+// PostTransactions writes a batch in one serializable transaction. Invalid or
+// rejected requests are reported in their result and skipped; an error is
+// returned only when the batch as a whole could not be written, in which case
+// nothing was.
+func (r *postgresRepository) PostTransactions(ctx context.Context, reqs []PostTransactionRequest) ([]PostResult, error) {
+	results := make([]PostResult, len(reqs))
+	var postings []posting
+	var positions []int
+	for i, req := range reqs {
+		results[i].TransactionID = req.TransactionID
+		if err := req.Validate(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		postings = append(postings, newPosting(req))
+		positions = append(positions, i)
+	}
+	if len(postings) == 0 {
+		return results, nil
+	}
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		planned, err := r.insertPostings(ctx, tx, postings)
+		if err != nil {
+			return err
+		}
+		for i, result := range planned {
+			results[positions[i]] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *postgresRepository) CommitPending(ctx context.Context, req CommitPendingRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		pending, err := r.lockPending(ctx, tx, req.PendingTransactionID, req.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		entries, err := r.getEntries(ctx, tx, pending.ID)
+		if err != nil {
+			return err
+		}
+
+		p, err := commitPosting(req, pending, entries)
+		if err != nil {
+			return err
+		}
+		return r.insertPosting(ctx, tx, p)
+	})
+}
+
+func (r *postgresRepository) VoidPending(ctx context.Context, req VoidPendingRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		pending, err := r.lockPending(ctx, tx, req.PendingTransactionID, req.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		entries, err := r.getEntries(ctx, tx, pending.ID)
+		if err != nil {
+			return err
+		}
+		return r.insertPosting(ctx, tx, voidPosting(req, pending, entries))
+	})
+}
+
+// inTx runs fn in a serializable transaction, running it again when it loses
+// a serialization or deadlock race. fn must therefore not leave side effects
+// outside tx that a second run would duplicate.
+func (r *postgresRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return withRetry(ctx, r.retry, r.metrics, func() error {
+		return r.runTx(ctx, fn)
+	})
+}
+
+func (r *postgresRepository) runTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 	})
@@ -107,56 +316,342 @@ func (r *postgresRepository) PostTransaction(ctx context.Context, req PostTransa
 	}
 	defer tx.Rollback()
 
-	now := time.Now()
-	insertTxQuery := `
-		INSERT INTO transactions (id, description, created_at)
-		VALUES ($1, $2, $3)
-	`
-	_, err = tx.ExecContext(ctx, insertTxQuery, req.TransactionID, req.Description, now)
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) insertPosting(ctx context.Context, tx *sql.Tx, p posting) error {
+	results, err := r.insertPostings(ctx, tx, []posting{p})
 	if err != nil {
-		return fmt.Errorf("failed to insert transaction: %w", err)
+		return err
 	}
+	return results[0].Err
+}
 
-	insertEntryQuery := `
-		INSERT INTO ledger_entries (id, transaction_id, entry_index, account_id, amount, currency, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
+// insertPostings locks everything the postings depend on, plans them with
+// planPostings and writes the accepted ones with multi-row inserts.
+func (r *postgresRepository) insertPostings(ctx context.Context, tx *sql.Tx, postings []posting) ([]PostResult, error) {
+	head, err := r.lockChainHead(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	state := batchState{head: head, now: chainTime(time.Now())}
+
+	var transactionIDs, adjustmentPeriodIDs, accountIDs []string
+	seenAccounts := make(map[string]bool)
+	addAccount := func(accountID string) {
+		if !seenAccounts[accountID] {
+			seenAccounts[accountID] = true
+			accountIDs = append(accountIDs, accountID)
+		}
+	}
+	var from, to time.Time
+	for _, p := range postings {
+		transactionIDs = append(transactionIDs, p.req.TransactionID)
+		for _, entry := range p.req.Entries {
+			addAccount(entry.AccountID)
+		}
+		for _, held := range p.released {
+			addAccount(held.AccountID)
+		}
+		if p.req.AdjustmentPeriodID != "" {
+			adjustmentPeriodIDs = append(adjustmentPeriodIDs, p.req.AdjustmentPeriodID)
+			continue
+		}
+		effectiveAt := effectiveTime(p.req.EffectiveAt, state.now)
+		if from.IsZero() || effectiveAt.Before(from) {
+			from = effectiveAt
+		}
+		if effectiveAt.After(to) {
+			to = effectiveAt
+		}
+	}
+
+	if state.requestHashes, err = r.getRequestHashes(ctx, tx, transactionIDs); err != nil {
+		return nil, err
+	}
+	if state.periods, err = r.lockPostingPeriods(ctx, tx, from, to, adjustmentPeriodIDs); err != nil {
+		return nil, err
+	}
+	if state.accounts, err = r.lockAccounts(ctx, tx, accountIDs); err != nil {
+		return nil, err
+	}
+	state.available = make(map[string]int64)
+	for _, accountID := range accountIDs {
+		account, ok := state.accounts[accountID]
+		if !ok || account.BalanceConstraint == BalanceConstraintNone {
+			continue
+		}
+		balance, err := r.getBalance(ctx, tx, BalanceQuery{AccountID: accountID})
+		if err != nil {
+			return nil, err
+		}
+		state.available[accountID] = balance.Available
+	}
 
-	for i, entry := range req.Entries {
-		entryID := uuid.New().String()
-		_, err = tx.ExecContext(ctx, insertEntryQuery,
-			entryID,
-			req.TransactionID,
-			i,
+	plan := planPostings(postings, state)
+	if len(plan.transactions) == 0 {
+		return plan.results, nil
+	}
+
+	if err := r.writePlan(ctx, tx, plan); err != nil {
+		return nil, err
+	}
+	return plan.results, nil
+}
+
+const batchInsertRows = 1000
+
+func (r *postgresRepository) writePlan(ctx context.Context, tx *sql.Tx, plan *postingPlan) error {
+	var txnRows, entryRows, refRows [][]interface{}
+	for i, txn := range plan.transactions {
+		metadata, err := marshalMetadata(txn.Metadata)
+		if err != nil {
+			return err
+		}
+		txnRows = append(txnRows, []interface{}{
+			txn.ID,
+			txn.Description,
+			txn.Status,
+			sql.NullString{String: txn.PendingTransactionID, Valid: txn.PendingTransactionID != ""},
+			sql.NullString{String: txn.PeriodID, Valid: txn.PeriodID != ""},
+			metadata,
+			plan.requestHashes[i],
+			txn.Sequence,
+			txn.PrevHash,
+			txn.Hash,
+			txn.CreatedAt,
+			txn.EffectiveAt,
+		})
+		for _, ref := range txn.References {
+			refRows = append(refRows, []interface{}{txn.ID, ref.Type, ref.ID})
+		}
+	}
+	for _, entry := range plan.entries {
+		entryRows = append(entryRows, []interface{}{
+			entry.ID,
+			entry.TransactionID,
+			entry.EntryIndex,
 			entry.AccountID,
 			entry.Amount,
 			entry.Currency,
-			now,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert ledger entry: %w", err)
-		}
+			sql.NullString{String: entry.Memo, Valid: entry.Memo != ""},
+			entry.CreatedAt,
+			entry.EffectiveAt,
+		})
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	err := bulkInsert(ctx, tx, `INSERT INTO transactions (
+		id, description, status, pending_transaction_id, period_id, metadata, request_hash,
+		sequence, prev_hash, hash, created_at, effective_at
+	) VALUES `, txnRows)
+	if err != nil {
+		return fmt.Errorf("failed to insert transactions: %w", err)
+	}
+
+	err = bulkInsert(ctx, tx, `INSERT INTO ledger_entries (
+		id, transaction_id, entry_index, account_id, amount, currency, memo, created_at, effective_at
+	) VALUES `, entryRows)
+	if err != nil {
+		return fmt.Errorf("failed to insert ledger entries: %w", err)
 	}
 
+	err = bulkInsert(ctx, tx, `INSERT INTO transaction_references (transaction_id, ref_type, ref_id) VALUES `, refRows)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction references: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE ledger_chain_head SET sequence = $1, hash = $2 WHERE id = 1`, plan.head.Sequence, plan.head.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to advance chain head: %w", err)
+	}
 	return nil
 }
 
-func (r *postgresRepository) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
+// bulkInsert appends rows to prefix as multi-row VALUES lists of at most
+// batchInsertRows rows per statement.
+func bulkInsert(ctx context.Context, tx *sql.Tx, prefix string, rows [][]interface{}) error {
+	for start := 0; start < len(rows); start += batchInsertRows {
+		end := start + batchInsertRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var query strings.Builder
+		query.WriteString(prefix)
+		var args []interface{}
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for j, value := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, value)
+				fmt.Fprintf(&query, "$%d", len(args))
+			}
+			query.WriteString(")")
+		}
+
+		if _, err := tx.ExecContext(ctx, query.String(), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockChainHead serializes postings on the single chain head row so that
+// every transaction links to exactly one predecessor. The lock is held until
+// the posting commits, so at most one posting transaction runs at a time
+// across the whole ledger; see the package doc.
+func (r *postgresRepository) lockChainHead(ctx context.Context, tx *sql.Tx) (*ChainHead, error) {
+	head := &ChainHead{}
+	err := tx.QueryRowContext(ctx, `SELECT sequence, hash FROM ledger_chain_head WHERE id = 1 FOR UPDATE`).Scan(
+		&head.Sequence,
+		&head.Hash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock chain head: %w", err)
+	}
+	return head, nil
+}
+
+func (r *postgresRepository) lockPending(ctx context.Context, tx *sql.Tx, pendingID, transactionID string) (*Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions t WHERE t.id = $1 FOR UPDATE`
+	pending, err := scanTransaction(tx.QueryRowContext(ctx, query, pendingID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transaction: %w", err)
+	}
+
+	var resolvedBy string
+	err = tx.QueryRowContext(ctx, `SELECT id FROM transactions WHERE pending_transaction_id = $1`, pendingID).Scan(&resolvedBy)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check pending resolution: %w", err)
+	}
+
+	if err := checkPending(pending, resolvedBy, transactionID); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// getRequestHashes returns the request hashes of the given transactions that
+// already exist. Transactions posted before hashes were recorded map to "",
+// which never matches a replay.
+func (r *postgresRepository) getRequestHashes(ctx context.Context, tx *sql.Tx, transactionIDs []string) (map[string]string, error) {
+	query := `SELECT id, COALESCE(request_hash, '') FROM transactions WHERE id = ANY($1)`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(transactionIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing transactions: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan existing transaction: %w", err)
+		}
+		hashes[id] = hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get existing transactions: %w", err)
+	}
+	return hashes, nil
+}
+
+// nullChainTime binds t as a timestamp the way chainTime stores it, with the
+// zero time as NULL. Postgres drops the offset of values bound to TIMESTAMP
+// columns, so every time compared against stored timestamps goes through it.
+func nullChainTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: chainTime(t), Valid: !t.IsZero()}
+}
+
+// lockPostingPeriods share-locks the regular periods overlapping [from, to]
+// and the named adjustment periods, so that a concurrent close waits for the
+// postings to finish.
+func (r *postgresRepository) lockPostingPeriods(ctx context.Context, tx *sql.Tx, from, to time.Time, adjustmentPeriodIDs []string) ([]*AccountingPeriod, error) {
 	query := `
-		SELECT id, description, created_at
-		FROM transactions
-		WHERE id = $1
+		SELECT ` + periodColumns + `
+		FROM accounting_periods p
+		WHERE (p.kind = 'REGULAR' AND $1::timestamp IS NOT NULL AND p.starts_at <= $2 AND p.ends_at > $1)
+		   OR p.id = ANY($3)
+		ORDER BY p.id
+		FOR SHARE
 	`
-	txn := &Transaction{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&txn.ID,
-		&txn.Description,
-		&txn.CreatedAt,
+	rows, err := tx.QueryContext(ctx, query,
+		nullChainTime(from),
+		nullChainTime(to),
+		pq.Array(adjustmentPeriodIDs),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounting periods: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []*AccountingPeriod
+	for rows.Next() {
+		period, err := scanPeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan accounting period: %w", err)
+		}
+		periods = append(periods, period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get accounting periods: %w", err)
+	}
+	return periods, nil
+}
+
+// lockAccounts locks the given accounts, in ID order, so that status changes
+// and balance checks see a stable view for the rest of the posting. Missing
+// accounts are absent from the result.
+func (r *postgresRepository) lockAccounts(ctx context.Context, tx *sql.Tx, accountIDs []string) (map[string]*Account, error) {
+	accounts := make(map[string]*Account, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return accounts, nil
+	}
+
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts a
+		WHERE a.id = ANY($1)
+		ORDER BY a.id
+		FOR UPDATE
+	`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(accountIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock accounts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts[account.ID] = account
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+func (r *postgresRepository) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions t WHERE t.id = $1`
+	txn, err := scanTransaction(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, ErrTransactionNotFound
 	}
@@ -167,52 +662,675 @@ func (r *postgresRepository) GetTransaction(ctx context.Context, id string) (*Tr
 }
 
 func (r *postgresRepository) GetEntriesByTransaction(ctx context.Context, transactionID string) ([]*LedgerEntry, error) {
+	return r.getEntries(ctx, r.db, transactionID)
+}
+
+func (r *postgresRepository) getEntries(ctx context.Context, q queryer, transactionID string) ([]*LedgerEntry, error) {
 	query := `
-		SELECT id, transaction_id, entry_index, account_id, amount, currency, created_at
-		FROM ledger_entries
-		WHERE transaction_id = $1
-		ORDER BY entry_index
+		SELECT ` + entryColumns + `
+		FROM ledger_entries e
+		WHERE e.transaction_id = $1
+		ORDER BY e.entry_index
 	`
-	rows, err := r.db.QueryContext(ctx, query, transactionID)
+	rows, err := q.QueryContext(ctx, query, transactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entries: %w", err)
 	}
 	defer rows.Close()
 
-	var entries []*LedgerEntry
+	return scanEntries(rows)
+}
+
+func (r *postgresRepository) GetEntriesByAccount(ctx context.Context, accountID string, limit int) ([]*LedgerEntry, error) {
+	query := `
+		SELECT ` + entryColumns + `
+		FROM ledger_entries e
+		WHERE e.account_id = $1
+		ORDER BY e.created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, accountID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEntries(rows)
+}
+
+func (r *postgresRepository) GetBalance(ctx context.Context, query BalanceQuery) (*Balance, error) {
+	return r.getBalance(ctx, r.db, query)
+}
+
+func (r *postgresRepository) getBalance(ctx context.Context, q queryer, query BalanceQuery) (*Balance, error) {
+	sqlQuery := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM accounts WHERE id = $1
+			UNION ALL
+			SELECT a.id FROM accounts a JOIN subtree s ON a.parent_id = s.id WHERE $2
+		)
+		SELECT root.type,
+		       COALESCE(SUM(e.amount) FILTER (WHERE t.status = 'POSTED'), 0),
+		       COALESCE(SUM(e.amount) FILTER (WHERE t.status = 'PENDING' AND e.amount > 0 AND r.id IS NULL), 0),
+		       COALESCE(SUM(-e.amount) FILTER (WHERE t.status = 'PENDING' AND e.amount < 0 AND r.id IS NULL), 0)
+		FROM accounts root
+		JOIN subtree s ON TRUE
+		LEFT JOIN ledger_entries e ON e.account_id = s.id
+		    AND ($3::timestamp IS NULL OR ` + query.Basis.column("e") + ` < $3)
+		LEFT JOIN transactions t ON t.id = e.transaction_id
+		LEFT JOIN transactions r ON r.pending_transaction_id = t.id
+		    AND ($3::timestamp IS NULL OR ` + query.Basis.column("r") + ` < $3)
+		WHERE root.id = $1
+		GROUP BY root.type
+	`
+	asOf := nullChainTime(query.AsOf)
+
+	balance := &Balance{AccountID: query.AccountID}
+	err := q.QueryRowContext(ctx, sqlQuery, query.AccountID, query.IncludeDescendants, asOf).Scan(
+		&balance.AccountType,
+		&balance.Posted,
+		&balance.PendingDebits,
+		&balance.PendingCredits,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	balance.computeAvailable()
+	return balance, nil
+}
+
+func (r *postgresRepository) GetAccountBalances(ctx context.Context, filter BalanceFilter) ([]*AccountBalance, error) {
+	query := `
+		SELECT a.id, a.name, a.type, e.currency,
+		       COALESCE(SUM(CASE WHEN e.amount > 0 THEN e.amount ELSE 0 END), 0) AS debits,
+		       COALESCE(SUM(CASE WHEN e.amount < 0 THEN -e.amount ELSE 0 END), 0) AS credits
+		FROM ledger_entries e
+		JOIN accounts a ON a.id = e.account_id
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE t.status = 'POSTED'
+		  AND ($1::timestamp IS NULL OR ` + filter.Basis.column("e") + ` >= $1)
+		  AND ($2::timestamp IS NULL OR ` + filter.Basis.column("e") + ` < $2)
+		GROUP BY a.id, a.name, a.type, e.currency
+		ORDER BY e.currency, a.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, nullChainTime(filter.From), nullChainTime(filter.To))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*AccountBalance
 	for rows.Next() {
-		entry := &LedgerEntry{}
+		balance := &AccountBalance{}
 		err := rows.Scan(
-			&entry.ID,
-			&entry.TransactionID,
-			&entry.EntryIndex,
-			&entry.AccountID,
-			&entry.Amount,
-			&entry.Currency,
-			&entry.CreatedAt,
+			&balance.AccountID,
+			&balance.AccountName,
+			&balance.AccountType,
+			&balance.Currency,
+			&balance.Debits,
+			&balance.Credits,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan entry: %w", err)
+			return nil, fmt.Errorf("failed to scan account balance: %w", err)
 		}
-		entries = append(entries, entry)
+		balances = append(balances, balance)
 	}
-	return entries, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get account balances: %w", err)
+	}
+	return balances, nil
 }
 
-func (r *postgresRepository) GetEntriesByAccount(ctx context.Context, accountID string, limit int) ([]*LedgerEntry, error) {
+func (r *postgresRepository) ListEntries(ctx context.Context, query EntryQuery) (*EntryPage, error) {
+	cursor, err := DecodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageSize(query.Limit)
+
+	where := &whereClause{}
+	if query.AccountID != "" {
+		where.add("e.account_id = %s", query.AccountID)
+	}
+	if query.Currency != "" {
+		where.add("e.currency = %s", query.Currency)
+	}
+	if !query.From.IsZero() {
+		where.add(query.Basis.column("e")+" >= %s", chainTime(query.From))
+	}
+	if !query.To.IsZero() {
+		where.add(query.Basis.column("e")+" < %s", chainTime(query.To))
+	}
+	if query.MinAmount != nil {
+		where.add("e.amount >= %s", *query.MinAmount)
+	}
+	if query.MaxAmount != nil {
+		where.add("e.amount <= %s", *query.MaxAmount)
+	}
+	if query.Description != "" {
+		where.add("t.description ILIKE %s", likePattern(query.Description))
+	}
+	if query.Status != "" {
+		where.add("t.status = %s", query.Status)
+	}
+	if query.Memo != "" {
+		// Served by the trigram index idx_ledger_entries_memo_trgm.
+		where.add("e.memo ILIKE %s", likePattern(query.Memo))
+	}
+	if err := addAnnotationFilters(where, query.Metadata, query.Reference); err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		where.add("(e.created_at, e.id) > (%s, %s)", chainTime(cursor.CreatedAt), cursor.ID)
+	}
+
+	sqlQuery := `
+		SELECT ` + entryColumns + `
+		FROM ledger_entries e
+		JOIN transactions t ON t.id = e.transaction_id
+	` + where.String() + `
+		ORDER BY e.created_at, e.id
+		LIMIT ` + where.arg(limit+1)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := scanEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &EntryPage{Entries: entries}
+
+	if len(page.Entries) > limit {
+		page.Entries = page.Entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+func (r *postgresRepository) ListTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error) {
+	cursor, err := DecodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := pageSize(query.Limit)
+
+	where := &whereClause{}
+	if !query.From.IsZero() {
+		where.add(query.Basis.column("t")+" >= %s", chainTime(query.From))
+	}
+	if !query.To.IsZero() {
+		where.add(query.Basis.column("t")+" < %s", chainTime(query.To))
+	}
+	if query.Description != "" {
+		where.add("t.description ILIKE %s", likePattern(query.Description))
+	}
+	if query.Status != "" {
+		where.add("t.status = %s", query.Status)
+	}
+	if err := addAnnotationFilters(where, query.Metadata, query.Reference); err != nil {
+		return nil, err
+	}
+
+	var entryConditions []string
+	if query.AccountID != "" {
+		entryConditions = append(entryConditions, "e.account_id = "+where.arg(query.AccountID))
+	}
+	if query.Currency != "" {
+		entryConditions = append(entryConditions, "e.currency = "+where.arg(query.Currency))
+	}
+	if query.MinAmount != nil {
+		entryConditions = append(entryConditions, "e.amount >= "+where.arg(*query.MinAmount))
+	}
+	if query.MaxAmount != nil {
+		entryConditions = append(entryConditions, "e.amount <= "+where.arg(*query.MaxAmount))
+	}
+	if len(entryConditions) > 0 {
+		where.conditions = append(where.conditions,
+			"EXISTS (SELECT 1 FROM ledger_entries e WHERE e.transaction_id = t.id AND "+strings.Join(entryConditions, " AND ")+")")
+	}
+
+	if cursor != nil {
+		where.add("(t.created_at, t.id) > (%s, %s)", chainTime(cursor.CreatedAt), cursor.ID)
+	}
+
+	sqlQuery := `
+		SELECT ` + transactionColumns + `
+		FROM transactions t
+	` + where.String() + `
+		ORDER BY t.created_at, t.id
+		LIMIT ` + where.arg(limit+1)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, where.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	page := &TransactionPage{}
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		page.Transactions = append(page.Transactions, txn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.NextCursor = Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+// whereClause accumulates AND-ed conditions whose %s verbs are replaced by
+// positional placeholders bound to args.
+type whereClause struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *whereClause) add(condition string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		placeholders[i] = w.arg(arg)
+	}
+	w.conditions = append(w.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+func (w *whereClause) arg(value interface{}) string {
+	w.args = append(w.args, value)
+	return fmt.Sprintf("$%d", len(w.args))
+}
+
+func (w *whereClause) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// addAnnotationFilters matches transactions whose metadata contains every
+// given key/value pair and that carry the given external reference.
+func addAnnotationFilters(where *whereClause, metadata map[string]string, ref *ExternalReference) error {
+	if len(metadata) > 0 {
+		data, err := marshalMetadata(metadata)
+		if err != nil {
+			return err
+		}
+		where.add("t.metadata @> %s::jsonb", data)
+	}
+	if ref != nil {
+		where.add(`EXISTS (
+			SELECT 1 FROM transaction_references tr
+			WHERE tr.transaction_id = t.id AND tr.ref_type = %s AND tr.ref_id = %s
+		)`, ref.Type, ref.ID)
+	}
+	return nil
+}
+
+func likePattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(s) + "%"
+}
+
+func (r *postgresRepository) CreatePeriod(ctx context.Context, period *AccountingPeriod) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if period.Kind == PeriodKindAdjustment {
+			parent, err := r.lockPeriod(ctx, tx, period.ParentID)
+			if err != nil {
+				return err
+			}
+			if err := checkAdjustmentParent(parent); err != nil {
+				return err
+			}
+		} else {
+			var overlaps bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT EXISTS (
+					SELECT 1 FROM accounting_periods
+					WHERE kind = 'REGULAR' AND starts_at < $2 AND ends_at > $1
+				)
+			`, chainTime(period.StartsAt), chainTime(period.EndsAt)).Scan(&overlaps)
+			if err != nil {
+				return fmt.Errorf("failed to check period overlap: %w", err)
+			}
+			if overlaps {
+				return ErrPeriodOverlap
+			}
+		}
+
+		query := `
+			INSERT INTO accounting_periods (id, name, kind, parent_period_id, starts_at, ends_at, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`
+		now := chainTime(time.Now())
+		_, err := tx.ExecContext(ctx, query,
+			period.ID,
+			period.Name,
+			period.Kind,
+			sql.NullString{String: period.ParentID, Valid: period.ParentID != ""},
+			chainTime(period.StartsAt),
+			chainTime(period.EndsAt),
+			period.Status,
+			now,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create accounting period: %w", err)
+		}
+		period.CreatedAt = now
+		period.UpdatedAt = now
+		return nil
+	})
+}
+
+func (r *postgresRepository) GetPeriod(ctx context.Context, id string) (*AccountingPeriod, error) {
+	query := `SELECT ` + periodColumns + ` FROM accounting_periods p WHERE p.id = $1`
+	period, err := scanPeriod(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPeriodNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounting period: %w", err)
+	}
+	return period, nil
+}
+
+func (r *postgresRepository) ListPeriods(ctx context.Context) ([]*AccountingPeriod, error) {
 	query := `
-		SELECT id, transaction_id, entry_index, account_id, amount, currency, created_at
-		FROM ledger_entries
-		WHERE account_id = $1
-		ORDER BY created_at DESC
+		SELECT ` + periodColumns + `
+		FROM accounting_periods p
+		ORDER BY p.starts_at, p.kind DESC, p.created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounting periods: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []*AccountingPeriod
+	for rows.Next() {
+		period, err := scanPeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan accounting period: %w", err)
+		}
+		periods = append(periods, period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list accounting periods: %w", err)
+	}
+	return periods, nil
+}
+
+func (r *postgresRepository) TransitionPeriod(ctx context.Context, id string, to PeriodStatus) (*AccountingPeriod, error) {
+	var period *AccountingPeriod
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		period, err = r.lockPeriod(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT `+periodColumns+` FROM accounting_periods p WHERE p.parent_period_id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to get adjustment periods: %w", err)
+		}
+		defer rows.Close()
+
+		var adjustments []*AccountingPeriod
+		for rows.Next() {
+			adjustment, err := scanPeriod(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan accounting period: %w", err)
+			}
+			adjustments = append(adjustments, adjustment)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to get adjustment periods: %w", err)
+		}
+
+		if err := checkPeriodTransition(period, to, adjustments); err != nil {
+			return err
+		}
+
+		now := chainTime(time.Now())
+		_, err = tx.ExecContext(ctx, `UPDATE accounting_periods SET status = $1, updated_at = $2 WHERE id = $3`, to, now, id)
+		if err != nil {
+			return fmt.Errorf("failed to update accounting period: %w", err)
+		}
+		period.Status = to
+		period.UpdatedAt = now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+func (r *postgresRepository) lockPeriod(ctx context.Context, tx *sql.Tx, id string) (*AccountingPeriod, error) {
+	query := `SELECT ` + periodColumns + ` FROM accounting_periods p WHERE p.id = $1 FOR UPDATE`
+	period, err := scanPeriod(tx.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPeriodNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounting period: %w", err)
+	}
+	return period, nil
+}
+
+func (r *postgresRepository) GetChainHead(ctx context.Context) (*ChainHead, error) {
+	head := &ChainHead{}
+	err := r.db.QueryRowContext(ctx, `SELECT sequence, hash FROM ledger_chain_head WHERE id = 1`).Scan(
+		&head.Sequence,
+		&head.Hash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain head: %w", err)
+	}
+	return head, nil
+}
+
+func (r *postgresRepository) ListChainLinks(ctx context.Context, afterSequence int64, limit int) ([]*ChainLink, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions t
+		WHERE t.sequence > $1
+		ORDER BY t.sequence
 		LIMIT $2
 	`
-	rows, err := r.db.QueryContext(ctx, query, accountID, limit)
+	rows, err := r.db.QueryContext(ctx, query, afterSequence, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get entries: %w", err)
+		return nil, fmt.Errorf("failed to list chain links: %w", err)
 	}
 	defer rows.Close()
 
+	var links []*ChainLink
+	linksByID := make(map[string]*ChainLink)
+	var ids []string
+	for rows.Next() {
+		txn, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		link := &ChainLink{Transaction: txn}
+		links = append(links, link)
+		linksByID[txn.ID] = link
+		ids = append(ids, txn.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list chain links: %w", err)
+	}
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	entryQuery := `
+		SELECT ` + entryColumns + `
+		FROM ledger_entries e
+		WHERE e.transaction_id = ANY($1)
+		ORDER BY e.transaction_id, e.entry_index
+	`
+	entryRows, err := r.db.QueryContext(ctx, entryQuery, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain entries: %w", err)
+	}
+	defer entryRows.Close()
+
+	entries, err := scanEntries(entryRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		link := linksByID[entry.TransactionID]
+		link.Entries = append(link.Entries, entry)
+	}
+
+	return links, nil
+}
+
+func (r *postgresRepository) CountUnchainedTransactions(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM transactions WHERE sequence IS NULL`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unchained transactions: %w", err)
+	}
+	return count, nil
+}
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+const accountColumns = `a.id, COALESCE(a.code, ''), a.name, a.type, a.currency, COALESCE(a.parent_id, ''),
+	a.status, a.balance_constraint, a.credit_limit, a.created_at, a.updated_at`
+
+const transactionColumns = `t.id, t.description, t.status, COALESCE(t.pending_transaction_id, ''),
+	COALESCE(t.period_id, ''), t.metadata,
+	COALESCE((
+		SELECT jsonb_agg(jsonb_build_object('type', tr.ref_type, 'id', tr.ref_id) ORDER BY tr.ref_type COLLATE "C", tr.ref_id COLLATE "C")
+		FROM transaction_references tr WHERE tr.transaction_id = t.id
+	), '[]'),
+	COALESCE(t.sequence, 0), COALESCE(t.prev_hash, ''), COALESCE(t.hash, ''), t.created_at, t.effective_at`
+
+const periodColumns = `p.id, p.name, p.kind, COALESCE(p.parent_period_id, ''), p.starts_at, p.ends_at,
+	p.status, p.created_at, p.updated_at`
+
+const entryColumns = `e.id, e.transaction_id, e.entry_index, e.account_id, e.amount, e.currency,
+	COALESCE(e.memo, ''), e.created_at, e.effective_at`
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAccount(row scanner) (*Account, error) {
+	account := &Account{}
+	err := row.Scan(
+		&account.ID,
+		&account.Code,
+		&account.Name,
+		&account.Type,
+		&account.Currency,
+		&account.ParentID,
+		&account.Status,
+		&account.BalanceConstraint,
+		&account.CreditLimit,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func scanTransaction(row scanner) (*Transaction, error) {
+	txn := &Transaction{}
+	var metadata, references []byte
+	err := row.Scan(
+		&txn.ID,
+		&txn.Description,
+		&txn.Status,
+		&txn.PendingTransactionID,
+		&txn.PeriodID,
+		&metadata,
+		&references,
+		&txn.Sequence,
+		&txn.PrevHash,
+		&txn.Hash,
+		&txn.CreatedAt,
+		&txn.EffectiveAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(metadata, &txn.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction metadata: %w", err)
+	}
+	if len(txn.Metadata) == 0 {
+		txn.Metadata = nil
+	}
+	if err := json.Unmarshal(references, &txn.References); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction references: %w", err)
+	}
+	if len(txn.References) == 0 {
+		txn.References = nil
+	}
+	return txn, nil
+}
+
+func marshalMetadata(metadata map[string]string) ([]byte, error) {
+	if len(metadata) == 0 {
+		return []byte("{}"), nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction metadata: %w", err)
+	}
+	return data, nil
+}
+
+func scanPeriod(row scanner) (*AccountingPeriod, error) {
+	period := &AccountingPeriod{}
+	err := row.Scan(
+		&period.ID,
+		&period.Name,
+		&period.Kind,
+		&period.ParentID,
+		&period.StartsAt,
+		&period.EndsAt,
+		&period.Status,
+		&period.CreatedAt,
+		&period.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+func scanEntries(rows *sql.Rows) ([]*LedgerEntry, error) {
 	var entries []*LedgerEntry
 	for rows.Next() {
 		entry := &LedgerEntry{}
@@ -223,12 +1341,17 @@ func (r *postgresRepository) GetEntriesByAccount(ctx context.Context, accountID
 			&entry.AccountID,
 			&entry.Amount,
 			&entry.Currency,
+			&entry.Memo,
 			&entry.CreatedAt,
+			&entry.EffectiveAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan entry: %w", err)
 		}
 		entries = append(entries, entry)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read entries: %w", err)
+	}
 	return entries, nil
 }
@@ -0,0 +1,109 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrRetriesExhausted = errors.New("transaction retries exhausted")
+
+// RetryPolicy bounds how often a serializable transaction that lost a
+// serialization or deadlock race is run again. Delays grow exponentially from
+// BaseDelay up to MaxDelay, with jitter so that the losers of one race do not
+// collide again.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// RetryMetrics observes transaction retries. Retried is called before each
+// retry with the attempt that failed; Exhausted when the last attempt failed
+// with a retryable error.
+type RetryMetrics interface {
+	Retried(attempt int, err error)
+	Exhausted(attempts int, err error)
+}
+
+type noopRetryMetrics struct{}
+
+func (noopRetryMetrics) Retried(int, error)   {}
+func (noopRetryMetrics) Exhausted(int, error) {}
+
+type RepositoryOption func(*postgresRepository)
+
+func WithRetryPolicy(policy RetryPolicy) RepositoryOption {
+	return func(r *postgresRepository) {
+		r.retry = policy
+	}
+}
+
+func WithRetryMetrics(metrics RetryMetrics) RepositoryOption {
+	return func(r *postgresRepository) {
+		r.metrics = metrics
+	}
+}
+
+// isRetryable reports whether err is a serialization failure (40001) or a
+// detected deadlock (40P01), after which the whole transaction can safely be
+// run again.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error or the
+// policy's attempts are used up. Exhaustion is reported as
+// ErrRetriesExhausted wrapping the last error, so callers never have to
+// recognize driver error codes themselves.
+func withRetry(ctx context.Context, policy RetryPolicy, metrics RetryMetrics, fn func() error) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if attempt == attempts {
+			metrics.Exhausted(attempt, err)
+			return fmt.Errorf("%w after %d attempts: %w", ErrRetriesExhausted, attempt, err)
+		}
+		metrics.Retried(attempt, err)
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type countingMetrics struct {
+	retried   int
+	exhausted int
+}
+
+func (m *countingMetrics) Retried(int, error)   { m.retried++ }
+func (m *countingMetrics) Exhausted(int, error) { m.exhausted++ }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, true},
+		{"wrapped", fmt.Errorf("failed to commit transaction: %w", &pq.Error{Code: "40001"}), true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"domain error", ErrInsufficientBalance, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Microsecond, MaxDelay: time.Millisecond}
+	conflict := &pq.Error{Code: "40001"}
+
+	t.Run("succeeds after conflicts", func(t *testing.T) {
+		metrics := &countingMetrics{}
+		calls := 0
+		err := withRetry(context.Background(), policy, metrics, func() error {
+			calls++
+			if calls < 3 {
+				return conflict
+			}
+			return nil
+		})
+		if err != nil || calls != 3 || metrics.retried != 2 || metrics.exhausted != 0 {
+			t.Errorf("err = %v, calls = %d, metrics = %+v", err, calls, metrics)
+		}
+	})
+
+	t.Run("non-retryable error returned as is", func(t *testing.T) {
+		calls := 0
+		err := withRetry(context.Background(), policy, &countingMetrics{}, func() error {
+			calls++
+			return ErrAccountClosed
+		})
+		if err != ErrAccountClosed || calls != 1 {
+			t.Errorf("err = %v, calls = %d", err, calls)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		metrics := &countingMetrics{}
+		calls := 0
+		err := withRetry(context.Background(), policy, metrics, func() error {
+			calls++
+			return conflict
+		})
+		if !errors.Is(err, ErrRetriesExhausted) || calls != 3 || metrics.exhausted != 1 {
+			t.Errorf("err = %v, calls = %d, metrics = %+v", err, calls, metrics)
+		}
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+		err := withRetry(ctx, slow, &countingMetrics{}, func() error {
+			cancel()
+			return conflict
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt, max := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 4: 50 * time.Millisecond, 80: 50 * time.Millisecond} {
+		d := policy.delay(attempt)
+		if d < max/2 || d > max {
+			t.Errorf("delay(%d) = %v, want between %v and %v", attempt, d, max/2, max)
+		}
+	}
+}
@@ -3,14 +3,34 @@ package ledger
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
 )
 
 type service struct {
-	repo Repository
+	repo      Repository
+	templates *TemplateRegistry
+}
+
+type ServiceOption func(*service)
+
+// WithTemplates replaces the built-in posting templates.
+func WithTemplates(templates *TemplateRegistry) ServiceOption {
+	return func(s *service) {
+		s.templates = templates
+	}
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, opts ...ServiceOption) Service {
+	s := &service{
+		repo:      repo,
+		templates: NewDefaultTemplateRegistry(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *service) PostTransaction(ctx context.Context, req PostTransactionRequest) error {
@@ -21,6 +41,24 @@ func (s *service) PostTransaction(ctx context.Context, req PostTransactionReques
 	return s.repo.PostTransaction(ctx, req)
 }
 
+func (s *service) ExpandTemplate(req TemplateRequest) (PostTransactionRequest, error) {
+	return s.templates.Expand(req)
+}
+
+func (s *service) PostFromTemplate(ctx context.Context, req TemplateRequest) error {
+	posting, err := s.templates.Expand(req)
+	if err != nil {
+		return err
+	}
+	return s.repo.PostTransaction(ctx, posting)
+}
+
+// PostTransactions posts a batch atomically as far as the database is
+// concerned but reports each request's outcome separately; see PostResult.
+func (s *service) PostTransactions(ctx context.Context, reqs []PostTransactionRequest) ([]PostResult, error) {
+	return s.repo.PostTransactions(ctx, reqs)
+}
+
 func (s *service) GetTransaction(ctx context.Context, id string) (*Transaction, []*LedgerEntry, error) {
 	txn, err := s.repo.GetTransaction(ctx, id)
 	if err != nil {
@@ -36,15 +74,254 @@ func (s *service) GetTransaction(ctx context.Context, id string) (*Transaction,
 }
 
 func (s *service) GetAccountBalance(ctx context.Context, accountID string) (int64, error) {
-	entries, err := s.repo.GetEntriesByAccount(ctx, accountID, 1000000)
+	balance, err := s.repo.GetBalance(ctx, BalanceQuery{AccountID: accountID})
 	if err != nil {
 		return 0, err
 	}
 
-	var balance int64
-	for _, entry := range entries {
-		balance += entry.Amount
+	return balance.Posted, nil
+}
+
+func (s *service) GetBalance(ctx context.Context, accountID string) (*Balance, error) {
+	return s.repo.GetBalance(ctx, BalanceQuery{AccountID: accountID})
+}
+
+func (s *service) GetRollupBalance(ctx context.Context, accountID string) (*Balance, error) {
+	return s.repo.GetBalance(ctx, BalanceQuery{AccountID: accountID, IncludeDescendants: true})
+}
+
+func (s *service) QueryBalance(ctx context.Context, query BalanceQuery) (*Balance, error) {
+	if err := query.Basis.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetBalance(ctx, query)
+}
+
+func (s *service) CreateAccount(ctx context.Context, account *Account) error {
+	if err := account.Validate(); err != nil {
+		return fmt.Errorf("invalid account: %w", err)
+	}
+
+	if account.ParentID != "" {
+		parent, err := s.repo.GetAccount(ctx, account.ParentID)
+		if err == ErrAccountNotFound {
+			return ErrInvalidParent
+		}
+		if err != nil {
+			return err
+		}
+		if err := validateParent(account, parent); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.CreateAccount(ctx, account)
+}
+
+func (s *service) SetBalanceConstraint(ctx context.Context, accountID string, constraint BalanceConstraint, creditLimit int64) error {
+	if err := validateConstraint(constraint, creditLimit); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateBalanceConstraint(ctx, accountID, constraint, creditLimit)
+}
+
+func (s *service) FreezeAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error) {
+	return s.changeAccountStatus(ctx, req, AccountStatusActive, AccountStatusFrozen)
+}
+
+func (s *service) UnfreezeAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error) {
+	return s.changeAccountStatus(ctx, req, AccountStatusFrozen, AccountStatusActive)
+}
+
+func (s *service) CloseAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error) {
+	return s.changeAccountStatus(ctx, req, "", AccountStatusClosed)
+}
+
+func (s *service) ReopenAccount(ctx context.Context, req AccountStatusRequest) (*AccountStatusChange, error) {
+	return s.changeAccountStatus(ctx, req, AccountStatusClosed, AccountStatusActive)
+}
+
+func (s *service) changeAccountStatus(ctx context.Context, req AccountStatusRequest, from, to AccountStatus) (*AccountStatusChange, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid status change: %w", err)
+	}
+
+	change := &AccountStatusChange{
+		AccountID:  req.AccountID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     req.Reason,
+		ChangedBy:  req.ChangedBy,
+	}
+	if err := s.repo.ChangeAccountStatus(ctx, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (s *service) GetAccountStatusHistory(ctx context.Context, accountID string) ([]*AccountStatusChange, error) {
+	return s.repo.ListAccountStatusChanges(ctx, accountID)
+}
+
+func (s *service) ListAccounts(ctx context.Context) ([]*AccountNode, error) {
+	accounts, err := s.repo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildAccountTree(accounts), nil
+}
+
+func (s *service) CommitPending(ctx context.Context, req CommitPendingRequest) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("invalid commit: %w", err)
+	}
+
+	return s.repo.CommitPending(ctx, req)
+}
+
+func (s *service) VoidPending(ctx context.Context, req VoidPendingRequest) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("invalid void: %w", err)
+	}
+
+	return s.repo.VoidPending(ctx, req)
+}
+
+func (s *service) CreatePeriod(ctx context.Context, req CreatePeriodRequest) (*AccountingPeriod, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid period: %w", err)
+	}
+
+	period := &AccountingPeriod{
+		ID:       platform.GenerateID("per"),
+		Name:     req.Name,
+		Kind:     PeriodKindRegular,
+		StartsAt: req.StartsAt.UTC(),
+		EndsAt:   req.EndsAt.UTC(),
+		Status:   PeriodStatusOpen,
+	}
+	if err := s.repo.CreatePeriod(ctx, period); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+func (s *service) ListPeriods(ctx context.Context) ([]*AccountingPeriod, error) {
+	return s.repo.ListPeriods(ctx)
+}
+
+func (s *service) StartPeriodClose(ctx context.Context, id string) (*AccountingPeriod, error) {
+	return s.repo.TransitionPeriod(ctx, id, PeriodStatusClosing)
+}
+
+func (s *service) ReopenPeriod(ctx context.Context, id string) (*AccountingPeriod, error) {
+	return s.repo.TransitionPeriod(ctx, id, PeriodStatusOpen)
+}
+
+func (s *service) ClosePeriod(ctx context.Context, id string) (*AccountingPeriod, error) {
+	return s.repo.TransitionPeriod(ctx, id, PeriodStatusClosed)
+}
+
+func (s *service) OpenAdjustmentPeriod(ctx context.Context, parentID, name string) (*AccountingPeriod, error) {
+	parent, err := s.repo.GetPeriod(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAdjustmentParent(parent); err != nil {
+		return nil, err
+	}
+
+	period := newAdjustmentPeriod(platform.GenerateID("per"), name, parent)
+	if err := s.repo.CreatePeriod(ctx, period); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+func (s *service) GetTrialBalance(ctx context.Context, period ReportPeriod) (*TrialBalance, error) {
+	if err := period.Validate(); err != nil {
+		return nil, err
+	}
+
+	balances, err := s.repo.GetAccountBalances(ctx, BalanceFilter{From: period.From, To: period.To, Basis: period.Basis})
+	if err != nil {
+		return nil, err
+	}
+
+	return buildTrialBalance(period, balances), nil
+}
+
+func (s *service) GetBalanceSheet(ctx context.Context, asOf time.Time, basis TimeBasis) (*BalanceSheet, error) {
+	if err := basis.Validate(); err != nil {
+		return nil, err
+	}
+
+	balances, err := s.repo.GetAccountBalances(ctx, BalanceFilter{To: asOf, Basis: basis})
+	if err != nil {
+		return nil, err
+	}
+
+	report := buildBalanceSheet(asOf, balances)
+	report.Basis = basis
+	return report, nil
+}
+
+func (s *service) GetIncomeStatement(ctx context.Context, period ReportPeriod) (*IncomeStatement, error) {
+	if err := period.Validate(); err != nil {
+		return nil, err
+	}
+
+	balances, err := s.repo.GetAccountBalances(ctx, BalanceFilter{From: period.From, To: period.To, Basis: period.Basis})
+	if err != nil {
+		return nil, err
+	}
+
+	return buildIncomeStatement(period, balances), nil
+}
+
+func (s *service) ListEntries(ctx context.Context, query EntryQuery) (*EntryPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListEntries(ctx, query)
+}
+
+func (s *service) ListTransactions(ctx context.Context, query TransactionQuery) (*TransactionPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListTransactions(ctx, query)
+}
+
+func (s *service) VerifyChain(ctx context.Context) (*ChainVerification, error) {
+	head, err := s.repo.GetChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := newChainVerifier()
+	var afterSequence int64
+	for {
+		links, err := s.repo.ListChainLinks(ctx, afterSequence, chainBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(links) == 0 {
+			break
+		}
+		verifier.verify(links)
+		afterSequence = links[len(links)-1].Transaction.Sequence
+	}
+
+	unchained, err := s.repo.CountUnchainedTransactions(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return balance, nil
+	return verifier.finish(head, unchained), nil
 }
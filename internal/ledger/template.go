@@ -0,0 +1,453 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTemplateNotFound      = errors.New("posting template not found")
+	ErrTemplateExists        = errors.New("posting template already registered")
+	ErrInvalidTemplate       = errors.New("invalid posting template")
+	ErrInvalidTemplateParams = errors.New("invalid posting template parameters")
+)
+
+// PostingTemplate describes a money movement once so that consumers post it
+// by name. Account, currency, memo, description and metadata values may
+// contain {name} placeholders filled from string parameters or Defaults.
+// Amounts are sums of amount parameters and integer literals, e.g.
+// "amount - fee" or "-fee". Entries that evaluate to zero are left out, so a
+// zero fee simply drops the fee line. An entry's Side pins it to debits
+// (positive amounts) or credits (negative amounts), so parameters such as a
+// fee larger than the amount cannot silently flip a line to the other side.
+type PostingTemplate struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Entries     []TemplateEntry   `json:"entries"`
+}
+
+type TemplateEntry struct {
+	Account  string    `json:"account"`
+	Amount   string    `json:"amount"`
+	Side     EntrySide `json:"side,omitempty"`
+	Currency string    `json:"currency,omitempty"`
+	Memo     string    `json:"memo,omitempty"`
+}
+
+// EntrySide is the side of the ledger a template entry must land on. The
+// empty side allows either.
+type EntrySide string
+
+const (
+	SideDebit  EntrySide = "debit"
+	SideCredit EntrySide = "credit"
+)
+
+// allows reports whether an evaluated, non-zero amount is on side s.
+func (s EntrySide) allows(amount int64) bool {
+	switch s {
+	case SideDebit:
+		return amount > 0
+	case SideCredit:
+		return amount < 0
+	}
+	return true
+}
+
+// TemplateRequest posts a registered template. Amounts feed the amount
+// expressions and Params the placeholders. Description overrides the
+// template's; Metadata is merged over it.
+type TemplateRequest struct {
+	Template      string
+	TransactionID string
+	Description   string
+	Amounts       map[string]int64
+	Params        map[string]string
+	Metadata      map[string]string
+	References    []ExternalReference
+	EffectiveAt   time.Time
+	Pending       bool
+}
+
+// defaultCurrency is the placeholder used for entries that do not name a
+// currency.
+const defaultCurrency = "{currency}"
+
+var placeholderPattern = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+type amountTerm struct {
+	sign    int64
+	param   string
+	literal int64
+}
+
+// parseAmount parses a sum such as "amount - fee + 100" into terms.
+func parseAmount(expr string) ([]amountTerm, error) {
+	fields := strings.Fields(strings.NewReplacer("+", " + ", "-", " - ").Replace(expr))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty amount", ErrInvalidTemplate)
+	}
+
+	var terms []amountTerm
+	sign := int64(1)
+	expectOperand := true
+	for _, field := range fields {
+		switch {
+		case field == "+" || field == "-":
+			if field == "-" {
+				sign = -sign
+			}
+			expectOperand = true
+		case !expectOperand:
+			return nil, fmt.Errorf("%w: missing operator in amount %q", ErrInvalidTemplate, expr)
+		default:
+			term := amountTerm{sign: sign}
+			if n, err := strconv.ParseInt(field, 10, 64); err == nil {
+				term.literal = n
+			} else if isIdentifier(field) {
+				term.param = field
+			} else {
+				return nil, fmt.Errorf("%w: bad operand %q in amount %q", ErrInvalidTemplate, field, expr)
+			}
+			terms = append(terms, term)
+			sign = 1
+			expectOperand = false
+		}
+	}
+	if expectOperand {
+		return nil, fmt.Errorf("%w: amount %q ends with an operator", ErrInvalidTemplate, expr)
+	}
+	return terms, nil
+}
+
+func isIdentifier(s string) bool {
+	return placeholderPattern.MatchString("{" + s + "}")
+}
+
+func evalAmount(terms []amountTerm, amounts map[string]int64) (int64, error) {
+	var total int64
+	for _, term := range terms {
+		value := term.literal
+		if term.param != "" {
+			v, ok := amounts[term.param]
+			if !ok {
+				return 0, fmt.Errorf("%w: missing amount %s", ErrInvalidTemplateParams, term.param)
+			}
+			value = v
+		}
+		total += term.sign * value
+	}
+	return total, nil
+}
+
+func fillPlaceholders(s string, params, defaults map[string]string) (string, error) {
+	var missing string
+	filled := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[1 : len(match)-1]
+		if value, ok := params[name]; ok && value != "" {
+			return value
+		}
+		if value, ok := defaults[name]; ok {
+			return value
+		}
+		if missing == "" {
+			missing = name
+		}
+		return match
+	})
+	if missing != "" {
+		return "", fmt.Errorf("%w: missing parameter %s", ErrInvalidTemplateParams, missing)
+	}
+	return filled, nil
+}
+
+func (t *PostingTemplate) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	if t.Description == "" {
+		return fmt.Errorf("%w: %s: description is required", ErrInvalidTemplate, t.Name)
+	}
+	if len(t.Entries) < 2 {
+		return fmt.Errorf("%w: %s: at least two entries required", ErrInvalidTemplate, t.Name)
+	}
+	for _, entry := range t.Entries {
+		if entry.Account == "" {
+			return fmt.Errorf("%w: %s: entry account is required", ErrInvalidTemplate, t.Name)
+		}
+		if _, err := parseAmount(entry.Amount); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		if entry.Side != "" && entry.Side != SideDebit && entry.Side != SideCredit {
+			return fmt.Errorf("%w: %s: unknown entry side %q", ErrInvalidTemplate, t.Name, entry.Side)
+		}
+	}
+	return t.checkBalanced()
+}
+
+// checkBalanced verifies that the entries cancel out for any parameter
+// values: per currency, every amount parameter and the literals must sum to
+// zero. Entries whose currency is only known at expansion time are grouped by
+// their currency placeholder text.
+func (t *PostingTemplate) checkBalanced() error {
+	sums := make(map[string]map[string]int64)
+	for _, entry := range t.Entries {
+		currency := entry.Currency
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		if sums[currency] == nil {
+			sums[currency] = make(map[string]int64)
+		}
+		terms, _ := parseAmount(entry.Amount)
+		for _, term := range terms {
+			if term.param != "" {
+				sums[currency][term.param] += term.sign
+			} else {
+				sums[currency][""] += term.sign * term.literal
+			}
+		}
+	}
+	for _, coefficients := range sums {
+		for _, sum := range coefficients {
+			if sum != 0 {
+				return fmt.Errorf("%w: %s: entries do not balance", ErrInvalidTemplate, t.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Expand builds the posting request for req and validates it. Amount
+// parameters must not be negative; the template's signs decide the sides, and
+// an entry that evaluates to the side opposite its Side is rejected.
+func (t *PostingTemplate) Expand(req TemplateRequest) (PostTransactionRequest, error) {
+	for name, amount := range req.Amounts {
+		if amount < 0 {
+			return PostTransactionRequest{}, fmt.Errorf("%w: amount %s must not be negative", ErrInvalidTemplateParams, name)
+		}
+	}
+
+	description := req.Description
+	if description == "" {
+		var err error
+		if description, err = fillPlaceholders(t.Description, req.Params, t.Defaults); err != nil {
+			return PostTransactionRequest{}, err
+		}
+	}
+
+	out := PostTransactionRequest{
+		TransactionID: req.TransactionID,
+		Description:   description,
+		Pending:       req.Pending,
+		References:    req.References,
+		EffectiveAt:   req.EffectiveAt,
+	}
+
+	if len(t.Metadata) > 0 || len(req.Metadata) > 0 {
+		out.Metadata = make(map[string]string, len(t.Metadata)+len(req.Metadata))
+		for key, value := range t.Metadata {
+			filled, err := fillPlaceholders(value, req.Params, t.Defaults)
+			if err != nil {
+				return PostTransactionRequest{}, err
+			}
+			out.Metadata[key] = filled
+		}
+		for key, value := range req.Metadata {
+			out.Metadata[key] = value
+		}
+	}
+
+	for _, entry := range t.Entries {
+		terms, err := parseAmount(entry.Amount)
+		if err != nil {
+			return PostTransactionRequest{}, err
+		}
+		amount, err := evalAmount(terms, req.Amounts)
+		if err != nil {
+			return PostTransactionRequest{}, err
+		}
+		if amount == 0 {
+			continue
+		}
+		if !entry.Side.allows(amount) {
+			return PostTransactionRequest{}, fmt.Errorf("%w: %s entry %q must be a %s, got %d",
+				ErrInvalidTemplateParams, t.Name, entry.Account, entry.Side, amount)
+		}
+
+		currency := entry.Currency
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		fields := []*string{&entry.Account, &currency, &entry.Memo}
+		for _, field := range fields {
+			if *field, err = fillPlaceholders(*field, req.Params, t.Defaults); err != nil {
+				return PostTransactionRequest{}, err
+			}
+		}
+
+		out.Entries = append(out.Entries, EntryRequest{
+			AccountID: entry.Account,
+			Amount:    amount,
+			Currency:  currency,
+			Memo:      entry.Memo,
+		})
+	}
+
+	if err := out.Validate(); err != nil {
+		return PostTransactionRequest{}, fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	return out, nil
+}
+
+// TemplateRegistry holds posting templates by name. It is safe for
+// concurrent use.
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	templates map[string]*PostingTemplate
+}
+
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{templates: make(map[string]*PostingTemplate)}
+}
+
+func (r *TemplateRegistry) Register(t *PostingTemplate) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[t.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrTemplateExists, t.Name)
+	}
+	r.templates[t.Name] = t
+	return nil
+}
+
+func (r *TemplateRegistry) Get(name string) (*PostingTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+func (r *TemplateRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *TemplateRegistry) Expand(req TemplateRequest) (PostTransactionRequest, error) {
+	t, err := r.Get(req.Template)
+	if err != nil {
+		return PostTransactionRequest{}, err
+	}
+	return t.Expand(req)
+}
+
+// LoadTemplates reads a JSON array of templates, as kept in configuration,
+// and registers each of them.
+func (r *TemplateRegistry) LoadTemplates(src io.Reader) error {
+	var templates []*PostingTemplate
+	decoder := json.NewDecoder(src)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&templates); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	for _, t := range templates {
+		if err := r.Register(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuiltinTemplates are the platform's standard money movements against the
+// default chart of accounts. The merchant payable account can be overridden
+// per request with the merchant_account parameter. Payouts move money from
+// the merchant payable out through the settlement bank account.
+func BuiltinTemplates() []*PostingTemplate {
+	defaults := map[string]string{
+		"customer_account":   "acc_customer_cash",
+		"merchant_account":   "acc_merchant_payable",
+		"fee_account":        "acc_platform_fee",
+		"settlement_account": "acc_settlement_bank",
+	}
+	metadata := map[string]string{"merchant_id": "{merchant}"}
+
+	return []*PostingTemplate{
+		{
+			Name:        "capture",
+			Description: "Capture for merchant {merchant}",
+			Defaults:    defaults,
+			Metadata:    metadata,
+			Entries: []TemplateEntry{
+				{Account: "{customer_account}", Amount: "amount", Side: SideDebit},
+				{Account: "{merchant_account}", Amount: "-amount", Side: SideCredit},
+			},
+		},
+		{
+			Name:        "capture_with_fee",
+			Description: "Capture for merchant {merchant}",
+			Defaults:    defaults,
+			Metadata:    metadata,
+			Entries: []TemplateEntry{
+				{Account: "{customer_account}", Amount: "amount", Side: SideDebit},
+				{Account: "{merchant_account}", Amount: "fee - amount", Side: SideCredit, Memo: "Merchant share"},
+				{Account: "{fee_account}", Amount: "-fee", Side: SideCredit, Memo: "Platform fee"},
+			},
+		},
+		{
+			Name:        "refund",
+			Description: "Refund for merchant {merchant}",
+			Defaults:    defaults,
+			Metadata:    metadata,
+			Entries: []TemplateEntry{
+				{Account: "{merchant_account}", Amount: "amount", Side: SideDebit},
+				{Account: "{customer_account}", Amount: "-amount", Side: SideCredit},
+			},
+		},
+		{
+			Name:        "payout",
+			Description: "Payout to merchant {merchant}",
+			Defaults:    defaults,
+			Metadata:    metadata,
+			Entries: []TemplateEntry{
+				{Account: "{merchant_account}", Amount: "amount", Side: SideDebit},
+				{Account: "{settlement_account}", Amount: "-amount", Side: SideCredit},
+			},
+		},
+	}
+}
+
+// NewDefaultTemplateRegistry returns a registry holding BuiltinTemplates.
+func NewDefaultTemplateRegistry() *TemplateRegistry {
+	r := NewTemplateRegistry()
+	for _, t := range BuiltinTemplates() {
+		if err := r.Register(t); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
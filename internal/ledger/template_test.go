@@ -0,0 +1,224 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	amounts := map[string]int64{"amount": 1000, "fee": 30}
+
+	tests := []struct {
+		expr    string
+		want    int64
+		wantErr bool
+	}{
+		{"amount", 1000, false},
+		{"-amount", -1000, false},
+		{"fee - amount", -970, false},
+		{"amount-fee+5", 975, false},
+		{"- -fee", 30, false},
+		{"", 0, true},
+		{"amount fee", 0, true},
+		{"amount -", 0, true},
+		{"amount * 2", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			terms, err := parseAmount(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := evalAmount(terms, amounts)
+			if err != nil || got != tt.want {
+				t.Errorf("evalAmount() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostingTemplate_Validate(t *testing.T) {
+	valid := func() *PostingTemplate {
+		return &PostingTemplate{
+			Name:        "transfer",
+			Description: "Transfer",
+			Entries: []TemplateEntry{
+				{Account: "{from}", Amount: "-amount"},
+				{Account: "{to}", Amount: "amount - fee"},
+				{Account: "acc_fees", Amount: "fee"},
+			},
+		}
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Expected valid template, got %v", err)
+	}
+
+	unbalanced := valid()
+	unbalanced.Entries[2].Amount = "-fee"
+	if err := unbalanced.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected unbalanced template to be rejected, got %v", err)
+	}
+
+	mixedCurrency := valid()
+	mixedCurrency.Entries[2].Currency = "EUR"
+	if err := mixedCurrency.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected template unbalanced per currency to be rejected, got %v", err)
+	}
+
+	badSide := valid()
+	badSide.Entries[0].Side = "left"
+	if err := badSide.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected unknown side to be rejected, got %v", err)
+	}
+
+	oneEntry := valid()
+	oneEntry.Entries = oneEntry.Entries[:1]
+	if err := oneEntry.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected single-entry template to be rejected, got %v", err)
+	}
+}
+
+func TestCaptureWithFeeTemplate(t *testing.T) {
+	registry := NewDefaultTemplateRegistry()
+
+	req := TemplateRequest{
+		Template:      "capture_with_fee",
+		TransactionID: "txn_1",
+		Amounts:       map[string]int64{"amount": 10000, "fee": 290},
+		Params:        map[string]string{"merchant": "m_42", "currency": "USD"},
+		Metadata:      map[string]string{"order": "A-1"},
+	}
+	got, err := registry.Expand(req)
+	if err != nil {
+		t.Fatalf("Failed to expand template: %v", err)
+	}
+
+	want := []EntryRequest{
+		{AccountID: "acc_customer_cash", Amount: 10000, Currency: "USD"},
+		{AccountID: "acc_merchant_payable", Amount: -9710, Currency: "USD", Memo: "Merchant share"},
+		{AccountID: "acc_platform_fee", Amount: -290, Currency: "USD", Memo: "Platform fee"},
+	}
+	if len(got.Entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), got.Entries)
+	}
+	for i := range want {
+		if got.Entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got.Entries[i], want[i])
+		}
+	}
+	if got.Description != "Capture for merchant m_42" {
+		t.Errorf("Unexpected description %q", got.Description)
+	}
+	if got.Metadata["merchant_id"] != "m_42" || got.Metadata["order"] != "A-1" {
+		t.Errorf("Unexpected metadata %v", got.Metadata)
+	}
+
+	t.Run("zero fee drops the fee line", func(t *testing.T) {
+		req := req
+		req.Amounts = map[string]int64{"amount": 10000, "fee": 0}
+		got, err := registry.Expand(req)
+		if err != nil {
+			t.Fatalf("Failed to expand template: %v", err)
+		}
+		if len(got.Entries) != 2 {
+			t.Errorf("Expected 2 entries, got %+v", got.Entries)
+		}
+	})
+
+	t.Run("fee above amount", func(t *testing.T) {
+		req := req
+		req.Amounts = map[string]int64{"amount": 100, "fee": 290}
+		if _, err := registry.Expand(req); !errors.Is(err, ErrInvalidTemplateParams) {
+			t.Errorf("Expected a fee above the amount to be rejected, got %v", err)
+		}
+
+		req.Amounts = map[string]int64{"amount": 290, "fee": 290}
+		got, err := registry.Expand(req)
+		if err != nil || len(got.Entries) != 2 {
+			t.Errorf("Expected a fee equal to the amount to drop the merchant line, got %+v, %v", got.Entries, err)
+		}
+	})
+
+	t.Run("account override", func(t *testing.T) {
+		req := req
+		req.Params = map[string]string{"merchant": "m_42", "currency": "USD", "merchant_account": "acc_merchant_42"}
+		got, err := registry.Expand(req)
+		if err != nil {
+			t.Fatalf("Failed to expand template: %v", err)
+		}
+		if got.Entries[1].AccountID != "acc_merchant_42" {
+			t.Errorf("Expected overridden merchant account, got %s", got.Entries[1].AccountID)
+		}
+	})
+
+	t.Run("missing parameters", func(t *testing.T) {
+		for _, req := range []TemplateRequest{
+			{Template: "capture_with_fee", TransactionID: "txn_1", Amounts: map[string]int64{"amount": 100}, Params: req.Params},
+			{Template: "capture_with_fee", TransactionID: "txn_1", Amounts: req.Amounts, Params: map[string]string{"merchant": "m_42"}},
+			{Template: "capture_with_fee", TransactionID: "txn_1", Amounts: map[string]int64{"amount": -100, "fee": 0}, Params: req.Params},
+		} {
+			if _, err := registry.Expand(req); !errors.Is(err, ErrInvalidTemplateParams) {
+				t.Errorf("Expected ErrInvalidTemplateParams, got %v", err)
+			}
+		}
+	})
+
+	if _, err := registry.Expand(TemplateRequest{Template: "missing"}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestPayoutTemplate(t *testing.T) {
+	got, err := NewDefaultTemplateRegistry().Expand(TemplateRequest{
+		Template:      "payout",
+		TransactionID: "txn_payout",
+		Amounts:       map[string]int64{"amount": 5000},
+		Params:        map[string]string{"merchant": "m_42", "currency": "USD"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to expand template: %v", err)
+	}
+	want := []EntryRequest{
+		{AccountID: "acc_merchant_payable", Amount: 5000, Currency: "USD"},
+		{AccountID: "acc_settlement_bank", Amount: -5000, Currency: "USD"},
+	}
+	if len(got.Entries) != 2 || got.Entries[0] != want[0] || got.Entries[1] != want[1] {
+		t.Errorf("Expected the payable debited and the settlement bank credited, got %+v", got.Entries)
+	}
+}
+
+func TestTemplateRegistry_LoadTemplates(t *testing.T) {
+	config := `[
+		{
+			"name": "chargeback",
+			"description": "Chargeback for merchant {merchant}",
+			"defaults": {"merchant_account": "acc_merchant_payable"},
+			"entries": [
+				{"account": "{merchant_account}", "amount": "amount + penalty"},
+				{"account": "acc_customer_cash", "amount": "-amount"},
+				{"account": "acc_platform_fee", "amount": "-penalty", "memo": "Chargeback fee"}
+			]
+		}
+	]`
+
+	registry := NewDefaultTemplateRegistry()
+	if err := registry.LoadTemplates(strings.NewReader(config)); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	if names := registry.Names(); len(names) != 5 || names[1] != "capture_with_fee" || names[2] != "chargeback" {
+		t.Errorf("Unexpected template names %v", names)
+	}
+
+	if err := registry.LoadTemplates(strings.NewReader(config)); !errors.Is(err, ErrTemplateExists) {
+		t.Errorf("Expected ErrTemplateExists, got %v", err)
+	}
+	if err := NewTemplateRegistry().LoadTemplates(strings.NewReader(`[{"name": "x", "entriez": []}]`)); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected unknown fields to be rejected, got %v", err)
+	}
+}
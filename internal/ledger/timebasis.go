@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"time"
+)
+
+// TimeBasis selects which timestamp time-bounded queries filter on: when a
+// transaction was booked (created_at) or when it economically took effect
+// (effective_at). Backdated postings differ only in the latter.
+type TimeBasis string
+
+const (
+	TimeBasisBooking   TimeBasis = "BOOKING"
+	TimeBasisEffective TimeBasis = "EFFECTIVE"
+)
+
+func (b TimeBasis) Validate() error {
+	switch b {
+	case "", TimeBasisBooking, TimeBasisEffective:
+		return nil
+	}
+	return ErrInvalidQuery
+}
+
+// column returns the timestamp column of the given table alias. The empty
+// basis defaults to booking time.
+func (b TimeBasis) column(alias string) string {
+	if b == TimeBasisEffective {
+		return alias + ".effective_at"
+	}
+	return alias + ".created_at"
+}
+
+// pick returns the timestamp the basis selects, as column does in SQL.
+func (b TimeBasis) pick(createdAt, effectiveAt time.Time) time.Time {
+	if b == TimeBasisEffective {
+		return effectiveAt
+	}
+	return createdAt
+}
+
+// effectiveTime resolves a requested effective date against the booking
+// time: zero means the posting takes effect when it is booked.
+func effectiveTime(requested, bookedAt time.Time) time.Time {
+	if requested.IsZero() {
+		return bookedAt
+	}
+	return chainTime(requested)
+}
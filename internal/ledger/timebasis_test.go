@@ -0,0 +1,56 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeBasis_Column(t *testing.T) {
+	tests := []struct {
+		basis TimeBasis
+		want  string
+	}{
+		{"", "e.created_at"},
+		{TimeBasisBooking, "e.created_at"},
+		{TimeBasisEffective, "e.effective_at"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.basis.column("e"); got != tt.want {
+			t.Errorf("column(%q) = %s, want %s", tt.basis, got, tt.want)
+		}
+	}
+
+	if err := TimeBasis("SETTLEMENT").Validate(); err != ErrInvalidQuery {
+		t.Errorf("Expected ErrInvalidQuery for unknown basis, got %v", err)
+	}
+}
+
+func TestEffectiveTime(t *testing.T) {
+	bookedAt := chainTime(time.Now())
+	if got := effectiveTime(time.Time{}, bookedAt); !got.Equal(bookedAt) {
+		t.Errorf("Expected zero effective date to default to booking time, got %v", got)
+	}
+
+	yesterday := bookedAt.Add(-24 * time.Hour).Add(123 * time.Nanosecond)
+	if got := effectiveTime(yesterday, bookedAt); !got.Equal(yesterday.Truncate(time.Microsecond)) {
+		t.Errorf("Expected backdated effective date, got %v", got)
+	}
+}
+
+func TestComputeChainHash_EffectiveAt(t *testing.T) {
+	now := chainTime(time.Now())
+	txn := &Transaction{ID: "txn_1", Description: "Settlement", CreatedAt: now}
+	link := &ChainLink{Transaction: txn}
+	legacy := computeChainHash(link)
+
+	txn.EffectiveAt = now
+	if computeChainHash(link) != legacy {
+		t.Error("Expected effective date equal to booking time to keep the hash unchanged")
+	}
+
+	txn.EffectiveAt = now.Add(-24 * time.Hour)
+	if computeChainHash(link) == legacy {
+		t.Error("Expected backdated effective date to change the hash")
+	}
+}
@@ -0,0 +1,81 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ConnectorRequest is what the service sends to a PSP. IdempotencyKey is the
+// merchant's key for the operation, empty when none was given; connectors pass
+// it on so that a retried call is not executed twice by the PSP either.
+type ConnectorRequest struct {
+	Operation         Operation
+	IntentID          string
+	MerchantID        string
+	ProviderPaymentID string
+	Amount            int64
+	Currency          string
+	IdempotencyKey    string
+}
+
+// Connector talks to a payment service provider.
+type Connector interface {
+	Name() string
+	// Authorize returns the provider's ID for the payment.
+	Authorize(ctx context.Context, req ConnectorRequest) (string, error)
+	Capture(ctx context.Context, req ConnectorRequest) error
+	Refund(ctx context.Context, req ConnectorRequest) error
+	Cancel(ctx context.Context, req ConnectorRequest) error
+}
+
+// mockConnector approves everything. Like a real PSP it remembers the
+// provider payment ID handed out per idempotency key, so a retried
+// authorization gets the same ID back.
+type mockConnector struct {
+	mu         sync.Mutex
+	authorized map[string]string
+}
+
+func NewMockConnector() Connector {
+	return &mockConnector{authorized: make(map[string]string)}
+}
+
+func (c *mockConnector) Name() string {
+	return "mock_provider"
+}
+
+func (c *mockConnector) Authorize(ctx context.Context, req ConnectorRequest) (string, error) {
+	if req.IdempotencyKey == "" {
+		return newProviderPaymentID(), nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := fmt.Sprintf("%d:%s:%s", len(req.MerchantID), req.MerchantID, req.IdempotencyKey)
+	if id, ok := c.authorized[key]; ok {
+		return id, nil
+	}
+	id := newProviderPaymentID()
+	c.authorized[key] = id
+	return id, nil
+}
+
+func (c *mockConnector) Capture(ctx context.Context, req ConnectorRequest) error {
+	return nil
+}
+
+func (c *mockConnector) Refund(ctx context.Context, req ConnectorRequest) error {
+	return nil
+}
+
+func (c *mockConnector) Cancel(ctx context.Context, req ConnectorRequest) error {
+	return nil
+}
+
+func newProviderPaymentID() string {
+	return fmt.Sprintf("psp_%s", uuid.New().String())
+}
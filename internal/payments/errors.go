@@ -0,0 +1,35 @@
+package payments
+
+import (
+	"net/http"
+
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+)
+
+const (
+	CodeInvalidStateTransition = "invalid_state_transition"
+	CodeIdempotencyKeyReused   = "idempotency_key_reused"
+)
+
+var errorMappings = []platform.ErrorMapping{
+	{Err: ErrInvalidRequest, Code: platform.CodeInvalidRequest, Status: http.StatusBadRequest},
+	{Err: ErrIntentNotFound, Code: platform.CodeNotFound, Status: http.StatusNotFound},
+	{Err: ErrOperationNotFound, Code: platform.CodeNotFound, Status: http.StatusNotFound},
+	{Err: ErrInvalidTransition, Code: CodeInvalidStateTransition, Status: http.StatusConflict},
+	{Err: ErrInvalidState, Code: CodeInvalidStateTransition, Status: http.StatusConflict},
+	// A concurrent update won; the request may succeed against the new version.
+	{Err: ErrVersionMismatch, Code: platform.CodeConflict, Status: http.StatusConflict, Retryable: true},
+	{Err: ErrIdempotencyMismatch, Code: CodeIdempotencyKeyReused, Status: http.StatusUnprocessableEntity, Param: "idempotency_key"},
+	{Err: ErrIdempotencyKeyExists, Code: platform.CodeConflict, Status: http.StatusConflict, Param: "idempotency_key"},
+	{Err: ErrIntentExists, Code: platform.CodeConflict, Status: http.StatusConflict},
+}
+
+// ToAPIError maps a payments failure onto the API error model.
+func ToAPIError(err error) *platform.Error {
+	return platform.MapError(err, errorMappings)
+}
+
+// invalidParam reports a bad request parameter as ErrInvalidRequest.
+func invalidParam(param, message string) error {
+	return platform.InvalidParam(ErrInvalidRequest, param, message)
+}
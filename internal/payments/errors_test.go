@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/internal/platform"
+)
+
+func TestToAPIError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      string
+		wantStatus    int
+		wantParam     string
+		wantRetryable bool
+	}{
+		{"validation", invalidParam("amount", "amount must be positive"), platform.CodeInvalidRequest, http.StatusBadRequest, "amount", false},
+		{"not found", ErrIntentNotFound, platform.CodeNotFound, http.StatusNotFound, "", false},
+		{"invalid transition", ErrInvalidTransition, CodeInvalidStateTransition, http.StatusConflict, "", false},
+		{"version mismatch", fmt.Errorf("failed to capture intent: %w", ErrVersionMismatch), platform.CodeConflict, http.StatusConflict, "", true},
+		{"key reused", fmt.Errorf("%w: key %q", ErrIdempotencyMismatch, "key_1"), CodeIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency_key", false},
+		{"timeout", context.DeadlineExceeded, platform.CodeTimeout, http.StatusServiceUnavailable, "", true},
+		{"unknown", errors.New("psp unreachable"), platform.CodeInternal, http.StatusInternalServerError, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := ToAPIError(tt.err)
+			if apiErr.Code != tt.wantCode || apiErr.Status != tt.wantStatus || apiErr.Param != tt.wantParam || apiErr.Retryable != tt.wantRetryable {
+				t.Errorf("Expected %s %d param=%q retryable=%v, got %+v", tt.wantCode, tt.wantStatus, tt.wantParam, tt.wantRetryable, apiErr)
+			}
+		})
+	}
+
+	wrapped := fmt.Errorf("failed to capture intent: %w: %w", ErrVersionMismatch, errors.New(`pq: could not serialize access`))
+	if apiErr := ToAPIError(wrapped); apiErr.Message != ErrVersionMismatch.Error() || apiErr.Err != wrapped {
+		t.Errorf("Expected the sentinel message with the cause kept in Err, got %q", apiErr.Message)
+	}
+
+	err := fmt.Errorf("failed to create intent: %w", invalidParam("currency", "currency is required"))
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Error("Expected validation errors to match ErrInvalidRequest")
+	}
+	if apiErr := ToAPIError(err); apiErr.Message != "currency is required" {
+		t.Errorf("Expected the validation message, got %q", apiErr.Message)
+	}
+}
@@ -5,9 +5,11 @@ package payments_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/payments/paymentstest"
 	"github.com/thilakshekharshriyan/playflow/internal/testutil"
 )
 
@@ -220,6 +222,43 @@ func TestPaymentFlow_EndToEnd(t *testing.T) {
 			t.Error("Expected error when capturing more than authorized amount")
 		}
 	})
+
+	t.Run("Cancel Flow: Authorized -> Canceled", func(t *testing.T) {
+		testDB.Truncate(t, "payment_intents")
+
+		createReq := payments.CreateIntentRequest{
+			MerchantID: "merchant_cancel",
+			Amount:     2500,
+			Currency:   "USD",
+		}
+		intent, _ := svc.CreateIntent(ctx, createReq)
+
+		authReq := payments.AuthorizeRequest{
+			IntentID: intent.ID,
+		}
+		svc.AuthorizeIntent(ctx, authReq)
+
+		canceled, err := svc.CancelIntent(ctx, payments.CancelRequest{IntentID: intent.ID, Reason: "abandoned"})
+		if err != nil {
+			t.Fatalf("Failed to cancel intent: %v", err)
+		}
+		if canceled.State != payments.StateCanceled {
+			t.Errorf("Expected state CANCELED, got %v", canceled.State)
+		}
+
+		_, err = svc.CaptureIntent(ctx, payments.CaptureRequest{IntentID: intent.ID, Amount: 2500})
+		if err != payments.ErrInvalidTransition {
+			t.Errorf("Expected ErrInvalidTransition capturing a canceled intent, got %v", err)
+		}
+
+		intents, err := svc.ListIntents(ctx, "merchant_cancel", 0)
+		if err != nil {
+			t.Fatalf("Failed to list intents: %v", err)
+		}
+		if len(intents) != 1 || intents[0].State != payments.StateCanceled {
+			t.Errorf("Expected one canceled intent, got %+v", intents)
+		}
+	})
 }
 
 func TestPaymentFlow_Idempotency(t *testing.T) {
@@ -257,6 +296,18 @@ func TestPaymentFlow_Idempotency(t *testing.T) {
 		}
 	})
 
+	t.Run("Mismatched Create Replay Is Rejected", func(t *testing.T) {
+		createReq := payments.CreateIntentRequest{
+			MerchantID:     "merchant_idem",
+			Amount:         20000,
+			Currency:       "USD",
+			IdempotencyKey: "idem_unique_123",
+		}
+		if _, err := svc.CreateIntent(ctx, createReq); !errors.Is(err, payments.ErrIdempotencyMismatch) {
+			t.Errorf("Expected ErrIdempotencyMismatch, got %v", err)
+		}
+	})
+
 	t.Run("Same Idempotency Key Different Merchant Creates New Intent", func(t *testing.T) {
 		createReq1 := payments.CreateIntentRequest{
 			MerchantID:     "merchant_A",
@@ -278,6 +329,38 @@ func TestPaymentFlow_Idempotency(t *testing.T) {
 			t.Error("Expected different intents for different merchants")
 		}
 	})
+
+	t.Run("Retried Capture Returns Original Result", func(t *testing.T) {
+		intent, err := svc.CreateIntent(ctx, payments.CreateIntentRequest{
+			MerchantID: "merchant_idem",
+			Amount:     10000,
+			Currency:   "USD",
+		})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err := svc.AuthorizeIntent(ctx, payments.AuthorizeRequest{IntentID: intent.ID, IdempotencyKey: "auth_retry"}); err != nil {
+			t.Fatalf("Authorize failed: %v", err)
+		}
+
+		captureReq := payments.CaptureRequest{IntentID: intent.ID, Amount: 10000, IdempotencyKey: "capture_retry"}
+		captured, err := svc.CaptureIntent(ctx, captureReq)
+		if err != nil {
+			t.Fatalf("First capture failed: %v", err)
+		}
+		retried, err := svc.CaptureIntent(ctx, captureReq)
+		if err != nil {
+			t.Fatalf("Retried capture failed: %v", err)
+		}
+		if retried.Version != captured.Version || retried.State != payments.StateCaptured {
+			t.Errorf("Expected the original capture result, got %s at %d", retried.State, retried.Version)
+		}
+
+		captureReq.Amount = 5000
+		if _, err := svc.CaptureIntent(ctx, captureReq); !errors.Is(err, payments.ErrIdempotencyMismatch) {
+			t.Errorf("Expected ErrIdempotencyMismatch, got %v", err)
+		}
+	})
 }
 
 func TestPaymentFlow_ConcurrentOperations(t *testing.T) {
@@ -392,3 +475,12 @@ func TestPaymentFlow_ValidationRules(t *testing.T) {
 		}
 	})
 }
+
+func TestPostgresRepository_Contract(t *testing.T) {
+	paymentstest.RunRepositoryTests(t, func(t *testing.T) payments.Repository {
+		testDB := testutil.SetupTestDB(t)
+		t.Cleanup(func() { testDB.Close(t) })
+		testDB.ApplyMigrations(t)
+		return payments.NewPostgresRepository(testDB.DB)
+	})
+}
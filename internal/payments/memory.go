@@ -0,0 +1,183 @@
+package payments
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryRepository is a Repository kept in process memory with the same
+// versioning, idempotency key and ordering rules as the Postgres repository.
+// Intents are copied in and out so callers never share stored state.
+type memoryRepository struct {
+	mu         sync.RWMutex
+	intents    map[string]*PaymentIntent
+	order      []string
+	operations map[operationKey]*OperationRecord
+}
+
+type operationKey struct {
+	merchantID string
+	operation  Operation
+	key        string
+}
+
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		intents:    make(map[string]*PaymentIntent),
+		operations: make(map[operationKey]*OperationRecord),
+	}
+}
+
+func (r *memoryRepository) Create(ctx context.Context, intent *PaymentIntent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.intents[intent.ID]; ok {
+		return ErrIntentExists
+	}
+	if intent.IdempotencyKey != "" && r.byIdempotencyKey(intent.MerchantID, intent.IdempotencyKey) != nil {
+		return ErrIdempotencyKeyExists
+	}
+
+	now := time.Now()
+	intent.Version = 0
+	intent.CreatedAt = now
+	intent.UpdatedAt = now
+
+	stored := *intent
+	stored.SelectedProvider = ""
+	stored.ProviderPaymentID = ""
+	r.intents[intent.ID] = &stored
+	r.order = append(r.order, intent.ID)
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, id string) (*PaymentIntent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	intent, ok := r.intents[id]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (r *memoryRepository) GetByIdempotencyKey(ctx context.Context, merchantID, key string) (*PaymentIntent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	intent := r.byIdempotencyKey(merchantID, key)
+	if intent == nil {
+		return nil, ErrIntentNotFound
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+func (r *memoryRepository) byIdempotencyKey(merchantID, key string) *PaymentIntent {
+	for _, intent := range r.intents {
+		if intent.MerchantID == merchantID && intent.IdempotencyKey == key {
+			return intent
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) UpdateState(ctx context.Context, id string, state PaymentState, expectedVersion int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	intent, err := r.lockVersion(id, expectedVersion)
+	if err != nil {
+		return err
+	}
+	intent.State = state
+	return nil
+}
+
+func (r *memoryRepository) UpdateStateWithProvider(ctx context.Context, id string, state PaymentState, provider, providerPaymentID string, expectedVersion int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	intent, err := r.lockVersion(id, expectedVersion)
+	if err != nil {
+		return err
+	}
+	intent.State = state
+	intent.SelectedProvider = provider
+	intent.ProviderPaymentID = providerPaymentID
+	return nil
+}
+
+func (r *memoryRepository) ApplyOperation(ctx context.Context, op *OperationRecord, state PaymentState, provider, providerPaymentID string, expectedVersion int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := operationKey{merchantID: op.MerchantID, operation: op.Operation, key: op.IdempotencyKey}
+	if _, ok := r.operations[key]; ok {
+		return ErrIdempotencyKeyExists
+	}
+	intent, err := r.lockVersion(op.IntentID, expectedVersion)
+	if err != nil {
+		return err
+	}
+	intent.State = state
+	intent.SelectedProvider = provider
+	intent.ProviderPaymentID = providerPaymentID
+
+	op.Result = *intent
+	op.CreatedAt = intent.UpdatedAt
+	stored := *op
+	r.operations[key] = &stored
+	return nil
+}
+
+func (r *memoryRepository) GetOperation(ctx context.Context, merchantID string, operation Operation, key string) (*OperationRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, ok := r.operations[operationKey{merchantID: merchantID, operation: operation, key: key}]
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+	copied := *op
+	return &copied, nil
+}
+
+// lockVersion bumps the version of the intent if it is still at
+// expectedVersion. Like the Postgres UPDATE ... WHERE version = $n, a missing
+// intent is indistinguishable from a stale version.
+func (r *memoryRepository) lockVersion(id string, expectedVersion int64) (*PaymentIntent, error) {
+	intent, ok := r.intents[id]
+	if !ok || intent.Version != expectedVersion {
+		return nil, ErrVersionMismatch
+	}
+	intent.Version++
+	intent.UpdatedAt = time.Now()
+	return intent, nil
+}
+
+func (r *memoryRepository) List(ctx context.Context, merchantID string, limit int) ([]*PaymentIntent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var intents []*PaymentIntent
+	for i := len(r.order) - 1; i >= 0; i-- {
+		intent := r.intents[r.order[i]]
+		if intent.MerchantID == merchantID {
+			copied := *intent
+			intents = append(intents, &copied)
+		}
+	}
+	sort.SliceStable(intents, func(i, j int) bool {
+		return intents[i].CreatedAt.After(intents[j].CreatedAt)
+	})
+	if len(intents) > limit {
+		intents = intents[:limit]
+	}
+	return intents, nil
+}
@@ -0,0 +1,14 @@
+package payments_test
+
+import (
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+	"github.com/thilakshekharshriyan/playflow/internal/payments/paymentstest"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	paymentstest.RunRepositoryTests(t, func(t *testing.T) payments.Repository {
+		return payments.NewMemoryRepository()
+	})
+}
@@ -11,7 +11,11 @@ var (
 	ErrInvalidState         = errors.New("invalid state")
 	ErrVersionMismatch      = errors.New("version mismatch - concurrent modification detected")
 	ErrIntentNotFound       = errors.New("payment intent not found")
+	ErrIntentExists         = errors.New("payment intent already exists")
 	ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+	ErrIdempotencyMismatch  = errors.New("idempotency key reused with different parameters")
+	ErrOperationNotFound    = errors.New("payment operation not found")
+	ErrInvalidRequest       = errors.New("invalid request")
 )
 
 type PaymentState string
@@ -22,6 +26,7 @@ const (
 	StateCaptured   PaymentState = "CAPTURED"
 	StateFailed     PaymentState = "FAILED"
 	StateRefunded   PaymentState = "REFUNDED"
+	StateCanceled   PaymentState = "CANCELED"
 )
 
 type PaymentIntent struct {
@@ -63,6 +68,37 @@ type RefundRequest struct {
 	IdempotencyKey string
 }
 
+type CancelRequest struct {
+	IntentID       string
+	Reason         string
+	IdempotencyKey string
+}
+
+// Operation names a mutating operation on an intent. Idempotency keys are
+// scoped per merchant and operation, so one key may be used for both the
+// capture and the refund of an intent.
+type Operation string
+
+const (
+	OperationAuthorize Operation = "authorize"
+	OperationCapture   Operation = "capture"
+	OperationRefund    Operation = "refund"
+	OperationCancel    Operation = "cancel"
+)
+
+// OperationRecord remembers an operation performed under an idempotency key
+// together with the intent as it stood right after, so a retry returns the
+// original result.
+type OperationRecord struct {
+	MerchantID     string
+	Operation      Operation
+	IdempotencyKey string
+	IntentID       string
+	Amount         int64
+	Result         PaymentIntent
+	CreatedAt      time.Time
+}
+
 type StateTransition struct {
 	From PaymentState
 	To   PaymentState
@@ -74,6 +110,8 @@ var allowedTransitions = map[StateTransition]bool{
 	{From: StateAuthorized, To: StateCaptured}: true,
 	{From: StateAuthorized, To: StateFailed}:   true,
 	{From: StateCaptured, To: StateRefunded}:   true,
+	{From: StateCreated, To: StateCanceled}:    true,
+	{From: StateAuthorized, To: StateCanceled}: true,
 }
 
 func CanTransition(from, to PaymentState) bool {
@@ -88,11 +126,19 @@ func ValidateTransition(from, to PaymentState) error {
 }
 
 type Repository interface {
+	// Create returns ErrIdempotencyKeyExists if the merchant already has an
+	// intent with the same idempotency key.
 	Create(ctx context.Context, intent *PaymentIntent) error
 	Get(ctx context.Context, id string) (*PaymentIntent, error)
 	GetByIdempotencyKey(ctx context.Context, merchantID, key string) (*PaymentIntent, error)
 	UpdateState(ctx context.Context, id string, state PaymentState, expectedVersion int64) error
 	UpdateStateWithProvider(ctx context.Context, id string, state PaymentState, provider, providerPaymentID string, expectedVersion int64) error
+	// ApplyOperation updates the intent like UpdateStateWithProvider and
+	// stores op, with Result set to the updated intent, in the same
+	// transaction. It returns ErrIdempotencyKeyExists if op's key has already
+	// been used for the same merchant and operation.
+	ApplyOperation(ctx context.Context, op *OperationRecord, state PaymentState, provider, providerPaymentID string, expectedVersion int64) error
+	GetOperation(ctx context.Context, merchantID string, operation Operation, key string) (*OperationRecord, error)
 	List(ctx context.Context, merchantID string, limit int) ([]*PaymentIntent, error)
 }
 
@@ -102,4 +148,6 @@ type Service interface {
 	AuthorizeIntent(ctx context.Context, req AuthorizeRequest) (*PaymentIntent, error)
 	CaptureIntent(ctx context.Context, req CaptureRequest) (*PaymentIntent, error)
 	RefundIntent(ctx context.Context, req RefundRequest) (*PaymentIntent, error)
+	CancelIntent(ctx context.Context, req CancelRequest) (*PaymentIntent, error)
+	ListIntents(ctx context.Context, merchantID string, limit int) ([]*PaymentIntent, error)
 }
@@ -16,10 +16,14 @@ func TestCanTransition(t *testing.T) {
 		{"AUTHORIZED to CAPTURED", StateAuthorized, StateCaptured, true},
 		{"AUTHORIZED to FAILED", StateAuthorized, StateFailed, true},
 		{"CAPTURED to REFUNDED", StateCaptured, StateRefunded, true},
+		{"CREATED to CANCELED", StateCreated, StateCanceled, true},
+		{"AUTHORIZED to CANCELED", StateAuthorized, StateCanceled, true},
 		{"CREATED to CAPTURED", StateCreated, StateCaptured, false},
 		{"CAPTURED to CREATED", StateCaptured, StateCreated, false},
 		{"REFUNDED to CAPTURED", StateRefunded, StateCaptured, false},
 		{"FAILED to AUTHORIZED", StateFailed, StateAuthorized, false},
+		{"CAPTURED to CANCELED", StateCaptured, StateCanceled, false},
+		{"CANCELED to AUTHORIZED", StateCanceled, StateAuthorized, false},
 	}
 
 	for _, tt := range tests {
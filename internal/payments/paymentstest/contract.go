@@ -0,0 +1,300 @@
+// Package paymentstest holds the behavior every payments.Repository must
+// share, so that the in-memory and Postgres repositories can be held to one
+// contract.
+package paymentstest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+)
+
+// RunRepositoryTests runs the contract against repositories built by
+// newRepo. Each subtest gets its own, empty repository.
+func RunRepositoryTests(t *testing.T, newRepo func(t *testing.T) payments.Repository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo payments.Repository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"OptimisticVersioning", testOptimisticVersioning},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"Operations", testOperations},
+		{"List", testList},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func newIntent(id, merchantID, key string) *payments.PaymentIntent {
+	return &payments.PaymentIntent{
+		ID:             id,
+		MerchantID:     merchantID,
+		Amount:         1000,
+		Currency:       "USD",
+		State:          payments.StateCreated,
+		IdempotencyKey: key,
+	}
+}
+
+func create(t *testing.T, repo payments.Repository, intent *payments.PaymentIntent) {
+	t.Helper()
+	if err := repo.Create(context.Background(), intent); err != nil {
+		t.Fatalf("Failed to create intent %s: %v", intent.ID, err)
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo payments.Repository) {
+	ctx := context.Background()
+
+	intent := newIntent("pi_1", "merchant_1", "")
+	create(t, repo, intent)
+	if intent.Version != 0 || intent.CreatedAt.IsZero() {
+		t.Errorf("Expected version 0 and timestamps to be set, got %+v", intent)
+	}
+
+	got, err := repo.Get(ctx, "pi_1")
+	if err != nil {
+		t.Fatalf("Failed to get intent: %v", err)
+	}
+	if got.MerchantID != "merchant_1" || got.Amount != 1000 || got.State != payments.StateCreated {
+		t.Errorf("Unexpected intent: %+v", got)
+	}
+	if _, err := repo.Get(ctx, "pi_missing"); err != payments.ErrIntentNotFound {
+		t.Errorf("Expected ErrIntentNotFound, got %v", err)
+	}
+	if err := repo.Create(ctx, newIntent("pi_1", "merchant_1", "")); err != payments.ErrIntentExists {
+		t.Errorf("Expected ErrIntentExists, got %v", err)
+	}
+
+	got.State = payments.StateFailed
+	if stored, _ := repo.Get(ctx, "pi_1"); stored.State != payments.StateCreated {
+		t.Error("Expected returned intents not to alias stored state")
+	}
+}
+
+func testIdempotencyKeys(t *testing.T, repo payments.Repository) {
+	ctx := context.Background()
+
+	create(t, repo, newIntent("pi_1", "merchant_1", "key_1"))
+	if err := repo.Create(ctx, newIntent("pi_2", "merchant_1", "key_1")); err != payments.ErrIdempotencyKeyExists {
+		t.Errorf("Expected ErrIdempotencyKeyExists, got %v", err)
+	}
+	create(t, repo, newIntent("pi_3", "merchant_2", "key_1"))
+	create(t, repo, newIntent("pi_4", "merchant_1", ""))
+	create(t, repo, newIntent("pi_5", "merchant_1", ""))
+
+	got, err := repo.GetByIdempotencyKey(ctx, "merchant_1", "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get intent by idempotency key: %v", err)
+	}
+	if got.ID != "pi_1" {
+		t.Errorf("Expected pi_1, got %s", got.ID)
+	}
+	got, err = repo.GetByIdempotencyKey(ctx, "merchant_2", "key_1")
+	if err != nil || got.ID != "pi_3" {
+		t.Errorf("Expected keys to be scoped per merchant, got %v, %v", got, err)
+	}
+	if _, err := repo.GetByIdempotencyKey(ctx, "merchant_1", "key_missing"); err != payments.ErrIntentNotFound {
+		t.Errorf("Expected ErrIntentNotFound, got %v", err)
+	}
+
+	const workers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.Create(ctx, newIntent(fmt.Sprintf("pi_race_%d", i), "merchant_1", "key_race"))
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			} else if err != payments.ErrIdempotencyKeyExists {
+				t.Errorf("Expected ErrIdempotencyKeyExists, got %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Errorf("Expected exactly one concurrent create to win, got %d", created)
+	}
+}
+
+func testOptimisticVersioning(t *testing.T, repo payments.Repository) {
+	ctx := context.Background()
+	create(t, repo, newIntent("pi_1", "merchant_1", ""))
+
+	if err := repo.UpdateStateWithProvider(ctx, "pi_1", payments.StateAuthorized, "mock_provider", "psp_1", 0); err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	if err := repo.UpdateState(ctx, "pi_1", payments.StateCaptured, 0); err != payments.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch for a stale version, got %v", err)
+	}
+	if err := repo.UpdateState(ctx, "pi_1", payments.StateCaptured, 1); err != nil {
+		t.Fatalf("Failed to capture: %v", err)
+	}
+	if err := repo.UpdateState(ctx, "pi_missing", payments.StateCaptured, 0); err != payments.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch for a missing intent, got %v", err)
+	}
+
+	got, err := repo.Get(ctx, "pi_1")
+	if err != nil {
+		t.Fatalf("Failed to get intent: %v", err)
+	}
+	if got.State != payments.StateCaptured || got.Version != 2 {
+		t.Errorf("Expected CAPTURED at version 2, got %s at %d", got.State, got.Version)
+	}
+	if got.SelectedProvider != "mock_provider" || got.ProviderPaymentID != "psp_1" {
+		t.Errorf("Expected provider to be kept, got %+v", got)
+	}
+	if got.UpdatedAt.Before(got.CreatedAt) {
+		t.Error("Expected updated_at not to precede created_at")
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, repo payments.Repository) {
+	ctx := context.Background()
+	create(t, repo, newIntent("pi_1", "merchant_1", ""))
+
+	const workers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.UpdateState(ctx, "pi_1", payments.StateAuthorized, 0); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if err != payments.ErrVersionMismatch {
+				t.Errorf("Expected ErrVersionMismatch, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("Expected exactly one update to win, got %d", succeeded)
+	}
+}
+
+func testOperations(t *testing.T, repo payments.Repository) {
+	ctx := context.Background()
+	create(t, repo, newIntent("pi_1", "merchant_1", ""))
+	create(t, repo, newIntent("pi_2", "merchant_1", ""))
+
+	if _, err := repo.GetOperation(ctx, "merchant_1", payments.OperationAuthorize, "key_1"); err != payments.ErrOperationNotFound {
+		t.Errorf("Expected ErrOperationNotFound, got %v", err)
+	}
+
+	op := &payments.OperationRecord{
+		MerchantID:     "merchant_1",
+		Operation:      payments.OperationAuthorize,
+		IdempotencyKey: "key_1",
+		IntentID:       "pi_1",
+	}
+	if err := repo.ApplyOperation(ctx, op, payments.StateAuthorized, "mock_provider", "psp_1", 0); err != nil {
+		t.Fatalf("Failed to apply operation: %v", err)
+	}
+	if op.Result.State != payments.StateAuthorized || op.Result.Version != 1 || op.Result.ProviderPaymentID != "psp_1" {
+		t.Errorf("Expected the updated intent as result, got %+v", op.Result)
+	}
+
+	got, err := repo.GetOperation(ctx, "merchant_1", payments.OperationAuthorize, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get operation: %v", err)
+	}
+	if got.IntentID != "pi_1" || got.Result.ID != "pi_1" || got.Result.State != payments.StateAuthorized || got.Result.Version != 1 {
+		t.Errorf("Unexpected operation %+v", got)
+	}
+
+	duplicate := &payments.OperationRecord{
+		MerchantID:     "merchant_1",
+		Operation:      payments.OperationAuthorize,
+		IdempotencyKey: "key_1",
+		IntentID:       "pi_2",
+	}
+	if err := repo.ApplyOperation(ctx, duplicate, payments.StateAuthorized, "mock_provider", "psp_2", 0); err != payments.ErrIdempotencyKeyExists {
+		t.Errorf("Expected ErrIdempotencyKeyExists, got %v", err)
+	}
+	if intent, _ := repo.Get(ctx, "pi_2"); intent.State != payments.StateCreated || intent.Version != 0 {
+		t.Errorf("Expected a rejected operation to leave the intent alone, got %+v", intent)
+	}
+
+	stale := &payments.OperationRecord{
+		MerchantID:     "merchant_1",
+		Operation:      payments.OperationCapture,
+		IdempotencyKey: "key_2",
+		IntentID:       "pi_1",
+		Amount:         1000,
+	}
+	if err := repo.ApplyOperation(ctx, stale, payments.StateCaptured, "mock_provider", "psp_1", 0); err != payments.ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, got %v", err)
+	}
+	if _, err := repo.GetOperation(ctx, "merchant_1", payments.OperationCapture, "key_2"); err != payments.ErrOperationNotFound {
+		t.Errorf("Expected a failed operation not to be recorded, got %v", err)
+	}
+
+	// Keys are scoped per operation and per merchant.
+	capture := &payments.OperationRecord{
+		MerchantID:     "merchant_1",
+		Operation:      payments.OperationCapture,
+		IdempotencyKey: "key_1",
+		IntentID:       "pi_1",
+		Amount:         1000,
+	}
+	if err := repo.ApplyOperation(ctx, capture, payments.StateCaptured, "mock_provider", "psp_1", 1); err != nil {
+		t.Fatalf("Expected the same key to be usable for another operation, got %v", err)
+	}
+	if capture.Result.State != payments.StateCaptured || capture.Result.SelectedProvider != "mock_provider" {
+		t.Errorf("Unexpected capture result %+v", capture.Result)
+	}
+	if _, err := repo.GetOperation(ctx, "merchant_2", payments.OperationCapture, "key_1"); err != payments.ErrOperationNotFound {
+		t.Errorf("Expected keys to be scoped per merchant, got %v", err)
+	}
+
+	// Later changes to the intent do not alter the recorded result.
+	if err := repo.UpdateState(ctx, "pi_1", payments.StateRefunded, 2); err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+	got, err = repo.GetOperation(ctx, "merchant_1", payments.OperationCapture, "key_1")
+	if err != nil || got.Result.State != payments.StateCaptured || got.Amount != 1000 {
+		t.Errorf("Expected the recorded capture result, got %+v, %v", got, err)
+	}
+}
+
+func testList(t *testing.T, repo payments.Repository) {
+	ctx := context.Background()
+
+	for _, id := range []string{"pi_1", "pi_2", "pi_3"} {
+		create(t, repo, newIntent(id, "merchant_1", ""))
+		time.Sleep(time.Millisecond)
+	}
+	create(t, repo, newIntent("pi_other", "merchant_2", ""))
+
+	intents, err := repo.List(ctx, "merchant_1", 2)
+	if err != nil {
+		t.Fatalf("Failed to list intents: %v", err)
+	}
+	if len(intents) != 2 || intents[0].ID != "pi_3" || intents[1].ID != "pi_2" {
+		t.Errorf("Expected newest two intents of merchant_1, got %d", len(intents))
+	}
+
+	intents, err = repo.List(ctx, "merchant_missing", 10)
+	if err != nil || len(intents) != 0 {
+		t.Errorf("Expected no intents, got %d, %v", len(intents), err)
+	}
+}
@@ -3,8 +3,11 @@ package payments
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type postgresRepository struct {
@@ -22,22 +25,35 @@ func (r *postgresRepository) Create(ctx context.Context, intent *PaymentIntent)
 			idempotency_key, created_at, updated_at
 		)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (merchant_id, idempotency_key) DO NOTHING
 	`
 	now := time.Now()
-	_, err := r.db.ExecContext(ctx, query,
+	result, err := r.db.ExecContext(ctx, query,
 		intent.ID,
 		intent.MerchantID,
 		intent.Amount,
 		intent.Currency,
 		intent.State,
 		0,
-		intent.IdempotencyKey,
+		sql.NullString{String: intent.IdempotencyKey, Valid: intent.IdempotencyKey != ""},
 		now,
 		now,
 	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrIntentExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create payment intent: %w", err)
 	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrIdempotencyKeyExists
+	}
+
 	intent.Version = 0
 	intent.CreatedAt = now
 	intent.UpdatedAt = now
@@ -179,6 +195,114 @@ func (r *postgresRepository) UpdateStateWithProvider(ctx context.Context, id str
 	return nil
 }
 
+func (r *postgresRepository) ApplyOperation(ctx context.Context, op *OperationRecord, state PaymentState, provider, providerPaymentID string, expectedVersion int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE payment_intents
+		SET state = $1, version = version + 1, updated_at = $2,
+		    selected_provider = NULLIF($3, ''), provider_payment_id = NULLIF($4, '')
+		WHERE id = $5 AND version = $6
+		RETURNING id, merchant_id, amount, currency, state, version,
+			idempotency_key, selected_provider, provider_payment_id,
+			created_at, updated_at
+	`
+	now := time.Now()
+	intent, err := scanIntent(tx.QueryRowContext(ctx, query, state, now, provider, providerPaymentID, op.IntentID, expectedVersion))
+	if err == sql.ErrNoRows {
+		return ErrVersionMismatch
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update payment intent state: %w", err)
+	}
+
+	result, err := json.Marshal(intent)
+	if err != nil {
+		return fmt.Errorf("failed to encode operation result: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO payment_operations (
+			merchant_id, operation, idempotency_key, intent_id, amount, result, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (merchant_id, operation, idempotency_key) DO NOTHING
+	`, op.MerchantID, op.Operation, op.IdempotencyKey, op.IntentID, op.Amount, result, now)
+	if err != nil {
+		return fmt.Errorf("failed to record payment operation: %w", err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if inserted == 0 {
+		return ErrIdempotencyKeyExists
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit payment operation: %w", err)
+	}
+	op.Result = *intent
+	op.CreatedAt = now
+	return nil
+}
+
+func (r *postgresRepository) GetOperation(ctx context.Context, merchantID string, operation Operation, key string) (*OperationRecord, error) {
+	query := `
+		SELECT merchant_id, operation, idempotency_key, intent_id, amount, result, created_at
+		FROM payment_operations
+		WHERE merchant_id = $1 AND operation = $2 AND idempotency_key = $3
+	`
+	op := &OperationRecord{}
+	var result []byte
+	err := r.db.QueryRowContext(ctx, query, merchantID, operation, key).Scan(
+		&op.MerchantID,
+		&op.Operation,
+		&op.IdempotencyKey,
+		&op.IntentID,
+		&op.Amount,
+		&result,
+		&op.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment operation: %w", err)
+	}
+	if err := json.Unmarshal(result, &op.Result); err != nil {
+		return nil, fmt.Errorf("failed to decode operation result: %w", err)
+	}
+	return op, nil
+}
+
+func scanIntent(row *sql.Row) (*PaymentIntent, error) {
+	intent := &PaymentIntent{}
+	var idempotencyKey, selectedProvider, providerPaymentID sql.NullString
+	err := row.Scan(
+		&intent.ID,
+		&intent.MerchantID,
+		&intent.Amount,
+		&intent.Currency,
+		&intent.State,
+		&intent.Version,
+		&idempotencyKey,
+		&selectedProvider,
+		&providerPaymentID,
+		&intent.CreatedAt,
+		&intent.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	intent.IdempotencyKey = idempotencyKey.String
+	intent.SelectedProvider = selectedProvider.String
+	intent.ProviderPaymentID = providerPaymentID.String
+	return intent, nil
+}
+
 func (r *postgresRepository) List(ctx context.Context, merchantID string, limit int) ([]*PaymentIntent, error) {
 	query := `
 		SELECT id, merchant_id, amount, currency, state, version,
@@ -4,37 +4,43 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/google/uuid"
 	"github.com/thilakshekharshriyan/playflow/internal/platform"
 )
 
 type service struct {
-	repo Repository
+	repo      Repository
+	connector Connector
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+type ServiceOption func(*service)
+
+// WithConnector replaces the mock PSP connector.
+func WithConnector(connector Connector) ServiceOption {
+	return func(s *service) {
+		s.connector = connector
+	}
+}
+
+func NewService(repo Repository, opts ...ServiceOption) Service {
+	s := &service{
+		repo:      repo,
+		connector: NewMockConnector(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *service) CreateIntent(ctx context.Context, req CreateIntentRequest) (*PaymentIntent, error) {
 	if req.MerchantID == "" {
-		return nil, fmt.Errorf("merchant ID is required")
+		return nil, invalidParam("merchant_id", "merchant ID is required")
 	}
 	if req.Amount <= 0 {
-		return nil, fmt.Errorf("amount must be positive")
+		return nil, invalidParam("amount", "amount must be positive")
 	}
 	if req.Currency == "" {
-		return nil, fmt.Errorf("currency is required")
-	}
-
-	if req.IdempotencyKey != "" {
-		existing, err := s.repo.GetByIdempotencyKey(ctx, req.MerchantID, req.IdempotencyKey)
-		if err != nil && err != ErrIntentNotFound {
-			return nil, fmt.Errorf("failed to check idempotency: %w", err)
-		}
-		if existing != nil {
-			return existing, nil
-		}
+		return nil, invalidParam("currency", "currency is required")
 	}
 
 	intent := &PaymentIntent{
@@ -46,74 +52,224 @@ func (s *service) CreateIntent(ctx context.Context, req CreateIntentRequest) (*P
 		IdempotencyKey: req.IdempotencyKey,
 	}
 
-	if err := s.repo.Create(ctx, intent); err != nil {
+	// The insert itself decides which of several concurrent requests with the
+	// same key wins; the others return the winner's intent.
+	err := s.repo.Create(ctx, intent)
+	if err == ErrIdempotencyKeyExists {
+		return s.existingIntent(ctx, req)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to create intent: %w", err)
 	}
 
 	return intent, nil
 }
 
+// existingIntent returns the intent created earlier under req's idempotency
+// key, provided it was created with the same parameters.
+func (s *service) existingIntent(ctx context.Context, req CreateIntentRequest) (*PaymentIntent, error) {
+	existing, err := s.repo.GetByIdempotencyKey(ctx, req.MerchantID, req.IdempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotency: %w", err)
+	}
+	if existing.Amount != req.Amount || existing.Currency != req.Currency {
+		return nil, fmt.Errorf("%w: key %q was used for intent %s of %d %s",
+			ErrIdempotencyMismatch, req.IdempotencyKey, existing.ID, existing.Amount, existing.Currency)
+	}
+	return existing, nil
+}
+
 func (s *service) GetIntent(ctx context.Context, id string) (*PaymentIntent, error) {
 	return s.repo.Get(ctx, id)
 }
 
 func (s *service) AuthorizeIntent(ctx context.Context, req AuthorizeRequest) (*PaymentIntent, error) {
-	intent, err := s.repo.Get(ctx, req.IntentID)
-	if err != nil {
-		return nil, err
-	}
+	return s.perform(ctx, operation{
+		kind:     OperationAuthorize,
+		intentID: req.IntentID,
+		key:      req.IdempotencyKey,
+		to:       StateAuthorized,
+	})
+}
 
-	if err := ValidateTransition(intent.State, StateAuthorized); err != nil {
-		return nil, err
-	}
+func (s *service) CaptureIntent(ctx context.Context, req CaptureRequest) (*PaymentIntent, error) {
+	return s.perform(ctx, operation{
+		kind:     OperationCapture,
+		intentID: req.IntentID,
+		amount:   req.Amount,
+		key:      req.IdempotencyKey,
+		to:       StateCaptured,
+		validate: func(intent *PaymentIntent) error {
+			if req.Amount < 0 {
+				return invalidParam("amount", "capture amount cannot be negative")
+			}
+			if req.Amount > intent.Amount {
+				return invalidParam("amount", "capture amount cannot exceed intent amount")
+			}
+			return nil
+		},
+	})
+}
 
-	providerPaymentID := fmt.Sprintf("psp_%s", uuid.New().String())
+func (s *service) RefundIntent(ctx context.Context, req RefundRequest) (*PaymentIntent, error) {
+	return s.perform(ctx, operation{
+		kind:     OperationRefund,
+		intentID: req.IntentID,
+		amount:   req.Amount,
+		key:      req.IdempotencyKey,
+		to:       StateRefunded,
+		validate: func(intent *PaymentIntent) error {
+			if req.Amount < 0 {
+				return invalidParam("amount", "refund amount cannot be negative")
+			}
+			if req.Amount > intent.Amount {
+				return invalidParam("amount", "refund amount cannot exceed intent amount")
+			}
+			return nil
+		},
+	})
+}
 
-	if err := s.repo.UpdateStateWithProvider(ctx, intent.ID, StateAuthorized, "mock_provider", providerPaymentID, intent.Version); err != nil {
-		return nil, fmt.Errorf("failed to authorize intent: %w", err)
-	}
+func (s *service) CancelIntent(ctx context.Context, req CancelRequest) (*PaymentIntent, error) {
+	return s.perform(ctx, operation{
+		kind:     OperationCancel,
+		intentID: req.IntentID,
+		key:      req.IdempotencyKey,
+		to:       StateCanceled,
+	})
+}
 
-	return s.repo.Get(ctx, intent.ID)
+// operation is one mutating call on an intent, as run by perform.
+type operation struct {
+	kind     Operation
+	intentID string
+	amount   int64
+	key      string
+	to       PaymentState
+	validate func(intent *PaymentIntent) error
 }
 
-func (s *service) CaptureIntent(ctx context.Context, req CaptureRequest) (*PaymentIntent, error) {
-	intent, err := s.repo.Get(ctx, req.IntentID)
+// perform moves an intent to op.to through the connector. With an
+// idempotency key, the operation is recorded atomically with the state change
+// and a retry of it returns the recorded intent instead of calling the PSP
+// again. A retry that arrives while the original is still running calls the
+// PSP with the same key, so the PSP deduplicates it, and then loses the
+// version check and replays the winner's result.
+func (s *service) perform(ctx context.Context, op operation) (*PaymentIntent, error) {
+	intent, err := s.repo.Get(ctx, op.intentID)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := ValidateTransition(intent.State, StateCaptured); err != nil {
-		return nil, err
+	if op.key != "" {
+		if result, err := s.replay(ctx, intent.MerchantID, op); err != ErrOperationNotFound {
+			return result, err
+		}
 	}
 
-	if req.Amount > intent.Amount {
-		return nil, fmt.Errorf("capture amount cannot exceed intent amount")
+	if err := ValidateTransition(intent.State, op.to); err != nil {
+		return nil, err
+	}
+	if op.validate != nil {
+		if err := op.validate(intent); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.repo.UpdateState(ctx, intent.ID, StateCaptured, intent.Version); err != nil {
-		return nil, fmt.Errorf("failed to capture intent: %w", err)
+	provider, providerPaymentID := intent.SelectedProvider, intent.ProviderPaymentID
+	connectorReq := ConnectorRequest{
+		Operation:         op.kind,
+		IntentID:          intent.ID,
+		MerchantID:        intent.MerchantID,
+		ProviderPaymentID: intent.ProviderPaymentID,
+		Amount:            op.amount,
+		Currency:          intent.Currency,
+		IdempotencyKey:    op.key,
+	}
+	switch op.kind {
+	case OperationAuthorize:
+		connectorReq.Amount = intent.Amount
+		providerPaymentID, err = s.connector.Authorize(ctx, connectorReq)
+		provider = s.connector.Name()
+	case OperationCapture:
+		err = s.connector.Capture(ctx, connectorReq)
+	case OperationRefund:
+		err = s.connector.Refund(ctx, connectorReq)
+	case OperationCancel:
+		err = s.connector.Cancel(ctx, connectorReq)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s intent with %s: %w", op.kind, s.connector.Name(), err)
 	}
 
-	return s.repo.Get(ctx, intent.ID)
-}
+	if op.key == "" {
+		if op.kind == OperationAuthorize {
+			err = s.repo.UpdateStateWithProvider(ctx, intent.ID, op.to, provider, providerPaymentID, intent.Version)
+		} else {
+			err = s.repo.UpdateState(ctx, intent.ID, op.to, intent.Version)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s intent: %w", op.kind, err)
+		}
+		return s.repo.Get(ctx, intent.ID)
+	}
 
-func (s *service) RefundIntent(ctx context.Context, req RefundRequest) (*PaymentIntent, error) {
-	intent, err := s.repo.Get(ctx, req.IntentID)
+	record := &OperationRecord{
+		MerchantID:     intent.MerchantID,
+		Operation:      op.kind,
+		IdempotencyKey: op.key,
+		IntentID:       intent.ID,
+		Amount:         op.amount,
+	}
+	err = s.repo.ApplyOperation(ctx, record, op.to, provider, providerPaymentID, intent.Version)
+	if err == ErrIdempotencyKeyExists || err == ErrVersionMismatch {
+		if result, replayErr := s.replay(ctx, intent.MerchantID, op); replayErr != ErrOperationNotFound {
+			return result, replayErr
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to %s intent: %w", op.kind, err)
 	}
 
-	if err := ValidateTransition(intent.State, StateRefunded); err != nil {
+	result := record.Result
+	return &result, nil
+}
+
+// replay returns the recorded result of op, or ErrOperationNotFound if its
+// key has not been used yet.
+func (s *service) replay(ctx context.Context, merchantID string, op operation) (*PaymentIntent, error) {
+	record, err := s.repo.GetOperation(ctx, merchantID, op.kind, op.key)
+	if err == ErrOperationNotFound {
 		return nil, err
 	}
-
-	if req.Amount > intent.Amount {
-		return nil, fmt.Errorf("refund amount cannot exceed intent amount")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotency: %w", err)
 	}
-
-	if err := s.repo.UpdateState(ctx, intent.ID, StateRefunded, intent.Version); err != nil {
-		return nil, fmt.Errorf("failed to refund intent: %w", err)
+	if record.IntentID != op.intentID || record.Amount != op.amount {
+		return nil, fmt.Errorf("%w: %s key %q was used for intent %s with amount %d",
+			ErrIdempotencyMismatch, op.kind, op.key, record.IntentID, record.Amount)
 	}
+	result := record.Result
+	return &result, nil
+}
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
 
-	return s.repo.Get(ctx, intent.ID)
+// ListIntents returns the merchant's most recent intents first. A
+// non-positive limit falls back to DefaultListLimit; larger limits are capped
+// at MaxListLimit.
+func (s *service) ListIntents(ctx context.Context, merchantID string, limit int) ([]*PaymentIntent, error) {
+	if merchantID == "" {
+		return nil, invalidParam("merchant_id", "merchant ID is required")
+	}
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+	return s.repo.List(ctx, merchantID, limit)
 }
@@ -0,0 +1,237 @@
+package payments_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/internal/payments"
+)
+
+// recordingConnector approves everything and records the calls it receives.
+type recordingConnector struct {
+	mu    sync.Mutex
+	calls []payments.ConnectorRequest
+}
+
+func (c *recordingConnector) record(req payments.ConnectorRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, req)
+}
+
+func (c *recordingConnector) count(op payments.Operation) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, call := range c.calls {
+		if call.Operation == op {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *recordingConnector) Name() string { return "recording" }
+
+func (c *recordingConnector) Authorize(ctx context.Context, req payments.ConnectorRequest) (string, error) {
+	c.record(req)
+	return "psp_" + req.IntentID, nil
+}
+
+func (c *recordingConnector) Capture(ctx context.Context, req payments.ConnectorRequest) error {
+	c.record(req)
+	return nil
+}
+
+func (c *recordingConnector) Refund(ctx context.Context, req payments.ConnectorRequest) error {
+	c.record(req)
+	return nil
+}
+
+func (c *recordingConnector) Cancel(ctx context.Context, req payments.ConnectorRequest) error {
+	c.record(req)
+	return nil
+}
+
+func newTestService(t *testing.T) (payments.Service, *recordingConnector, *payments.PaymentIntent) {
+	t.Helper()
+	connector := &recordingConnector{}
+	svc := payments.NewService(payments.NewMemoryRepository(), payments.WithConnector(connector))
+	intent, err := svc.CreateIntent(context.Background(), payments.CreateIntentRequest{
+		MerchantID: "merchant_1",
+		Amount:     10000,
+		Currency:   "USD",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create intent: %v", err)
+	}
+	return svc, connector, intent
+}
+
+func TestService_OperationRetriesReturnOriginalResult(t *testing.T) {
+	svc, connector, intent := newTestService(t)
+	ctx := context.Background()
+
+	authReq := payments.AuthorizeRequest{IntentID: intent.ID, IdempotencyKey: "auth_1"}
+	authorized, err := svc.AuthorizeIntent(ctx, authReq)
+	if err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	if authorized.SelectedProvider != "recording" || authorized.ProviderPaymentID != "psp_"+intent.ID {
+		t.Errorf("Expected the connector's payment ID, got %+v", authorized)
+	}
+
+	captureReq := payments.CaptureRequest{IntentID: intent.ID, Amount: 10000, IdempotencyKey: "capture_1"}
+	captured, err := svc.CaptureIntent(ctx, captureReq)
+	if err != nil {
+		t.Fatalf("Failed to capture: %v", err)
+	}
+	retried, err := svc.CaptureIntent(ctx, captureReq)
+	if err != nil {
+		t.Fatalf("Expected a retried capture to succeed, got %v", err)
+	}
+	if retried.State != payments.StateCaptured || retried.Version != captured.Version {
+		t.Errorf("Expected the original capture result, got %+v", retried)
+	}
+	if n := connector.count(payments.OperationCapture); n != 1 {
+		t.Errorf("Expected the PSP to be asked to capture once, got %d", n)
+	}
+
+	if _, err := svc.RefundIntent(ctx, payments.RefundRequest{IntentID: intent.ID, IdempotencyKey: "refund_1"}); err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+
+	// The intent has moved on, but retries still see the state they produced.
+	retriedAuth, err := svc.AuthorizeIntent(ctx, authReq)
+	if err != nil {
+		t.Fatalf("Expected a retried authorization to succeed, got %v", err)
+	}
+	if retriedAuth.State != payments.StateAuthorized || retriedAuth.Version != authorized.Version {
+		t.Errorf("Expected the original authorization result, got %+v", retriedAuth)
+	}
+	if n := connector.count(payments.OperationAuthorize); n != 1 {
+		t.Errorf("Expected the PSP to be asked to authorize once, got %d", n)
+	}
+
+	for _, call := range connector.calls {
+		if call.IdempotencyKey == "" {
+			t.Errorf("Expected the idempotency key to be forwarded for %s", call.Operation)
+		}
+	}
+}
+
+func TestService_OperationKeyMismatch(t *testing.T) {
+	svc, _, intent := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := svc.AuthorizeIntent(ctx, payments.AuthorizeRequest{IntentID: intent.ID}); err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	if _, err := svc.CaptureIntent(ctx, payments.CaptureRequest{IntentID: intent.ID, Amount: 5000, IdempotencyKey: "capture_1"}); err != nil {
+		t.Fatalf("Failed to capture: %v", err)
+	}
+
+	_, err := svc.CaptureIntent(ctx, payments.CaptureRequest{IntentID: intent.ID, Amount: 6000, IdempotencyKey: "capture_1"})
+	if !errors.Is(err, payments.ErrIdempotencyMismatch) {
+		t.Errorf("Expected ErrIdempotencyMismatch for a different amount, got %v", err)
+	}
+
+	other, err := svc.CreateIntent(ctx, payments.CreateIntentRequest{MerchantID: "merchant_1", Amount: 5000, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Failed to create intent: %v", err)
+	}
+	if _, err := svc.AuthorizeIntent(ctx, payments.AuthorizeRequest{IntentID: other.ID}); err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+	_, err = svc.CaptureIntent(ctx, payments.CaptureRequest{IntentID: other.ID, Amount: 5000, IdempotencyKey: "capture_1"})
+	if !errors.Is(err, payments.ErrIdempotencyMismatch) {
+		t.Errorf("Expected ErrIdempotencyMismatch for a different intent, got %v", err)
+	}
+}
+
+func TestService_ConcurrentCaptureRetries(t *testing.T) {
+	svc, _, intent := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := svc.AuthorizeIntent(ctx, payments.AuthorizeRequest{IntentID: intent.ID}); err != nil {
+		t.Fatalf("Failed to authorize: %v", err)
+	}
+
+	const retries = 8
+	var wg sync.WaitGroup
+	results := make(chan *payments.PaymentIntent, retries)
+	for i := 0; i < retries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			captured, err := svc.CaptureIntent(ctx, payments.CaptureRequest{IntentID: intent.ID, Amount: 10000, IdempotencyKey: "capture_1"})
+			if err != nil {
+				t.Errorf("Expected every retry to succeed, got %v", err)
+				return
+			}
+			results <- captured
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for captured := range results {
+		if captured.State != payments.StateCaptured || captured.Version != 2 {
+			t.Errorf("Expected one capture at version 2, got %s at %d", captured.State, captured.Version)
+		}
+	}
+	got, err := svc.GetIntent(ctx, intent.ID)
+	if err != nil || got.Version != 2 {
+		t.Errorf("Expected the intent to be captured once, got %+v, %v", got, err)
+	}
+}
+
+func TestService_CreateIntentIdempotency(t *testing.T) {
+	svc := payments.NewService(payments.NewMemoryRepository())
+	ctx := context.Background()
+	req := payments.CreateIntentRequest{
+		MerchantID:     "merchant_1",
+		Amount:         10000,
+		Currency:       "USD",
+		IdempotencyKey: "create_1",
+	}
+
+	const retries = 8
+	var wg sync.WaitGroup
+	ids := make(chan string, retries)
+	for i := 0; i < retries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			intent, err := svc.CreateIntent(ctx, req)
+			if err != nil {
+				t.Errorf("Expected every retry to succeed, got %v", err)
+				return
+			}
+			ids <- intent.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	first := ""
+	for id := range ids {
+		if first == "" {
+			first = id
+		} else if id != first {
+			t.Errorf("Expected one intent, got %s and %s", first, id)
+		}
+	}
+
+	req.Amount = 5000
+	if _, err := svc.CreateIntent(ctx, req); !errors.Is(err, payments.ErrIdempotencyMismatch) {
+		t.Errorf("Expected ErrIdempotencyMismatch for a different amount, got %v", err)
+	}
+	req.Amount = 10000
+	req.Currency = "EUR"
+	if _, err := svc.CreateIntent(ctx, req); !errors.Is(err, payments.ErrIdempotencyMismatch) {
+		t.Errorf("Expected ErrIdempotencyMismatch for a different currency, got %v", err)
+	}
+}
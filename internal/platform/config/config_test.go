@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "playflow.env")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(map[string]string{}, nil)
+	if err != nil {
+		t.Fatalf("Expected defaults to be valid, got %v", err)
+	}
+	if cfg.API.Addr() != ":8080" || cfg.API.Timeout != 30*time.Second {
+		t.Errorf("Unexpected API defaults %+v", cfg.API)
+	}
+	if cfg.Database.MaxOpenConns != 25 || cfg.Database.ConnMaxLifetime != 5*time.Minute {
+		t.Errorf("Unexpected database defaults %+v", cfg.Database)
+	}
+	if cfg.Redis.URL != "" || cfg.Idempotency.TTL != 24*time.Hour || cfg.Worker.InboxRetention != 7*24*time.Hour || cfg.Worker.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Unexpected defaults %+v", cfg)
+	}
+	if len(cfg.Kafka.Brokers) != 1 || cfg.Kafka.Brokers[0] != "localhost:9092" {
+		t.Errorf("Unexpected Kafka brokers %v", cfg.Kafka.Brokers)
+	}
+}
+
+func TestLoad_FileAndEnvironment(t *testing.T) {
+	path := writeFile(t, `
+# API
+API_PORT=9000
+export API_TIMEOUT=5s
+KAFKA_BROKERS="kafka-1:9092, kafka-2:9092"
+IDEMPOTENCY_IGNORED_FIELDS=metadata.trace_id,client_ts
+LOG_FORMAT=console
+`)
+	cfg, err := load(map[string]string{"API_PORT": "9100", "OTEL_ENABLED": "true"}, []string{path})
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if cfg.API.Port != 9100 {
+		t.Errorf("Expected the environment to override the file, got port %d", cfg.API.Port)
+	}
+	if cfg.API.Timeout != 5*time.Second || cfg.Log.Format != "console" || !cfg.OTel.Enabled {
+		t.Errorf("Expected file and environment values, got %+v", cfg)
+	}
+	if strings.Join(cfg.Kafka.Brokers, "|") != "kafka-1:9092|kafka-2:9092" {
+		t.Errorf("Unexpected brokers %v", cfg.Kafka.Brokers)
+	}
+	if len(cfg.Idempotency.IgnoredFields) != 2 {
+		t.Errorf("Unexpected ignored fields %v", cfg.Idempotency.IgnoredFields)
+	}
+}
+
+func TestLoad_EnvExample(t *testing.T) {
+	cfg, err := load(map[string]string{}, []string{"../../../.env.example"})
+	if err != nil {
+		t.Fatalf("Expected .env.example to load, got %v", err)
+	}
+	if cfg.Redis.URL != "redis://localhost:6379" || cfg.API.Host != "0.0.0.0" || cfg.PSP.StripeAPIKey == "" {
+		t.Errorf("Expected .env.example values, got %+v", cfg)
+	}
+}
+
+func TestLoad_ReportsEveryError(t *testing.T) {
+	_, err := load(map[string]string{
+		"API_PORT":                   "70000",
+		"API_TIMEOUT":                "soon",
+		"DATABASE_MAX_OPEN_CONNS":    "many",
+		"DATABASE_URL":               "mysql://user:hunter2@db:3306/payflow",
+		"OTEL_ENABLED":               "maybe",
+		"LOG_LEVEL":                  "loud",
+		"RETRY_MAX_INTERVAL":         "10ms",
+		"RATE_LIMIT_BURST":           "0",
+		"CIRCUIT_BREAKER_TIMEOUT":    "-1s",
+		"DATABASE_CONN_MAX_LIFETIME": "5m",
+	}, []string{filepath.Join(t.TempDir(), "missing.env")})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected ErrInvalidConfig, got %v", err)
+	}
+
+	for _, key := range []string{
+		"API_PORT", "API_TIMEOUT", "DATABASE_MAX_OPEN_CONNS", "DATABASE_URL", "OTEL_ENABLED",
+		"LOG_LEVEL", "RETRY_MAX_INTERVAL", "RATE_LIMIT_BURST", "CIRCUIT_BREAKER_TIMEOUT", "missing.env",
+	} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected the error to mention %s, got:\n%v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Error("Expected the error not to leak the database password")
+	}
+}
+
+func TestLoad_DatabaseURLRequiredOutsideDevelopment(t *testing.T) {
+	_, err := load(map[string]string{"APP_ENV": "production"}, nil)
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "DATABASE_URL") {
+		t.Errorf("Expected a missing DATABASE_URL to be rejected in production, got %v", err)
+	}
+
+	cfg, err := load(map[string]string{"APP_ENV": "production", "DATABASE_URL": "postgres://app@db:5432/payflow"}, nil)
+	if err != nil || cfg.Database.URL != "postgres://app@db:5432/payflow" {
+		t.Errorf("Expected an explicit DATABASE_URL to be accepted, got %v", err)
+	}
+}
+
+func TestLoad_MalformedFile(t *testing.T) {
+	path := writeFile(t, "API_PORT=9000\nnot a setting\n")
+	_, err := load(map[string]string{}, []string{path})
+	if err == nil || !strings.Contains(err.Error(), path+":2") {
+		t.Errorf("Expected the malformed line to be reported, got %v", err)
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg, err := load(map[string]string{
+		"DATABASE_URL":   "postgres://payflow:hunter2@db:5432/payflow?sslmode=verify-full&sslpassword=keypass1",
+		"REDIS_URL":      "redis://cache:6379?password=s3cret",
+		"STRIPE_API_KEY": "sk_live_123",
+		"API_PORT":       "9000",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if cfg.PSP.StripeAPIKey != "sk_live_123" {
+		t.Error("Expected the secret itself to be loaded")
+	}
+
+	settings := cfg.Redacted()
+	for key, value := range settings {
+		if strings.Contains(value, "hunter2") || strings.Contains(value, "keypass1") || strings.Contains(value, "s3cret") || strings.Contains(value, "sk_live") {
+			t.Errorf("Expected %s to be redacted, got %q", key, value)
+		}
+	}
+	if settings["STRIPE_API_KEY"] != redacted || settings["ADYEN_API_KEY"] != "" {
+		t.Errorf("Expected set secrets to be masked and unset ones empty, got %q and %q",
+			settings["STRIPE_API_KEY"], settings["ADYEN_API_KEY"])
+	}
+	if !strings.Contains(settings["DATABASE_URL"], "sslmode=verify-full") || settings["API_PORT"] != "9000" {
+		t.Errorf("Expected non-secret values to be kept, got %v", settings)
+	}
+}
@@ -0,0 +1,129 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Error codes shared by every domain. Codes are part of the API contract:
+// clients match on them, so they never change once published.
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeNotFound       = "not_found"
+	CodeConflict       = "conflict"
+	CodeTimeout        = "timeout"
+	CodeCanceled       = "canceled"
+	CodeInternal       = "internal_error"
+)
+
+// Error is a failure as reported to API clients. Code is stable and machine
+// readable, Message is meant for people and may change. Param names the
+// request parameter at fault, if any, and Retryable tells the client whether
+// sending the same request again may succeed. Details carries identifiers
+// that help the client act on the failure, such as the account at fault. Err
+// is the underlying error, kept for errors.Is and for logs; it is never shown
+// to clients.
+type Error struct {
+	Code      string
+	Message   string
+	Param     string
+	Status    int
+	Retryable bool
+	Details   map[string]string
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Param != "" {
+		return e.Param + ": " + e.Message
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// InvalidParam reports a bad request parameter. cause is the domain's
+// invalid-request sentinel, so errors.Is keeps matching it.
+func InvalidParam(cause error, param, message string) *Error {
+	return &Error{
+		Code:    CodeInvalidRequest,
+		Message: message,
+		Param:   param,
+		Status:  http.StatusBadRequest,
+		Err:     cause,
+	}
+}
+
+// ErrorMapping describes how a domain sentinel error is reported. Message
+// defaults to the sentinel's own text.
+type ErrorMapping struct {
+	Err       error
+	Code      string
+	Status    int
+	Param     string
+	Message   string
+	Retryable bool
+}
+
+// Detailer is implemented by domain errors that can name what they are about
+// without leaking internal context, such as the ID of the offending account.
+// MapError copies the details of the first Detailer in the chain into the
+// Error it builds.
+type Detailer interface {
+	ErrorDetails() map[string]string
+}
+
+// MapError turns err into an *Error. An *Error anywhere in the chain is
+// returned as is; otherwise the first mapping whose Err matches is used. The
+// message comes from the mapping, never from err, whose wrapped chain may
+// carry driver errors and internal context; err itself is only kept in Err.
+// Context errors become timeouts and cancellations, and anything else is an
+// internal error.
+func MapError(err error, mappings []ErrorMapping) *Error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.Err) {
+			message := m.Message
+			if message == "" {
+				message = m.Err.Error()
+			}
+			return &Error{
+				Code:      m.Code,
+				Message:   message,
+				Param:     m.Param,
+				Status:    m.Status,
+				Retryable: m.Retryable,
+				Details:   details(err),
+				Err:       err,
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: CodeTimeout, Message: "request timed out", Status: http.StatusServiceUnavailable, Retryable: true, Err: err}
+	case errors.Is(err, context.Canceled):
+		// 499 is nginx's "client closed request"; nobody is left to read it.
+		return &Error{Code: CodeCanceled, Message: "request canceled", Status: 499, Retryable: true, Err: err}
+	default:
+		return &Error{Code: CodeInternal, Message: "internal server error", Status: http.StatusInternalServerError, Retryable: true, Err: err}
+	}
+}
+
+func details(err error) map[string]string {
+	var detailer Detailer
+	if errors.As(err, &detailer) {
+		return detailer.ErrorDetails()
+	}
+	return nil
+}
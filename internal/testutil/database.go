@@ -73,17 +73,60 @@ func (tdb *TestDB) ApplyMigrations(t *testing.T) {
 	migrations := []string{
 		`CREATE TABLE accounts (
 			id VARCHAR(255) PRIMARY KEY,
+			code VARCHAR(50) UNIQUE,
 			name VARCHAR(255) NOT NULL,
 			type VARCHAR(50) NOT NULL CHECK (type IN ('ASSET', 'LIABILITY', 'REVENUE', 'EXPENSE')),
 			currency VARCHAR(3) NOT NULL,
+			parent_id VARCHAR(255) REFERENCES accounts(id),
+			status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'FROZEN', 'CLOSED')),
+			balance_constraint VARCHAR(20) NOT NULL DEFAULT 'NONE'
+				CHECK (balance_constraint IN ('NONE', 'NO_OVERDRAFT', 'CREDIT_LIMIT')),
+			credit_limit BIGINT NOT NULL DEFAULT 0 CHECK (credit_limit >= 0),
 			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
 			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
 		)`,
+		`CREATE TABLE account_status_changes (
+			id BIGSERIAL PRIMARY KEY,
+			account_id VARCHAR(255) NOT NULL REFERENCES accounts(id),
+			from_status VARCHAR(20) NOT NULL,
+			to_status VARCHAR(20) NOT NULL,
+			reason TEXT NOT NULL,
+			changed_by VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE accounting_periods (
+			id VARCHAR(255) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(20) NOT NULL CHECK (kind IN ('REGULAR', 'ADJUSTMENT')),
+			parent_period_id VARCHAR(255) REFERENCES accounting_periods(id),
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'CLOSING', 'CLOSED')),
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			CHECK (ends_at > starts_at),
+			CHECK ((kind = 'ADJUSTMENT') = (parent_period_id IS NOT NULL))
+		)`,
 		`CREATE TABLE transactions (
 			id VARCHAR(255) PRIMARY KEY,
 			description TEXT NOT NULL,
-			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+			status VARCHAR(20) NOT NULL DEFAULT 'POSTED' CHECK (status IN ('POSTED', 'PENDING', 'VOIDED')),
+			pending_transaction_id VARCHAR(255) UNIQUE REFERENCES transactions(id),
+			period_id VARCHAR(255) REFERENCES accounting_periods(id),
+			metadata JSONB NOT NULL DEFAULT '{}',
+			request_hash VARCHAR(64),
+			sequence BIGINT UNIQUE,
+			prev_hash VARCHAR(64),
+			hash VARCHAR(64),
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			effective_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE ledger_chain_head (
+			id INT PRIMARY KEY CHECK (id = 1),
+			sequence BIGINT NOT NULL,
+			hash VARCHAR(64) NOT NULL
 		)`,
+		`INSERT INTO ledger_chain_head (id, sequence, hash) VALUES (1, 0, '')`,
 		`CREATE TABLE ledger_entries (
 			id VARCHAR(255) PRIMARY KEY,
 			transaction_id VARCHAR(255) NOT NULL REFERENCES transactions(id),
@@ -91,39 +134,65 @@ func (tdb *TestDB) ApplyMigrations(t *testing.T) {
 			account_id VARCHAR(255) NOT NULL REFERENCES accounts(id),
 			amount BIGINT NOT NULL,
 			currency VARCHAR(3) NOT NULL,
+			memo TEXT,
 			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			effective_at TIMESTAMP NOT NULL DEFAULT NOW(),
 			UNIQUE (transaction_id, entry_index)
 		)`,
+		`CREATE TABLE transaction_references (
+			transaction_id VARCHAR(255) NOT NULL REFERENCES transactions(id),
+			ref_type VARCHAR(50) NOT NULL CHECK (ref_type IN ('payment_intent_id', 'refund_id', 'payout_id')),
+			ref_id VARCHAR(255) NOT NULL,
+			PRIMARY KEY (transaction_id, ref_type, ref_id)
+		)`,
+		`CREATE INDEX idx_transaction_references_ref ON transaction_references(ref_type, ref_id)`,
 		`CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries(transaction_id)`,
 		`CREATE INDEX idx_ledger_entries_account_id ON ledger_entries(account_id)`,
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX idx_ledger_entries_memo_trgm ON ledger_entries USING GIN (memo gin_trgm_ops) WHERE memo IS NOT NULL`,
 		`INSERT INTO accounts (id, name, type, currency) VALUES
 			('acc_customer_cash', 'Customer Cash', 'ASSET', 'USD'),
 			('acc_merchant_receivable', 'Merchant Receivable', 'ASSET', 'USD'),
 			('acc_platform_fee', 'Platform Fee', 'REVENUE', 'USD'),
-			('acc_merchant_payable', 'Merchant Payable', 'LIABILITY', 'USD')`,
+			('acc_merchant_payable', 'Merchant Payable', 'LIABILITY', 'USD'),
+			('acc_settlement_bank', 'Settlement Bank', 'ASSET', 'USD')`,
 		`CREATE TABLE payment_intents (
 			id VARCHAR(255) PRIMARY KEY,
 			merchant_id VARCHAR(255) NOT NULL,
 			amount BIGINT NOT NULL,
 			currency VARCHAR(3) NOT NULL,
-			state VARCHAR(50) NOT NULL CHECK (state IN ('CREATED', 'AUTHORIZED', 'CAPTURED', 'FAILED', 'REFUNDED')),
+			state VARCHAR(50) NOT NULL CHECK (state IN ('CREATED', 'AUTHORIZED', 'CAPTURED', 'FAILED', 'REFUNDED', 'CANCELED')),
 			version BIGINT NOT NULL DEFAULT 0,
 			idempotency_key VARCHAR(255),
 			selected_provider VARCHAR(100),
 			provider_payment_id VARCHAR(255),
 			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			CONSTRAINT uq_payment_intents_merchant_idempotency_key UNIQUE (merchant_id, idempotency_key)
 		)`,
 		`CREATE INDEX idx_payment_intents_merchant_id ON payment_intents(merchant_id)`,
 		`CREATE INDEX idx_payment_intents_state ON payment_intents(state)`,
+		`CREATE TABLE payment_operations (
+			merchant_id VARCHAR(255) NOT NULL,
+			operation VARCHAR(20) NOT NULL CHECK (operation IN ('authorize', 'capture', 'refund', 'cancel')),
+			idempotency_key VARCHAR(255) NOT NULL,
+			intent_id VARCHAR(255) NOT NULL REFERENCES payment_intents(id),
+			amount BIGINT NOT NULL,
+			result JSONB NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (merchant_id, operation, idempotency_key)
+		)`,
+		`CREATE INDEX idx_payment_operations_intent_id ON payment_operations(intent_id)`,
 		`CREATE TABLE idempotency_records (
 			id VARCHAR(255) PRIMARY KEY,
 			merchant_id VARCHAR(255) NOT NULL,
 			idempotency_key VARCHAR(255) NOT NULL,
 			request_hash VARCHAR(64) NOT NULL,
+			state VARCHAR(20) NOT NULL DEFAULT 'COMPLETED' CHECK (state IN ('IN_PROGRESS', 'COMPLETED')),
 			response_body TEXT,
 			status_code INT,
 			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			locked_at TIMESTAMP NOT NULL DEFAULT NOW(),
 			expires_at TIMESTAMP NOT NULL,
 			UNIQUE (merchant_id, idempotency_key)
 		)`,
@@ -159,5 +228,11 @@ func (tdb *TestDB) Truncate(t *testing.T, tables ...string) {
 		if err != nil {
 			t.Fatalf("Failed to truncate table %s: %v", table, err)
 		}
+		if table == "transactions" {
+			_, err = tdb.DB.ExecContext(ctx, "UPDATE ledger_chain_head SET sequence = 0, hash = ''")
+			if err != nil {
+				t.Fatalf("Failed to reset ledger chain head: %v", err)
+			}
+		}
 	}
 }
@@ -0,0 +1,78 @@
+//go:build integration
+// +build integration
+
+package worker_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/thilakshekharshriyan/playflow/internal/testutil"
+	"github.com/thilakshekharshriyan/playflow/internal/worker"
+)
+
+func TestPruner_PruneOnce(t *testing.T) {
+	testDB := testutil.SetupTestDB(t)
+	defer testDB.Close(t)
+	testDB.ApplyMigrations(t)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 0; i < 7; i++ {
+		expiresAt := now.Add(-time.Hour)
+		if i >= 5 {
+			expiresAt = now.Add(time.Hour)
+		}
+		_, err := testDB.DB.ExecContext(ctx, `
+			INSERT INTO idempotency_records (id, merchant_id, idempotency_key, request_hash, expires_at)
+			VALUES ($1, 'merchant_1', $1, 'hash', $2)
+		`, fmt.Sprintf("idem_%d", i), expiresAt)
+		if err != nil {
+			t.Fatalf("Failed to insert idempotency record: %v", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		processedAt := now.Add(-10 * 24 * time.Hour)
+		if i >= 3 {
+			processedAt = now.Add(-time.Hour)
+		}
+		_, err := testDB.DB.ExecContext(ctx, `INSERT INTO inbox_events (event_id, processed_at) VALUES ($1, $2)`,
+			fmt.Sprintf("evt_%d", i), processedAt)
+		if err != nil {
+			t.Fatalf("Failed to insert inbox event: %v", err)
+		}
+	}
+
+	pruner := worker.NewPruner(testDB.DB, worker.PruneConfig{
+		BatchSize:      2,
+		InboxRetention: 7 * 24 * time.Hour,
+	}, zap.NewNop())
+
+	result, err := pruner.PruneOnce(ctx)
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if result.IdempotencyRecords != 5 || result.InboxEvents != 3 {
+		t.Errorf("Expected 5 idempotency records and 3 inbox events pruned, got %+v", result)
+	}
+
+	var idempotencyLeft, inboxLeft int
+	testDB.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM idempotency_records`).Scan(&idempotencyLeft)
+	testDB.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM inbox_events`).Scan(&inboxLeft)
+	if idempotencyLeft != 2 || inboxLeft != 1 {
+		t.Errorf("Expected 2 idempotency records and 1 inbox event left, got %d and %d", idempotencyLeft, inboxLeft)
+	}
+
+	result, err = pruner.PruneOnce(ctx)
+	if err != nil || result.Total() != 0 {
+		t.Errorf("Expected second pass to prune nothing, got %+v, %v", result, err)
+	}
+	if removed := pruner.Removed(); removed.Total() != 8 {
+		t.Errorf("Expected 8 rows removed in total, got %+v", removed)
+	}
+}
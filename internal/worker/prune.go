@@ -0,0 +1,230 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrPruningFailed is returned by Run once MaxFailures passes in a row have
+// failed, so that a worker that cannot prune stops instead of looking healthy.
+var ErrPruningFailed = errors.New("pruning failed")
+
+type PruneConfig struct {
+	// Interval is the time between pruning passes.
+	Interval time.Duration
+	// BatchSize caps the rows removed by each DELETE, keeping locks and
+	// transactions short on large tables.
+	BatchSize int
+	// IdempotencyRetention is how long idempotency records are kept after
+	// they expire.
+	IdempotencyRetention time.Duration
+	// InboxRetention is how long processed inbox events are kept for
+	// deduplication.
+	InboxRetention time.Duration
+	// MaxFailures is the number of consecutive failed passes after which Run
+	// gives up.
+	MaxFailures int
+}
+
+func DefaultPruneConfig() PruneConfig {
+	return PruneConfig{
+		Interval:             time.Hour,
+		BatchSize:            1000,
+		IdempotencyRetention: 0,
+		InboxRetention:       7 * 24 * time.Hour,
+		MaxFailures:          5,
+	}
+}
+
+// PruneResult counts the rows removed from each table.
+type PruneResult struct {
+	IdempotencyRecords int64
+	InboxEvents        int64
+}
+
+func (r PruneResult) Total() int64 {
+	return r.IdempotencyRecords + r.InboxEvents
+}
+
+func (r PruneResult) add(other PruneResult) PruneResult {
+	return PruneResult{
+		IdempotencyRecords: r.IdempotencyRecords + other.IdempotencyRecords,
+		InboxEvents:        r.InboxEvents + other.InboxEvents,
+	}
+}
+
+// PruneMetrics observes pruning. Pruned is called after every pass with the
+// rows it removed, including those removed before a failure; Failed with the
+// error of each failed pass.
+type PruneMetrics interface {
+	Pruned(result PruneResult)
+	Failed(err error)
+}
+
+type noopPruneMetrics struct{}
+
+func (noopPruneMetrics) Pruned(PruneResult) {}
+func (noopPruneMetrics) Failed(error)       {}
+
+type PrunerOption func(*Pruner)
+
+func WithPruneMetrics(metrics PruneMetrics) PrunerOption {
+	return func(p *Pruner) {
+		p.metrics = metrics
+	}
+}
+
+// Pruner deletes expired idempotency records and old inbox events.
+type Pruner struct {
+	db      *sql.DB
+	config  PruneConfig
+	logger  *zap.Logger
+	metrics PruneMetrics
+	// prune runs one pass; it is PruneOnce outside of tests.
+	prune func(ctx context.Context) (PruneResult, error)
+
+	mu      sync.Mutex
+	removed PruneResult
+}
+
+func NewPruner(db *sql.DB, config PruneConfig, logger *zap.Logger, opts ...PrunerOption) *Pruner {
+	defaults := DefaultPruneConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = defaults.MaxFailures
+	}
+	p := &Pruner{db: db, config: config, logger: logger, metrics: noopPruneMetrics{}}
+	p.prune = p.PruneOnce
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Removed returns the rows removed since the pruner was created.
+func (p *Pruner) Removed() PruneResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.removed
+}
+
+// Run prunes immediately and then every Interval until ctx is done, when it
+// returns ctx.Err(). A failed pass is retried at the next tick; after
+// MaxFailures failures in a row Run returns ErrPruningFailed.
+func (p *Pruner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		result, err := p.prune(ctx)
+		p.metrics.Pruned(result)
+		fields := []zap.Field{
+			zap.Int64("idempotency_records", result.IdempotencyRecords),
+			zap.Int64("inbox_events", result.InboxEvents),
+		}
+		switch {
+		case err == nil:
+			failures = 0
+			p.logger.Info("Pruned expired rows", fields...)
+		case ctx.Err() == nil:
+			failures++
+			p.metrics.Failed(err)
+			p.logger.Error("Pruning failed", append(fields, zap.Int("consecutive_failures", failures), zap.Error(err))...)
+			if failures >= p.config.MaxFailures {
+				return fmt.Errorf("%w: %d passes in a row: %w", ErrPruningFailed, failures, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PruneOnce runs a single pass over both tables, deleting batch by batch
+// until nothing older than the retention is left. Rows deleted before an
+// error are still counted.
+func (p *Pruner) PruneOnce(ctx context.Context) (PruneResult, error) {
+	var result PruneResult
+	defer func() {
+		p.mu.Lock()
+		p.removed = p.removed.add(result)
+		p.mu.Unlock()
+	}()
+
+	now := time.Now()
+
+	var err error
+	result.IdempotencyRecords, err = pruneInBatches(ctx, p.config.BatchSize, func(ctx context.Context, limit int) (int64, error) {
+		return p.deleteBatch(ctx, `
+			DELETE FROM idempotency_records
+			WHERE id IN (
+				SELECT id FROM idempotency_records
+				WHERE expires_at < $1
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+		`, now.Add(-p.config.IdempotencyRetention), limit)
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to prune idempotency records: %w", err)
+	}
+
+	result.InboxEvents, err = pruneInBatches(ctx, p.config.BatchSize, func(ctx context.Context, limit int) (int64, error) {
+		return p.deleteBatch(ctx, `
+			DELETE FROM inbox_events
+			WHERE event_id IN (
+				SELECT event_id FROM inbox_events
+				WHERE processed_at < $1
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+		`, now.Add(-p.config.InboxRetention), limit)
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to prune inbox events: %w", err)
+	}
+
+	return result, nil
+}
+
+func (p *Pruner) deleteBatch(ctx context.Context, query string, cutoff time.Time, limit int) (int64, error) {
+	res, err := p.db.ExecContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// pruneInBatches calls deleteBatch until a batch comes back short, returning
+// the total removed.
+func pruneInBatches(ctx context.Context, batchSize int, deleteBatch func(ctx context.Context, limit int) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := deleteBatch(ctx, batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPruneInBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int64
+		batchSize int
+		wantCalls int
+	}{
+		{"empty", 0, 10, 1},
+		{"partial batch", 7, 10, 1},
+		{"exact multiple", 20, 10, 3},
+		{"several batches", 25, 10, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining := tt.rows
+			calls := 0
+			total, err := pruneInBatches(context.Background(), tt.batchSize, func(ctx context.Context, limit int) (int64, error) {
+				calls++
+				n := min(remaining, int64(limit))
+				remaining -= n
+				return n, nil
+			})
+			if err != nil {
+				t.Fatalf("pruneInBatches() error = %v", err)
+			}
+			if total != tt.rows || calls != tt.wantCalls {
+				t.Errorf("pruneInBatches() = %d in %d calls, want %d in %d", total, calls, tt.rows, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPruneInBatches_StopsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	calls := 0
+	total, err := pruneInBatches(context.Background(), 5, func(ctx context.Context, limit int) (int64, error) {
+		calls++
+		if calls == 3 {
+			return 0, boom
+		}
+		return int64(limit), nil
+	})
+	if !errors.Is(err, boom) || total != 10 {
+		t.Errorf("Expected boom after 10 rows, got %d, %v", total, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	total, err = pruneInBatches(ctx, 5, func(ctx context.Context, limit int) (int64, error) {
+		cancel()
+		return int64(limit), nil
+	})
+	if !errors.Is(err, context.Canceled) || total != 5 {
+		t.Errorf("Expected cancellation after one batch, got %d, %v", total, err)
+	}
+}
+
+type recordingPruneMetrics struct {
+	pruned int64
+	failed int
+}
+
+func (m *recordingPruneMetrics) Pruned(result PruneResult) { m.pruned += result.Total() }
+func (m *recordingPruneMetrics) Failed(error)              { m.failed++ }
+
+func TestPrunerRun_StopsAfterConsecutiveFailures(t *testing.T) {
+	boom := errors.New("permission denied for table idempotency_records")
+	metrics := &recordingPruneMetrics{}
+	pruner := NewPruner(nil, PruneConfig{Interval: time.Millisecond, MaxFailures: 3}, zap.NewNop(), WithPruneMetrics(metrics))
+
+	passes := 0
+	pruner.prune = func(ctx context.Context) (PruneResult, error) {
+		passes++
+		// A successful pass resets the count, so it takes passes 3 to 5.
+		if passes == 2 {
+			return PruneResult{InboxEvents: 4}, nil
+		}
+		return PruneResult{IdempotencyRecords: 1}, boom
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := pruner.Run(ctx)
+	if !errors.Is(err, ErrPruningFailed) || !errors.Is(err, boom) {
+		t.Fatalf("Expected ErrPruningFailed wrapping the cause, got %v", err)
+	}
+	if passes != 5 || metrics.failed != 4 || metrics.pruned != 8 {
+		t.Errorf("Expected 5 passes, 4 failures and 8 rows, got %d, %d and %d", passes, metrics.failed, metrics.pruned)
+	}
+}
+
+func TestPrunerRun_StopsOnCancel(t *testing.T) {
+	pruner := NewPruner(nil, PruneConfig{Interval: time.Millisecond}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	pruner.prune = func(ctx context.Context) (PruneResult, error) {
+		cancel()
+		return PruneResult{}, ctx.Err()
+	}
+	if err := pruner.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,4 @@
+-- Drop keyset pagination indexes
+DROP INDEX IF EXISTS idx_transactions_created_id;
+DROP INDEX IF EXISTS idx_ledger_entries_created_id;
+DROP INDEX IF EXISTS idx_ledger_entries_account_created_id;
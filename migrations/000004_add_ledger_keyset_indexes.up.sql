@@ -0,0 +1,4 @@
+-- Keyset pagination indexes ordered by (created_at, id)
+CREATE INDEX idx_ledger_entries_account_created_id ON ledger_entries(account_id, created_at, id);
+CREATE INDEX idx_ledger_entries_created_id ON ledger_entries(created_at, id);
+CREATE INDEX idx_transactions_created_id ON transactions(created_at, id);
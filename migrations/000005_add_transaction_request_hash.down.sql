@@ -0,0 +1,2 @@
+-- Drop request fingerprint
+ALTER TABLE transactions DROP COLUMN IF EXISTS request_hash;
@@ -0,0 +1,2 @@
+-- Fingerprint of the posting request, used to detect conflicting replays
+ALTER TABLE transactions ADD COLUMN request_hash VARCHAR(64);
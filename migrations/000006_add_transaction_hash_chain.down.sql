@@ -0,0 +1,6 @@
+-- Drop hash chain
+DROP TABLE IF EXISTS ledger_chain_head;
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash,
+    DROP COLUMN IF EXISTS sequence;
@@ -0,0 +1,16 @@
+-- Hash chain over transactions: each transaction stores a hash of its
+-- contents and entries plus the hash of its predecessor. Transactions posted
+-- before this migration are left unchained and reported by VerifyChain.
+ALTER TABLE transactions
+    ADD COLUMN sequence BIGINT UNIQUE,
+    ADD COLUMN prev_hash VARCHAR(64),
+    ADD COLUMN hash VARCHAR(64);
+
+-- Single-row head of the chain, locked by every posting
+CREATE TABLE ledger_chain_head (
+    id INT PRIMARY KEY CHECK (id = 1),
+    sequence BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+INSERT INTO ledger_chain_head (id, sequence, hash) VALUES (1, 0, '');
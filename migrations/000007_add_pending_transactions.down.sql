@@ -0,0 +1,5 @@
+-- Drop two-phase posting columns
+DROP INDEX IF EXISTS idx_transactions_pending;
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS pending_transaction_id,
+    DROP COLUMN IF EXISTS status;
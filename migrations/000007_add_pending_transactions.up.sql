@@ -0,0 +1,7 @@
+-- Two-phase postings: PENDING transactions hold funds until a POSTED commit
+-- or a VOIDED record references them through pending_transaction_id
+ALTER TABLE transactions
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'POSTED' CHECK (status IN ('POSTED', 'PENDING', 'VOIDED')),
+    ADD COLUMN pending_transaction_id VARCHAR(255) UNIQUE REFERENCES transactions(id);
+
+CREATE INDEX idx_transactions_pending ON transactions(id) WHERE status = 'PENDING';
@@ -0,0 +1,5 @@
+-- Drop chart of accounts hierarchy
+DROP INDEX IF EXISTS idx_accounts_parent_id;
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS code;
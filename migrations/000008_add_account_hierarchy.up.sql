@@ -0,0 +1,6 @@
+-- Chart of accounts: optional unique account codes and parent/child nesting
+ALTER TABLE accounts
+    ADD COLUMN code VARCHAR(50) UNIQUE,
+    ADD COLUMN parent_id VARCHAR(255) REFERENCES accounts(id);
+
+CREATE INDEX idx_accounts_parent_id ON accounts(parent_id);
@@ -0,0 +1,3 @@
+-- Drop accounting periods
+ALTER TABLE transactions DROP COLUMN IF EXISTS period_id;
+DROP TABLE IF EXISTS accounting_periods;
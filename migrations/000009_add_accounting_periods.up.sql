@@ -0,0 +1,20 @@
+-- Accounting periods: postings are locked out of a period once it leaves OPEN;
+-- late adjustments go through an ADJUSTMENT period attached to the regular one
+CREATE TABLE accounting_periods (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('REGULAR', 'ADJUSTMENT')),
+    parent_period_id VARCHAR(255) REFERENCES accounting_periods(id),
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'CLOSING', 'CLOSED')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at),
+    CHECK ((kind = 'ADJUSTMENT') = (parent_period_id IS NOT NULL))
+);
+
+CREATE INDEX idx_accounting_periods_range ON accounting_periods(starts_at, ends_at) WHERE kind = 'REGULAR';
+CREATE INDEX idx_accounting_periods_parent ON accounting_periods(parent_period_id);
+
+ALTER TABLE transactions ADD COLUMN period_id VARCHAR(255) REFERENCES accounting_periods(id);
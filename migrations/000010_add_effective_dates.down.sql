@@ -0,0 +1,6 @@
+-- Drop effective dates
+DROP INDEX IF EXISTS idx_ledger_entries_effective_at;
+DROP INDEX IF EXISTS idx_ledger_entries_account_effective;
+DROP INDEX IF EXISTS idx_transactions_effective_at;
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS effective_at;
+ALTER TABLE transactions DROP COLUMN IF EXISTS effective_at;
@@ -0,0 +1,13 @@
+-- Effective dates: when a posting economically took effect, separate from
+-- when it was booked. Existing rows took effect when booked.
+ALTER TABLE transactions ADD COLUMN effective_at TIMESTAMP;
+UPDATE transactions SET effective_at = created_at;
+ALTER TABLE transactions ALTER COLUMN effective_at SET NOT NULL;
+
+ALTER TABLE ledger_entries ADD COLUMN effective_at TIMESTAMP;
+UPDATE ledger_entries SET effective_at = created_at;
+ALTER TABLE ledger_entries ALTER COLUMN effective_at SET NOT NULL;
+
+CREATE INDEX idx_transactions_effective_at ON transactions(effective_at);
+CREATE INDEX idx_ledger_entries_account_effective ON ledger_entries(account_id, effective_at);
+CREATE INDEX idx_ledger_entries_effective_at ON ledger_entries(effective_at);
@@ -0,0 +1,5 @@
+-- Drop transaction annotations
+DROP TABLE IF EXISTS transaction_references;
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS memo;
+DROP INDEX IF EXISTS idx_transactions_metadata;
+ALTER TABLE transactions DROP COLUMN IF EXISTS metadata;
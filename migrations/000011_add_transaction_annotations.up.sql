@@ -0,0 +1,15 @@
+-- Structured annotations: transaction metadata, typed external references
+-- and per-entry memos, so ledger lines can be traced to their source
+ALTER TABLE transactions ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX idx_transactions_metadata ON transactions USING GIN (metadata jsonb_path_ops);
+
+ALTER TABLE ledger_entries ADD COLUMN memo TEXT;
+
+CREATE TABLE transaction_references (
+    transaction_id VARCHAR(255) NOT NULL REFERENCES transactions(id),
+    ref_type VARCHAR(50) NOT NULL CHECK (ref_type IN ('payment_intent_id', 'refund_id', 'payout_id')),
+    ref_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (transaction_id, ref_type, ref_id)
+);
+
+CREATE INDEX idx_transaction_references_ref ON transaction_references(ref_type, ref_id);
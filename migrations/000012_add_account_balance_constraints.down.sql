@@ -0,0 +1,4 @@
+-- Drop account balance constraints
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS credit_limit,
+    DROP COLUMN IF EXISTS balance_constraint;
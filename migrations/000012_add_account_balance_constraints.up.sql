@@ -0,0 +1,5 @@
+-- Per-account balance constraints checked inside PostTransaction
+ALTER TABLE accounts
+    ADD COLUMN balance_constraint VARCHAR(20) NOT NULL DEFAULT 'NONE'
+        CHECK (balance_constraint IN ('NONE', 'NO_OVERDRAFT', 'CREDIT_LIMIT')),
+    ADD COLUMN credit_limit BIGINT NOT NULL DEFAULT 0 CHECK (credit_limit >= 0);
@@ -0,0 +1,17 @@
+-- Account lifecycle: ACTIVE, FROZEN (credits only) and CLOSED, with an audit
+-- trail of every status change
+ALTER TABLE accounts
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE'
+        CHECK (status IN ('ACTIVE', 'FROZEN', 'CLOSED'));
+
+CREATE TABLE account_status_changes (
+    id BIGSERIAL PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL REFERENCES accounts(id),
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_account_status_changes_account_id ON account_status_changes(account_id);
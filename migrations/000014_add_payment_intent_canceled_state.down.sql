@@ -0,0 +1,4 @@
+-- Remove the CANCELED payment intent state
+ALTER TABLE payment_intents DROP CONSTRAINT payment_intents_state_check;
+ALTER TABLE payment_intents ADD CONSTRAINT payment_intents_state_check
+    CHECK (state IN ('CREATED', 'AUTHORIZED', 'CAPTURED', 'FAILED', 'REFUNDED'));
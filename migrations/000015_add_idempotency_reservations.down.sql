@@ -0,0 +1,5 @@
+-- Drop idempotency reservations
+DELETE FROM idempotency_records WHERE state = 'IN_PROGRESS';
+ALTER TABLE idempotency_records
+    DROP COLUMN IF EXISTS locked_at,
+    DROP COLUMN IF EXISTS state;
@@ -0,0 +1,7 @@
+-- Idempotency keys are reserved (IN_PROGRESS) before the request executes and
+-- completed with the stored response afterwards. locked_at lets a retry take
+-- over a reservation abandoned by a crashed request.
+ALTER TABLE idempotency_records
+    ADD COLUMN state VARCHAR(20) NOT NULL DEFAULT 'COMPLETED'
+        CHECK (state IN ('IN_PROGRESS', 'COMPLETED')),
+    ADD COLUMN locked_at TIMESTAMP NOT NULL DEFAULT NOW();
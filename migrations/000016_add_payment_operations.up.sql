@@ -0,0 +1,16 @@
+-- Authorize, capture, refund and cancel calls made with an idempotency key are
+-- recorded with the resulting intent so that a retry returns the original
+-- result instead of running the operation again. Keys are scoped per merchant
+-- and operation.
+CREATE TABLE payment_operations (
+    merchant_id VARCHAR(255) NOT NULL,
+    operation VARCHAR(20) NOT NULL CHECK (operation IN ('authorize', 'capture', 'refund', 'cancel')),
+    idempotency_key VARCHAR(255) NOT NULL,
+    intent_id VARCHAR(255) NOT NULL REFERENCES payment_intents(id),
+    amount BIGINT NOT NULL,
+    result JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (merchant_id, operation, idempotency_key)
+);
+
+CREATE INDEX idx_payment_operations_intent_id ON payment_operations(intent_id);
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_payment_intents_idempotency_key ON payment_intents(idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+ALTER TABLE payment_intents
+    DROP CONSTRAINT IF EXISTS uq_payment_intents_merchant_idempotency_key;
@@ -0,0 +1,35 @@
+-- Make (merchant_id, idempotency_key) unique so concurrent creates with the
+-- same key cannot both insert an intent. Keys duplicated before this
+-- constraint existed are client data, so the migration refuses to run until
+-- an operator has resolved them; the error lists the affected keys. Find them
+-- with:
+--   SELECT merchant_id, idempotency_key, array_agg(id ORDER BY created_at)
+--   FROM payment_intents WHERE idempotency_key IS NOT NULL
+--   GROUP BY 1, 2 HAVING COUNT(*) > 1;
+DO $$
+DECLARE
+    duplicates BIGINT;
+    sample TEXT;
+BEGIN
+    SELECT COUNT(*), string_agg(merchant_id || '/' || idempotency_key, ', ')
+    INTO duplicates, sample
+    FROM (
+        SELECT merchant_id, idempotency_key
+        FROM payment_intents
+        WHERE idempotency_key IS NOT NULL
+        GROUP BY merchant_id, idempotency_key
+        HAVING COUNT(*) > 1
+        ORDER BY merchant_id, idempotency_key
+        LIMIT 20
+    ) d;
+    IF duplicates > 0 THEN
+        RAISE EXCEPTION 'payment_intents has duplicate (merchant_id, idempotency_key) pairs, first %: %', duplicates, sample
+            USING HINT = 'Resolve the duplicate intents before adding uq_payment_intents_merchant_idempotency_key.';
+    END IF;
+END $$;
+
+ALTER TABLE payment_intents
+    ADD CONSTRAINT uq_payment_intents_merchant_idempotency_key UNIQUE (merchant_id, idempotency_key);
+
+-- Lookups always include the merchant, which the constraint's index covers.
+DROP INDEX IF EXISTS idx_payment_intents_idempotency_key;
@@ -0,0 +1,2 @@
+-- The pg_trgm extension is left installed; other objects may depend on it.
+DROP INDEX IF EXISTS idx_ledger_entries_memo_trgm;
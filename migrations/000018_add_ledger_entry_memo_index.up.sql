@@ -0,0 +1,8 @@
+-- Memo search matches substrings case-insensitively (ILIKE '%...%'), which a
+-- B-tree cannot serve; a trigram index keeps it off a sequential scan of
+-- ledger_entries.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_memo_trgm
+    ON ledger_entries USING GIN (memo gin_trgm_ops)
+    WHERE memo IS NOT NULL;
@@ -0,0 +1,4 @@
+-- The account is kept once payouts have been posted against it.
+DELETE FROM accounts a
+WHERE a.id = 'acc_settlement_bank'
+  AND NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.account_id = a.id);
@@ -0,0 +1,5 @@
+-- Bank account that merchant payouts are paid from. The payout posting
+-- template credits it and debits the merchant payable.
+INSERT INTO accounts (id, name, type, currency) VALUES
+    ('acc_settlement_bank', 'Settlement Bank', 'ASSET', 'USD')
+ON CONFLICT (id) DO NOTHING;
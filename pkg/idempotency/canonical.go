@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CanonicalJSON re-encodes a JSON document so that equivalent documents are
+// byte-for-byte equal: object keys are sorted, insignificant whitespace is
+// dropped and numbers are written in their shortest form (1.0 and 1e0 both
+// become 1). Fields named by ignore are removed first; a name is a dotted
+// path from the root, such as "client_timestamp" or "metadata.sent_at".
+func CanonicalJSON(data []byte, ignore ...string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON: trailing data")
+	}
+
+	for _, path := range ignore {
+		removePath(v, strings.Split(path, "."))
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func removePath(v any, path []string) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+	removePath(obj[path[0]], path[1:])
+}
+
+func writeCanonical(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		buf.WriteByte('{')
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeString(buf, k)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case json.Number:
+		buf.WriteString(canonicalNumber(v))
+	case string:
+		writeString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case nil:
+		buf.WriteString("null")
+	default:
+		return fmt.Errorf("unexpected JSON value %T", v)
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+}
+
+// canonicalNumber leaves integer literals as they are (JSON has no leading
+// zeros, so they are already canonical, and this keeps them exact beyond
+// float64 precision). Other numbers are written in the shortest form that
+// round-trips through float64, as an integer when they are whole.
+func canonicalNumber(n json.Number) string {
+	if !strings.ContainsAny(n.String(), ".eE") {
+		return n.String()
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return n.String()
+	}
+	if f >= -1<<53 && f <= 1<<53 && f == math.Trunc(f) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// HashHTTPRequest hashes the parts of a request that decide its outcome: the
+// method, the path, the query parameters in sorted order and the canonical
+// form of the body. Bodies that are not JSON are hashed as sent.
+func HashHTTPRequest(method string, u *url.URL, body []byte, ignore ...string) string {
+	h := sha256.New()
+	io.WriteString(h, strings.ToUpper(method))
+	h.Write([]byte{0})
+	io.WriteString(h, u.EscapedPath())
+	h.Write([]byte{0})
+	io.WriteString(h, u.Query().Encode())
+	h.Write([]byte{0})
+
+	if len(bytes.TrimSpace(body)) > 0 {
+		if canonical, err := CanonicalJSON(body, ignore...); err == nil {
+			body = canonical
+		}
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		ignore []string
+		want   string
+	}{
+		{"sorted keys", `{"b": 1, "a": {"d": true, "c": null}}`, nil, `{"a":{"c":null,"d":true},"b":1}`},
+		{"whitespace", "{\n  \"amount\" : 100 ,\n  \"currency\":\"USD\"\n}", nil, `{"amount":100,"currency":"USD"}`},
+		{"number forms", `[1.0, 1e2, -0.50, 12345678901234567890, 0.1]`, nil, `[1,100,-0.5,12345678901234567890,0.1]`},
+		{"no HTML escaping", `{"memo": "a<b&c"}`, nil, `{"memo":"a<b&c"}`},
+		{"ignored fields", `{"amount": 1, "sent_at": "now", "metadata": {"ts": 5, "order": "A"}}`, []string{"sent_at", "metadata.ts", "missing.path"}, `{"amount":1,"metadata":{"order":"A"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON([]byte(tt.input), tt.ignore...)
+			if err != nil {
+				t.Fatalf("CanonicalJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, invalid := range []string{`{"a":`, `{} {}`, `nope`} {
+		if _, err := CanonicalJSON([]byte(invalid)); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestHashHTTPRequest(t *testing.T) {
+	path := &url.URL{Path: "/v1/payment_intents"}
+	base := HashHTTPRequest("POST", path, []byte(`{"amount":100,"currency":"USD"}`))
+
+	equivalent := HashHTTPRequest("post", path, []byte(`{ "currency": "USD", "amount": 100.0 }`))
+	if equivalent != base {
+		t.Error("Expected reordered body to hash equal")
+	}
+
+	ignored := HashHTTPRequest("POST", path, []byte(`{"amount":100,"currency":"USD","client_ts":"2026-01-01"}`), "client_ts")
+	if ignored != base {
+		t.Error("Expected ignored field to be left out of the hash")
+	}
+
+	for name, other := range map[string]string{
+		"method": HashHTTPRequest("PUT", path, []byte(`{"amount":100,"currency":"USD"}`)),
+		"path":   HashHTTPRequest("POST", &url.URL{Path: "/v1/refunds"}, []byte(`{"amount":100,"currency":"USD"}`)),
+		"query":  HashHTTPRequest("POST", &url.URL{Path: path.Path, RawQuery: "expand=true"}, []byte(`{"amount":100,"currency":"USD"}`)),
+		"body":   HashHTTPRequest("POST", path, []byte(`{"amount":101,"currency":"USD"}`)),
+	} {
+		if other == base {
+			t.Errorf("Expected a different %s to change the hash", name)
+		}
+	}
+
+	query1 := HashHTTPRequest("POST", &url.URL{Path: path.Path, RawQuery: "a=1&b=2"}, nil)
+	query2 := HashHTTPRequest("POST", &url.URL{Path: path.Path, RawQuery: "b=2&a=1"}, nil)
+	if query1 != query2 {
+		t.Error("Expected query parameter order not to matter")
+	}
+
+	if HashHTTPRequest("POST", path, []byte("not json")) == HashHTTPRequest("POST", path, []byte("not  json")) {
+		t.Error("Expected non-JSON bodies to be hashed as sent")
+	}
+}
+
+func TestHashRequest(t *testing.T) {
+	type request struct {
+		Currency string `json:"currency"`
+		Amount   int64  `json:"amount"`
+	}
+
+	fromStruct, err := HashRequest(request{Currency: "USD", Amount: 100})
+	if err != nil {
+		t.Fatalf("HashRequest() error = %v", err)
+	}
+	fromMap, err := HashRequest(map[string]any{"amount": 100, "currency": "USD"})
+	if err != nil {
+		t.Fatalf("HashRequest() error = %v", err)
+	}
+	if fromStruct != fromMap {
+		t.Error("Expected struct and map with the same fields to hash equal")
+	}
+}
@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const (
+	KeyHeader        = "Idempotency-Key"
+	MerchantIDHeader = "X-Merchant-ID"
+	ReplayedHeader   = "Idempotent-Replayed"
+	// RetryableHeader marks a response as transient. Handlers set it to
+	// "true" on errors that a retry with the same key may succeed past, such
+	// as a lost optimistic-locking race, so the response is not stored.
+	RetryableHeader = "X-Should-Retry"
+)
+
+// Handler wraps next so that mutating requests carrying an Idempotency-Key
+// header execute at most once per merchant and key. Keys are scoped by the
+// X-Merchant-ID header, so keyed requests without it are rejected with 400
+// rather than sharing one scope across merchants. The key is reserved
+// before next runs, so a concurrent request with the same key gets 409 (after
+// the optional in-progress wait). Retries with the same request are answered
+// with the stored status and body; reusing a key for a different request is
+// rejected with 422. Server errors and responses marked with RetryableHeader
+// release the key, so the request can be retried under the same key.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(KeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		merchantID := r.Header.Get(MerchantIDHeader)
+		if merchantID == "" {
+			writeError(w, http.StatusBadRequest, "invalid_request", MerchantIDHeader+" header is required with "+KeyHeader)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		requestHash := HashHTTPRequest(r.Method, r.URL, body, m.ignore...)
+
+		record, err := m.Reserve(ctx, merchantID, key, requestHash)
+		switch {
+		case errors.Is(err, ErrAlreadyProcessed):
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(ReplayedHeader, "true")
+			w.WriteHeader(record.StatusCode)
+			io.WriteString(w, record.ResponseBody)
+			return
+		case errors.Is(err, ErrRequestMismatch):
+			writeError(w, http.StatusUnprocessableEntity, "idempotency_key_reused", err.Error())
+			return
+		case errors.Is(err, ErrRequestInProgress):
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusConflict, "request_in_progress", err.Error())
+			return
+		case err != nil:
+			zap.L().Error("Idempotency check failed", zap.String("idempotency_key", key), zap.Error(err))
+			writeError(w, http.StatusInternalServerError, "internal_error", "internal server error")
+			return
+		}
+
+		// The reservation is settled even if the request context has been
+		// canceled or timed out.
+		settleCtx := context.WithoutCancel(ctx)
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			if p := recover(); p != nil {
+				m.release(settleCtx, record)
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError || rec.Header().Get(RetryableHeader) == "true" {
+			m.release(settleCtx, record)
+			return
+		}
+		// The client already has its response; a failed save only means a
+		// retry will execute the request again once the lock times out.
+		if err := m.SaveResponse(settleCtx, record, rec.body.String(), rec.status); err != nil {
+			zap.L().Error("Failed to save idempotent response", zap.String("idempotency_key", key), zap.Error(err))
+		}
+	})
+}
+
+func (m *Middleware) release(ctx context.Context, record *Record) {
+	if err := m.Release(ctx, record); err != nil {
+		zap.L().Error("Failed to release idempotency key", zap.String("idempotency_key", record.IdempotencyKey), zap.Error(err))
+	}
+}
+
+// responseRecorder passes the response through to the client while keeping a
+// copy of the status and body for storage.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
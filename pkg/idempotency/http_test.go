@@ -0,0 +1,211 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingHandler answers with a body that changes on every call, so a
+// replayed response is distinguishable from a re-executed one.
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(h.status)
+	w.Write([]byte(`{"call":` + strconv.Itoa(h.calls) + `}`))
+}
+
+func send(handler http.Handler, method, merchantID, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/v1/payment_intents", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(KeyHeader, key)
+	}
+	req.Header.Set(MerchantIDHeader, merchantID)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddlewareHandler_Replay(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	handler := NewMiddleware(NewMemoryStore(), time.Hour).Handler(next)
+
+	first := send(handler, "POST", "merchant_1", "key_1", `{"amount":100}`)
+	if first.Code != http.StatusCreated || first.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("Unexpected first response %d %v", first.Code, first.Header())
+	}
+
+	retry := send(handler, "POST", "merchant_1", "key_1", `{"amount":100}`)
+	if next.calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", next.calls)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected replay of %d %q, got %d %q", first.Code, first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get(ReplayedHeader) != "true" {
+		t.Error("Expected replayed header on retry")
+	}
+
+	mismatch := send(handler, "POST", "merchant_1", "key_1", `{"amount":200}`)
+	if mismatch.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for key reuse, got %d", mismatch.Code)
+	}
+
+	otherMerchant := send(handler, "POST", "merchant_2", "key_1", `{"amount":100}`)
+	if otherMerchant.Code != http.StatusCreated || next.calls != 2 {
+		t.Errorf("Expected key to be scoped per merchant, got %d after %d calls", otherMerchant.Code, next.calls)
+	}
+}
+
+func TestMiddlewareHandler_Passthrough(t *testing.T) {
+	next := &countingHandler{status: http.StatusOK}
+	handler := NewMiddleware(NewMemoryStore(), time.Hour).Handler(next)
+
+	send(handler, "POST", "merchant_1", "", `{}`)
+	send(handler, "POST", "merchant_1", "", `{}`)
+	send(handler, "GET", "merchant_1", "key_get", "")
+	send(handler, "GET", "merchant_1", "key_get", "")
+	if next.calls != 4 {
+		t.Errorf("Expected requests without a key and GETs to always execute, got %d calls", next.calls)
+	}
+}
+
+func TestMiddlewareHandler_MerchantRequired(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	handler := NewMiddleware(NewMemoryStore(), time.Hour).Handler(next)
+
+	rec := send(handler, "POST", "", "key_1", `{"merchant_id":"merchant_1"}`)
+	if rec.Code != http.StatusBadRequest || next.calls != 0 {
+		t.Errorf("Expected 400 for a keyed request without a merchant, got %d after %d calls", rec.Code, next.calls)
+	}
+	if rec := send(handler, "POST", "", "", `{}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected requests without a key to pass through, got %d", rec.Code)
+	}
+}
+
+func TestMiddlewareHandler_ServerErrorsNotStored(t *testing.T) {
+	next := &countingHandler{status: http.StatusServiceUnavailable}
+	handler := NewMiddleware(NewMemoryStore(), time.Hour).Handler(next)
+
+	send(handler, "POST", "merchant_1", "key_1", `{}`)
+	next.status = http.StatusCreated
+	retry := send(handler, "POST", "merchant_1", "key_1", `{}`)
+	if retry.Code != http.StatusCreated || next.calls != 2 {
+		t.Errorf("Expected retry after a 5xx to execute, got %d after %d calls", retry.Code, next.calls)
+	}
+}
+
+func TestMiddlewareHandler_RetryableNotStored(t *testing.T) {
+	next := &countingHandler{status: http.StatusConflict}
+	handler := NewMiddleware(NewMemoryStore(), time.Hour).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if next.status == http.StatusConflict {
+			w.Header().Set(RetryableHeader, "true")
+		}
+		next.ServeHTTP(w, r)
+	}))
+
+	first := send(handler, "POST", "merchant_1", "key_1", `{}`)
+	if first.Code != http.StatusConflict || first.Header().Get(RetryableHeader) != "true" {
+		t.Fatalf("Unexpected first response %d %v", first.Code, first.Header())
+	}
+	next.status = http.StatusOK
+	retry := send(handler, "POST", "merchant_1", "key_1", `{}`)
+	if retry.Code != http.StatusOK || next.calls != 2 || retry.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("Expected retry after a retryable 409 to execute, got %d after %d calls", retry.Code, next.calls)
+	}
+
+	replay := send(handler, "POST", "merchant_1", "key_1", `{}`)
+	if replay.Code != http.StatusOK || next.calls != 2 {
+		t.Errorf("Expected the successful retry to be stored, got %d after %d calls", replay.Code, next.calls)
+	}
+}
+
+func TestMiddlewareHandler_ConcurrentRequest(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-finish
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"pi_1"}`))
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		started, finish = make(chan struct{}), make(chan struct{})
+		handler := NewMiddleware(NewMemoryStore(), time.Hour).Handler(next)
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- send(handler, "POST", "merchant_1", "key_1", `{}`) }()
+		<-started
+
+		concurrent := send(handler, "POST", "merchant_1", "key_1", `{}`)
+		if concurrent.Code != http.StatusConflict || concurrent.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected 409 with Retry-After while in progress, got %d", concurrent.Code)
+		}
+		close(finish)
+		if first := <-done; first.Code != http.StatusCreated {
+			t.Errorf("Expected first request to complete, got %d", first.Code)
+		}
+	})
+
+	t.Run("wait and replay", func(t *testing.T) {
+		started, finish = make(chan struct{}), make(chan struct{})
+		handler := NewMiddleware(NewMemoryStore(), time.Hour, WithInProgressWait(5*time.Second)).Handler(next)
+
+		go send(handler, "POST", "merchant_1", "key_1", `{}`)
+		<-started
+		time.AfterFunc(100*time.Millisecond, func() { close(finish) })
+
+		waited := send(handler, "POST", "merchant_1", "key_1", `{}`)
+		if waited.Code != http.StatusCreated || waited.Header().Get(ReplayedHeader) != "true" {
+			t.Errorf("Expected waiting request to replay the first response, got %d", waited.Code)
+		}
+	})
+}
+
+func TestMiddlewareHandler_StaleReservation(t *testing.T) {
+	store := NewMemoryStore()
+	requestHash := HashHTTPRequest("POST", &url.URL{Path: "/v1/payment_intents"}, []byte(`{}`))
+	abandoned := &Record{
+		ID:             "idem_crashed",
+		MerchantID:     "merchant_1",
+		IdempotencyKey: "key_1",
+		RequestHash:    requestHash,
+		LockedAt:       time.Now().Add(-2 * time.Minute),
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	if holder, err := store.Reserve(context.Background(), abandoned, time.Minute); holder != nil || err != nil {
+		t.Fatalf("Failed to reserve: %v %v", holder, err)
+	}
+
+	next := &countingHandler{status: http.StatusCreated}
+	handler := NewMiddleware(store, time.Hour, WithLockTimeout(time.Minute)).Handler(next)
+	rec := send(handler, "POST", "merchant_1", "key_1", `{}`)
+	if rec.Code != http.StatusCreated || next.calls != 1 {
+		t.Errorf("Expected stale reservation to be taken over, got %d after %d calls", rec.Code, next.calls)
+	}
+
+	if err := store.Complete(context.Background(), abandoned); err != ErrReservationLost {
+		t.Errorf("Expected ErrReservationLost for the abandoned reservation, got %v", err)
+	}
+
+	changed := *abandoned
+	changed.ID, changed.IdempotencyKey = "idem_crashed_2", "key_2"
+	if holder, err := store.Reserve(context.Background(), &changed, time.Minute); holder != nil || err != nil {
+		t.Fatalf("Failed to reserve: %v %v", holder, err)
+	}
+	rec = send(handler, "POST", "merchant_1", "key_2", `{"amount":200}`)
+	if rec.Code != http.StatusCreated || next.calls != 2 {
+		t.Errorf("Expected a retry with a changed body to take over, got %d after %d calls", rec.Code, next.calls)
+	}
+}
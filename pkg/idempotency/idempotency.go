@@ -9,10 +9,22 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 var (
-	ErrAlreadyProcessed = errors.New("request already processed")
+	ErrAlreadyProcessed  = errors.New("request already processed")
+	ErrRequestMismatch   = errors.New("idempotency key reused with different request body")
+	ErrRequestInProgress = errors.New("request with this idempotency key is in progress")
+	ErrReservationLost   = errors.New("idempotency reservation no longer held")
+)
+
+type RecordState string
+
+const (
+	StateInProgress RecordState = "IN_PROGRESS"
+	StateCompleted  RecordState = "COMPLETED"
 )
 
 type Record struct {
@@ -20,15 +32,28 @@ type Record struct {
 	MerchantID     string
 	IdempotencyKey string
 	RequestHash    string
+	State          RecordState
 	ResponseBody   string
 	StatusCode     int
 	CreatedAt      time.Time
+	LockedAt       time.Time
 	ExpiresAt      time.Time
 }
 
 type Store interface {
 	Get(ctx context.Context, merchantID, key string) (*Record, error)
-	Store(ctx context.Context, record *Record) error
+	// Reserve inserts record as an IN_PROGRESS reservation and returns nil if
+	// the key was free. Expired records are replaced, as are IN_PROGRESS
+	// reservations whose lock is older than lockTimeout, whatever request
+	// they were for. Otherwise the record currently holding the key is
+	// returned and nothing is written.
+	Reserve(ctx context.Context, record *Record, lockTimeout time.Duration) (*Record, error)
+	// Complete stores the response for a reservation made by Reserve. It
+	// returns ErrReservationLost if the reservation was taken over.
+	Complete(ctx context.Context, record *Record) error
+	// Release deletes a reservation that has not completed so the request
+	// can be retried under the same key.
+	Release(ctx context.Context, record *Record) error
 }
 
 type postgresStore struct {
@@ -39,124 +64,285 @@ func NewPostgresStore(db *sql.DB) Store {
 	return &postgresStore{db: db}
 }
 
-func (s *postgresStore) Get(ctx context.Context, merchantID, key string) (*Record, error) {
-	query := `
-		SELECT id, merchant_id, idempotency_key, request_hash, response_body, 
-		       status_code, created_at, expires_at
-		FROM idempotency_records
-		WHERE merchant_id = $1 AND idempotency_key = $2 AND expires_at > NOW()
-	`
+const recordColumns = `
+	id, merchant_id, idempotency_key, request_hash, state, response_body,
+	status_code, created_at, locked_at, expires_at
+`
+
+func scanRecord(row *sql.Row) (*Record, error) {
 	record := &Record{}
 	var responseBody sql.NullString
+	var statusCode sql.NullInt64
 
-	err := s.db.QueryRowContext(ctx, query, merchantID, key).Scan(
+	err := row.Scan(
 		&record.ID,
 		&record.MerchantID,
 		&record.IdempotencyKey,
 		&record.RequestHash,
+		&record.State,
 		&responseBody,
-		&record.StatusCode,
+		&statusCode,
 		&record.CreatedAt,
+		&record.LockedAt,
 		&record.ExpiresAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+		return nil, err
 	}
 
 	if responseBody.Valid {
 		record.ResponseBody = responseBody.String
 	}
+	if statusCode.Valid {
+		record.StatusCode = int(statusCode.Int64)
+	}
 
 	return record, nil
 }
 
-func (s *postgresStore) Store(ctx context.Context, record *Record) error {
+func (s *postgresStore) Get(ctx context.Context, merchantID, key string) (*Record, error) {
 	query := `
+		SELECT` + recordColumns + `
+		FROM idempotency_records
+		WHERE merchant_id = $1 AND idempotency_key = $2 AND expires_at > NOW()
+	`
+	record, err := scanRecord(s.db.QueryRowContext(ctx, query, merchantID, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	return record, nil
+}
+
+func (s *postgresStore) Reserve(ctx context.Context, record *Record, lockTimeout time.Duration) (*Record, error) {
+	insert := `
 		INSERT INTO idempotency_records (
-			id, merchant_id, idempotency_key, request_hash,
-			response_body, status_code, created_at, expires_at
+			id, merchant_id, idempotency_key, request_hash, state,
+			created_at, locked_at, expires_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		ON CONFLICT (merchant_id, idempotency_key) DO NOTHING
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7)
+		ON CONFLICT (merchant_id, idempotency_key) DO UPDATE SET
+			id = EXCLUDED.id,
+			request_hash = EXCLUDED.request_hash,
+			state = EXCLUDED.state,
+			response_body = NULL,
+			status_code = NULL,
+			created_at = EXCLUDED.created_at,
+			locked_at = EXCLUDED.locked_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_records.expires_at <= EXCLUDED.created_at
+		   OR (idempotency_records.state = $5 AND idempotency_records.locked_at <= $8)
+		RETURNING id
 	`
-	_, err := s.db.ExecContext(ctx, query,
-		record.ID,
-		record.MerchantID,
-		record.IdempotencyKey,
-		record.RequestHash,
+	existing := `
+		SELECT` + recordColumns + `
+		FROM idempotency_records
+		WHERE merchant_id = $1 AND idempotency_key = $2
+	`
+
+	// The holder can be deleted between the conditional insert and the
+	// lookup, in which case the key is free again and the insert is retried.
+	for attempt := 0; attempt < 3; attempt++ {
+		var id string
+		err := s.db.QueryRowContext(ctx, insert,
+			record.ID,
+			record.MerchantID,
+			record.IdempotencyKey,
+			record.RequestHash,
+			StateInProgress,
+			record.LockedAt,
+			record.ExpiresAt,
+			record.LockedAt.Add(-lockTimeout),
+		).Scan(&id)
+		if err == nil {
+			record.State = StateInProgress
+			record.CreatedAt = record.LockedAt
+			return nil, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+
+		holder, err := scanRecord(s.db.QueryRowContext(ctx, existing, record.MerchantID, record.IdempotencyKey))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+		}
+		return holder, nil
+	}
+
+	return nil, ErrRequestInProgress
+}
+
+func (s *postgresStore) Complete(ctx context.Context, record *Record) error {
+	query := `
+		UPDATE idempotency_records
+		SET state = $1, response_body = $2, status_code = $3
+		WHERE merchant_id = $4 AND idempotency_key = $5 AND id = $6 AND state = $7
+	`
+	result, err := s.db.ExecContext(ctx, query,
+		StateCompleted,
 		record.ResponseBody,
 		record.StatusCode,
-		record.CreatedAt,
-		record.ExpiresAt,
+		record.MerchantID,
+		record.IdempotencyKey,
+		record.ID,
+		StateInProgress,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to store idempotency record: %w", err)
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrReservationLost
+	}
+
+	record.State = StateCompleted
 	return nil
 }
 
-func HashRequest(body interface{}) (string, error) {
+func (s *postgresStore) Release(ctx context.Context, record *Record) error {
+	query := `
+		DELETE FROM idempotency_records
+		WHERE merchant_id = $1 AND idempotency_key = $2 AND id = $3 AND state = $4
+	`
+	_, err := s.db.ExecContext(ctx, query, record.MerchantID, record.IdempotencyKey, record.ID, StateInProgress)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// HashRequest hashes the canonical JSON encoding of body, so the result does
+// not depend on field order or on whether body is a struct or a map.
+func HashRequest(body interface{}, ignore ...string) (string, error) {
 	data, err := json.Marshal(body)
 	if err != nil {
 		return "", err
 	}
+	data, err = CanonicalJSON(data, ignore...)
+	if err != nil {
+		return "", err
+	}
 
 	hash := sha256.Sum256(data)
 	return hex.EncodeToString(hash[:]), nil
 }
 
+const (
+	DefaultLockTimeout = time.Minute
+	pollInterval       = 50 * time.Millisecond
+)
+
 type Middleware struct {
-	store Store
-	ttl   time.Duration
+	store       Store
+	ttl         time.Duration
+	lockTimeout time.Duration
+	wait        time.Duration
+	ignore      []string
 }
 
-func NewMiddleware(store Store, ttl time.Duration) *Middleware {
-	return &Middleware{
-		store: store,
-		ttl:   ttl,
-	}
-}
+type Option func(*Middleware)
 
-func (m *Middleware) CheckIdempotency(ctx context.Context, merchantID, key, requestHash string) (*Record, error) {
-	if key == "" {
-		return nil, nil
+// WithLockTimeout sets how long an IN_PROGRESS reservation is honoured before
+// any request with the same key, even with a changed body, may take it over. It should exceed the longest
+// time a request can run, or a slow request may end up executing twice.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Middleware) {
+		m.lockTimeout = timeout
 	}
+}
 
-	record, err := m.store.Get(ctx, merchantID, key)
-	if err != nil {
-		return nil, err
+// WithInProgressWait makes a request that finds its key in progress poll for
+// up to wait for the other request to finish, and replay its response, before
+// giving up with ErrRequestInProgress.
+func WithInProgressWait(wait time.Duration) Option {
+	return func(m *Middleware) {
+		m.wait = wait
 	}
+}
 
-	if record != nil {
-		if record.RequestHash != requestHash {
-			return nil, fmt.Errorf("idempotency key reused with different request body")
-		}
-		return record, ErrAlreadyProcessed
+// WithIgnoredFields leaves the named body fields out of the request hash, so
+// that retries differing only in, say, a client timestamp still match. Names
+// are dotted paths as accepted by CanonicalJSON.
+func WithIgnoredFields(fields ...string) Option {
+	return func(m *Middleware) {
+		m.ignore = append(m.ignore, fields...)
 	}
-
-	return nil, nil
 }
 
-func (m *Middleware) SaveResponse(ctx context.Context, merchantID, key, requestHash, responseBody string, statusCode int) error {
-	if key == "" {
-		return nil
+func NewMiddleware(store Store, ttl time.Duration, opts ...Option) *Middleware {
+	m := &Middleware{
+		store:       store,
+		ttl:         ttl,
+		lockTimeout: DefaultLockTimeout,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
 
+// Reserve claims the key for a request before it executes. It returns the new
+// reservation on success. If the key already holds a response for the same
+// request, that record is returned with ErrAlreadyProcessed.
+func (m *Middleware) Reserve(ctx context.Context, merchantID, key, requestHash string) (*Record, error) {
+	now := time.Now()
 	record := &Record{
-		ID:             fmt.Sprintf("idem_%d", time.Now().UnixNano()),
+		ID:             "idem_" + uuid.New().String(),
 		MerchantID:     merchantID,
 		IdempotencyKey: key,
 		RequestHash:    requestHash,
-		ResponseBody:   responseBody,
-		StatusCode:     statusCode,
-		CreatedAt:      time.Now(),
-		ExpiresAt:      time.Now().Add(m.ttl),
+		LockedAt:       now,
+		ExpiresAt:      now.Add(m.ttl),
 	}
+	deadline := now.Add(m.wait)
+
+	for {
+		holder, err := m.store.Reserve(ctx, record, m.lockTimeout)
+		if err != nil && !errors.Is(err, ErrRequestInProgress) {
+			return nil, err
+		}
+		if err == nil && holder == nil {
+			return record, nil
+		}
+		if holder != nil {
+			if holder.RequestHash != requestHash {
+				return nil, ErrRequestMismatch
+			}
+			if holder.State == StateCompleted {
+				return holder, ErrAlreadyProcessed
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return holder, ErrRequestInProgress
+		}
+		select {
+		case <-ctx.Done():
+			return holder, ErrRequestInProgress
+		case <-time.After(pollInterval):
+		}
+		record.LockedAt = time.Now()
+	}
+}
+
+func (m *Middleware) SaveResponse(ctx context.Context, record *Record, responseBody string, statusCode int) error {
+	record.ResponseBody = responseBody
+	record.StatusCode = statusCode
+	return m.store.Complete(ctx, record)
+}
 
-	return m.store.Store(ctx, record)
+func (m *Middleware) Release(ctx context.Context, record *Record) error {
+	return m.store.Release(ctx, record)
 }
@@ -0,0 +1,221 @@
+// Package idempotencytest holds the behavior every idempotency.Store must
+// share, so that the in-memory, Postgres and Redis stores can be held to one
+// contract.
+package idempotencytest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+)
+
+const lockTimeout = time.Minute
+
+// RunStoreTests runs the contract against stores built by newStore. Each
+// subtest gets its own, empty store.
+func RunStoreTests(t *testing.T, newStore func(t *testing.T) idempotency.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store idempotency.Store)
+	}{
+		{"ReserveAndComplete", testReserveAndComplete},
+		{"Release", testRelease},
+		{"MerchantScoping", testMerchantScoping},
+		{"StaleReservation", testStaleReservation},
+		{"StaleReservationDifferentHash", testStaleReservationDifferentHash},
+		{"ExpiredRecord", testExpiredRecord},
+		{"ConcurrentReserve", testConcurrentReserve},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newRecord(id, merchantID, key, hash string, lockedAt time.Time) *idempotency.Record {
+	return &idempotency.Record{
+		ID:             id,
+		MerchantID:     merchantID,
+		IdempotencyKey: key,
+		RequestHash:    hash,
+		LockedAt:       lockedAt,
+		ExpiresAt:      lockedAt.Add(time.Hour),
+	}
+}
+
+func reserve(t *testing.T, store idempotency.Store, record *idempotency.Record) {
+	t.Helper()
+	holder, err := store.Reserve(context.Background(), record, lockTimeout)
+	if err != nil || holder != nil {
+		t.Fatalf("Failed to reserve %s: holder %+v, err %v", record.IdempotencyKey, holder, err)
+	}
+}
+
+func testReserveAndComplete(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	record := newRecord("idem_1", "merchant_1", "key_1", "hash_1", now)
+	reserve(t, store, record)
+
+	got, err := store.Get(ctx, "merchant_1", "key_1")
+	if err != nil || got == nil || got.State != idempotency.StateInProgress {
+		t.Fatalf("Expected IN_PROGRESS record, got %+v, %v", got, err)
+	}
+
+	holder, err := store.Reserve(ctx, newRecord("idem_2", "merchant_1", "key_1", "hash_1", now), lockTimeout)
+	if err != nil || holder == nil || holder.ID != "idem_1" || holder.State != idempotency.StateInProgress {
+		t.Fatalf("Expected in-progress holder idem_1, got %+v, %v", holder, err)
+	}
+
+	record.ResponseBody = `{"id":"pi_1"}`
+	record.StatusCode = 201
+	if err := store.Complete(ctx, record); err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+
+	holder, err = store.Reserve(ctx, newRecord("idem_3", "merchant_1", "key_1", "hash_1", now.Add(2*lockTimeout)), lockTimeout)
+	if err != nil || holder == nil {
+		t.Fatalf("Expected completed holder, got %+v, %v", holder, err)
+	}
+	if holder.State != idempotency.StateCompleted || holder.StatusCode != 201 || holder.ResponseBody != `{"id":"pi_1"}` {
+		t.Errorf("Unexpected completed record %+v", holder)
+	}
+
+	if err := store.Complete(ctx, newRecord("idem_other", "merchant_1", "key_1", "hash_1", now)); err != idempotency.ErrReservationLost {
+		t.Errorf("Expected ErrReservationLost completing a reservation not held, got %v", err)
+	}
+}
+
+func testRelease(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	record := newRecord("idem_1", "merchant_1", "key_1", "hash_1", now)
+	reserve(t, store, record)
+	if err := store.Release(ctx, record); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if got, err := store.Get(ctx, "merchant_1", "key_1"); err != nil || got != nil {
+		t.Fatalf("Expected released key to be free, got %+v, %v", got, err)
+	}
+
+	retry := newRecord("idem_2", "merchant_1", "key_1", "hash_2", now)
+	reserve(t, store, retry)
+
+	// Releasing a reservation that was since taken over leaves the new one.
+	if err := store.Release(ctx, record); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if got, _ := store.Get(ctx, "merchant_1", "key_1"); got == nil || got.ID != "idem_2" {
+		t.Errorf("Expected idem_2 to keep the key, got %+v", got)
+	}
+}
+
+func testMerchantScoping(t *testing.T, store idempotency.Store) {
+	now := time.Now()
+	reserve(t, store, newRecord("idem_1", "merchant_1", "key_1", "hash_1", now))
+	reserve(t, store, newRecord("idem_2", "merchant_2", "key_1", "hash_1", now))
+}
+
+func testStaleReservation(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	live := newRecord("idem_live", "merchant_1", "key_live", "hash_1", now.Add(-lockTimeout/2))
+	reserve(t, store, live)
+	holder, err := store.Reserve(ctx, newRecord("idem_2", "merchant_1", "key_live", "hash_1", now), lockTimeout)
+	if err != nil || holder == nil || holder.ID != "idem_live" {
+		t.Fatalf("Expected a live reservation not to be taken over, got %+v, %v", holder, err)
+	}
+
+	abandoned := newRecord("idem_1", "merchant_1", "key_1", "hash_1", now.Add(-2*lockTimeout))
+	reserve(t, store, abandoned)
+
+	takeover := newRecord("idem_3", "merchant_1", "key_1", "hash_1", now)
+	reserve(t, store, takeover)
+	if err := store.Complete(ctx, abandoned); err != idempotency.ErrReservationLost {
+		t.Errorf("Expected ErrReservationLost for the abandoned reservation, got %v", err)
+	}
+	if err := store.Complete(ctx, takeover); err != nil {
+		t.Errorf("Failed to complete takeover: %v", err)
+	}
+}
+
+// testStaleReservationDifferentHash covers a client that crashed mid-request
+// and retries with a changed body: once stale, the reservation is taken over
+// regardless of its hash, and later requests are compared with the new one.
+func testStaleReservationDifferentHash(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	abandoned := newRecord("idem_1", "merchant_1", "key_1", "hash_1", now.Add(-2*lockTimeout))
+	reserve(t, store, abandoned)
+
+	takeover := newRecord("idem_2", "merchant_1", "key_1", "hash_2", now)
+	reserve(t, store, takeover)
+	if err := store.Complete(ctx, abandoned); err != idempotency.ErrReservationLost {
+		t.Errorf("Expected ErrReservationLost for the abandoned reservation, got %v", err)
+	}
+
+	holder, err := store.Reserve(ctx, newRecord("idem_3", "merchant_1", "key_1", "hash_1", now), lockTimeout)
+	if err != nil || holder == nil || holder.ID != "idem_2" || holder.RequestHash != "hash_2" {
+		t.Fatalf("Expected the new reservation to hold the key, got %+v, %v", holder, err)
+	}
+}
+
+func testExpiredRecord(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	expired := newRecord("idem_1", "merchant_1", "key_1", "hash_1", now.Add(-2*time.Hour))
+	expired.ExpiresAt = now.Add(-time.Hour)
+	reserve(t, store, expired)
+	expired.StatusCode = 200
+	if err := store.Complete(ctx, expired); err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+	if got, err := store.Get(ctx, "merchant_1", "key_1"); err != nil || got != nil {
+		t.Fatalf("Expected expired record to be hidden, got %+v, %v", got, err)
+	}
+
+	reserve(t, store, newRecord("idem_2", "merchant_1", "key_1", "hash_2", now))
+}
+
+func testConcurrentReserve(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	const workers = 16
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		acquired int
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			holder, err := store.Reserve(ctx, newRecord(fmt.Sprintf("idem_%d", i), "merchant_1", "key_1", "hash_1", now), lockTimeout)
+			if err != nil {
+				t.Errorf("Reserve failed: %v", err)
+				return
+			}
+			if holder == nil {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if acquired != 1 {
+		t.Errorf("Expected exactly one reservation, got %d", acquired)
+	}
+}
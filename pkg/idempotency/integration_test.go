@@ -0,0 +1,21 @@
+//go:build integration
+// +build integration
+
+package idempotency_test
+
+import (
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/internal/testutil"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency/idempotencytest"
+)
+
+func TestPostgresStore_Contract(t *testing.T) {
+	idempotencytest.RunStoreTests(t, func(t *testing.T) idempotency.Store {
+		testDB := testutil.SetupTestDB(t)
+		t.Cleanup(func() { testDB.Close(t) })
+		testDB.ApplyMigrations(t)
+		return idempotency.NewPostgresStore(testDB.DB)
+	})
+}
@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryStore is a Store kept in process memory with the same reservation
+// rules as the Postgres store. It suits tests and single-instance
+// deployments; records are only removed when they are replaced.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{records: make(map[string]*Record)}
+}
+
+func memoryKey(merchantID, key string) string {
+	return merchantID + "\x00" + key
+}
+
+func (s *memoryStore) Get(ctx context.Context, merchantID, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[memoryKey(merchantID, key)]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, record *Record, lockTimeout time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := memoryKey(record.MerchantID, record.IdempotencyKey)
+	if holder, ok := s.records[id]; ok && !canReplace(holder, record, lockTimeout) {
+		copied := *holder
+		return &copied, nil
+	}
+
+	record.State = StateInProgress
+	record.CreatedAt = record.LockedAt
+	record.ResponseBody = ""
+	record.StatusCode = 0
+	stored := *record
+	s.records[id] = &stored
+	return nil, nil
+}
+
+// canReplace reports whether a reservation for record may overwrite holder:
+// either holder has expired, or it is an abandoned reservation. An abandoned
+// reservation is taken over whatever request it was for, so a client that
+// crashed mid-request can retry with a changed body.
+func canReplace(holder, record *Record, lockTimeout time.Duration) bool {
+	if !holder.ExpiresAt.After(record.LockedAt) {
+		return true
+	}
+	return holder.State == StateInProgress &&
+		!holder.LockedAt.After(record.LockedAt.Add(-lockTimeout))
+}
+
+func (s *memoryStore) Complete(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	holder, ok := s.records[memoryKey(record.MerchantID, record.IdempotencyKey)]
+	if !ok || holder.ID != record.ID || holder.State != StateInProgress {
+		return ErrReservationLost
+	}
+	holder.State = StateCompleted
+	holder.ResponseBody = record.ResponseBody
+	holder.StatusCode = record.StatusCode
+	record.State = StateCompleted
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := memoryKey(record.MerchantID, record.IdempotencyKey)
+	if holder, ok := s.records[id]; ok && holder.ID == record.ID && holder.State == StateInProgress {
+		delete(s.records, id)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package idempotency_test
+
+import (
+	"testing"
+
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency/idempotencytest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	idempotencytest.RunStoreTests(t, func(t *testing.T) idempotency.Store {
+		return idempotency.NewMemoryStore()
+	})
+}
@@ -0,0 +1,217 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultRedisPrefix = "idempotency:"
+
+// redisStore keeps records as JSON values that Redis expires at ExpiresAt.
+// Reservations are taken with SET NX; the compare-and-set steps (taking over
+// a stale reservation, completing or releasing one) run as Lua scripts so
+// they are atomic against concurrent requests.
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client, prefix: defaultRedisPrefix}
+}
+
+// redisRecord is the stored form of a Record. Times are Unix milliseconds so
+// the Lua scripts can compare them.
+type redisRecord struct {
+	ID             string      `json:"id"`
+	MerchantID     string      `json:"merchant_id"`
+	IdempotencyKey string      `json:"idempotency_key"`
+	RequestHash    string      `json:"request_hash"`
+	State          RecordState `json:"state"`
+	ResponseBody   string      `json:"response_body,omitempty"`
+	StatusCode     int         `json:"status_code,omitempty"`
+	CreatedAt      int64       `json:"created_at"`
+	LockedAt       int64       `json:"locked_at"`
+	ExpiresAt      int64       `json:"expires_at"`
+}
+
+func toRedisRecord(record *Record) redisRecord {
+	return redisRecord{
+		ID:             record.ID,
+		MerchantID:     record.MerchantID,
+		IdempotencyKey: record.IdempotencyKey,
+		RequestHash:    record.RequestHash,
+		State:          record.State,
+		ResponseBody:   record.ResponseBody,
+		StatusCode:     record.StatusCode,
+		CreatedAt:      record.CreatedAt.UnixMilli(),
+		LockedAt:       record.LockedAt.UnixMilli(),
+		ExpiresAt:      record.ExpiresAt.UnixMilli(),
+	}
+}
+
+func (r redisRecord) record() *Record {
+	return &Record{
+		ID:             r.ID,
+		MerchantID:     r.MerchantID,
+		IdempotencyKey: r.IdempotencyKey,
+		RequestHash:    r.RequestHash,
+		State:          r.State,
+		ResponseBody:   r.ResponseBody,
+		StatusCode:     r.StatusCode,
+		CreatedAt:      time.UnixMilli(r.CreatedAt),
+		LockedAt:       time.UnixMilli(r.LockedAt),
+		ExpiresAt:      time.UnixMilli(r.ExpiresAt),
+	}
+}
+
+func decodeRedisRecord(data string) (*Record, error) {
+	var stored redisRecord
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+	return stored.record(), nil
+}
+
+// key length-prefixes the merchant ID so that merchant and key boundaries
+// cannot be confused when either contains the separator.
+func (s *redisStore) key(merchantID, key string) string {
+	return fmt.Sprintf("%s%d:%s:%s", s.prefix, len(merchantID), merchantID, key)
+}
+
+// redisTTL is the Redis expiry for a record. Records that are already past
+// ExpiresAt still get a short TTL; readers check ExpiresAt themselves.
+func redisTTL(record *Record) time.Duration {
+	ttl := time.Until(record.ExpiresAt)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	return ttl
+}
+
+func (s *redisStore) Get(ctx context.Context, merchantID, key string) (*Record, error) {
+	data, err := s.client.Get(ctx, s.key(merchantID, key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	record, err := decodeRedisRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	if !record.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return record, nil
+}
+
+// takeoverScript replaces the record at KEYS[1] with ARGV[1] if the current
+// record has expired (ARGV[3] is now) or is an IN_PROGRESS reservation locked
+// at or before ARGV[4]. It returns nil when the new record was written and the
+// current record otherwise.
+var takeoverScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local rec = cjson.decode(current)
+	local expired = rec.expires_at <= tonumber(ARGV[3])
+	local stale = rec.state == 'IN_PROGRESS' and rec.locked_at <= tonumber(ARGV[4])
+	if not expired and not stale then
+		return current
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return false
+`)
+
+func (s *redisStore) Reserve(ctx context.Context, record *Record, lockTimeout time.Duration) (*Record, error) {
+	reservation := *record
+	reservation.State = StateInProgress
+	reservation.CreatedAt = record.LockedAt
+	reservation.ResponseBody = ""
+	reservation.StatusCode = 0
+	data, err := json.Marshal(toRedisRecord(&reservation))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	key := s.key(record.MerchantID, record.IdempotencyKey)
+	ttl := redisTTL(record)
+	ok, err := s.client.SetNX(ctx, key, data, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if !ok {
+		current, err := takeoverScript.Run(ctx, s.client, []string{key},
+			data,
+			ttl.Milliseconds(),
+			record.LockedAt.UnixMilli(),
+			record.LockedAt.Add(-lockTimeout).UnixMilli(),
+		).Text()
+		if err == nil {
+			return decodeRedisRecord(current)
+		}
+		if !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+	}
+
+	*record = reservation
+	return nil, nil
+}
+
+// replaceScript overwrites the record at KEYS[1] with ARGV[1], keeping its
+// TTL, or deletes it when ARGV[1] is empty, provided the current record is
+// the IN_PROGRESS reservation with ID ARGV[2]. It returns 1 on success.
+var replaceScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return 0
+end
+local rec = cjson.decode(current)
+if rec.id ~= ARGV[2] or rec.state ~= 'IN_PROGRESS' then
+	return 0
+end
+if ARGV[1] == '' then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[1], 'KEEPTTL')
+end
+return 1
+`)
+
+func (s *redisStore) Complete(ctx context.Context, record *Record) error {
+	completed := *record
+	completed.State = StateCompleted
+	data, err := json.Marshal(toRedisRecord(&completed))
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	replaced, err := replaceScript.Run(ctx, s.client, []string{s.key(record.MerchantID, record.IdempotencyKey)}, data, record.ID).Int()
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
+	}
+	if replaced == 0 {
+		return ErrReservationLost
+	}
+
+	record.State = StateCompleted
+	return nil
+}
+
+func (s *redisStore) Release(ctx context.Context, record *Record) error {
+	err := replaceScript.Run(ctx, s.client, []string{s.key(record.MerchantID, record.IdempotencyKey)}, "", record.ID).Err()
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency record: %w", err)
+	}
+	return nil
+}
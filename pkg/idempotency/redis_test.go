@@ -0,0 +1,63 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency/idempotencytest"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestRedisStore(t *testing.T) {
+	idempotencytest.RunStoreTests(t, func(t *testing.T) idempotency.Store {
+		_, client := newRedis(t)
+		return idempotency.NewRedisStore(client)
+	})
+}
+
+func TestRedisStore_TTL(t *testing.T) {
+	mr, client := newRedis(t)
+	store := idempotency.NewRedisStore(client)
+	ctx := context.Background()
+
+	now := time.Now()
+	record := &idempotency.Record{
+		ID:             "idem_1",
+		MerchantID:     "merchant_1",
+		IdempotencyKey: "key_1",
+		RequestHash:    "hash_1",
+		LockedAt:       now,
+		ExpiresAt:      now.Add(time.Hour),
+	}
+	if holder, err := store.Reserve(ctx, record, time.Minute); holder != nil || err != nil {
+		t.Fatalf("Failed to reserve: %+v, %v", holder, err)
+	}
+	if err := store.Complete(ctx, record); err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+
+	keys := mr.Keys()
+	if len(keys) != 1 {
+		t.Fatalf("Expected one key, got %v", keys)
+	}
+	if ttl := mr.TTL(keys[0]); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("Expected completion to keep the reservation TTL, got %v", ttl)
+	}
+
+	mr.FastForward(time.Hour)
+	if got, err := store.Get(ctx, "merchant_1", "key_1"); err != nil || got != nil {
+		t.Errorf("Expected record to expire with its TTL, got %+v, %v", got, err)
+	}
+}
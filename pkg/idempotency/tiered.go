@@ -0,0 +1,59 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// tieredStore puts a fast cache, normally Redis, in front of a durable
+// primary store, normally Postgres. Retries of requests already seen are
+// answered from the cache; new reservations and completed responses are
+// written through to the primary, which stays the source of truth. When the
+// cache fails, the store falls back to the primary alone.
+type tieredStore struct {
+	cache   Store
+	primary Store
+}
+
+func NewTieredStore(cache, primary Store) Store {
+	return &tieredStore{cache: cache, primary: primary}
+}
+
+func (s *tieredStore) Get(ctx context.Context, merchantID, key string) (*Record, error) {
+	if record, err := s.cache.Get(ctx, merchantID, key); err == nil && record != nil {
+		return record, nil
+	}
+	return s.primary.Get(ctx, merchantID, key)
+}
+
+func (s *tieredStore) Reserve(ctx context.Context, record *Record, lockTimeout time.Duration) (*Record, error) {
+	holder, err := s.cache.Reserve(ctx, record, lockTimeout)
+	if err == nil && holder != nil {
+		return holder, nil
+	}
+	cached := err == nil
+
+	// The cache may have lost a record (eviction, restart) that the primary
+	// still holds, so the primary has the final say on every new reservation.
+	holder, err = s.primary.Reserve(ctx, record, lockTimeout)
+	if cached && (err != nil || holder != nil) {
+		s.cache.Release(ctx, record)
+	}
+	return holder, err
+}
+
+// Complete and Release treat the cache as best effort: a cache entry left
+// IN_PROGRESS is taken over once it goes stale, and the primary then answers
+// for the key.
+func (s *tieredStore) Complete(ctx context.Context, record *Record) error {
+	if err := s.primary.Complete(ctx, record); err != nil {
+		return err
+	}
+	s.cache.Complete(ctx, record)
+	return nil
+}
+
+func (s *tieredStore) Release(ctx context.Context, record *Record) error {
+	s.cache.Release(ctx, record)
+	return s.primary.Release(ctx, record)
+}
@@ -0,0 +1,74 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency"
+	"github.com/thilakshekharshriyan/playflow/pkg/idempotency/idempotencytest"
+)
+
+func TestTieredStore(t *testing.T) {
+	idempotencytest.RunStoreTests(t, func(t *testing.T) idempotency.Store {
+		_, client := newRedis(t)
+		return idempotency.NewTieredStore(idempotency.NewRedisStore(client), idempotency.NewMemoryStore())
+	})
+}
+
+func TestTieredStore_Fallback(t *testing.T) {
+	ctx := context.Background()
+	newRecord := func(id string) *idempotency.Record {
+		now := time.Now()
+		return &idempotency.Record{
+			ID:             id,
+			MerchantID:     "merchant_1",
+			IdempotencyKey: "key_1",
+			RequestHash:    "hash_1",
+			LockedAt:       now,
+			ExpiresAt:      now.Add(time.Hour),
+		}
+	}
+
+	t.Run("cache lost the record", func(t *testing.T) {
+		mr, client := newRedis(t)
+		primary := idempotency.NewMemoryStore()
+		store := idempotency.NewTieredStore(idempotency.NewRedisStore(client), primary)
+
+		record := newRecord("idem_1")
+		if holder, err := store.Reserve(ctx, record, time.Minute); holder != nil || err != nil {
+			t.Fatalf("Failed to reserve: %+v, %v", holder, err)
+		}
+		record.StatusCode = 201
+		if err := store.Complete(ctx, record); err != nil {
+			t.Fatalf("Failed to complete: %v", err)
+		}
+
+		mr.FlushAll()
+		holder, err := store.Reserve(ctx, newRecord("idem_2"), time.Minute)
+		if err != nil || holder == nil || holder.ID != "idem_1" || holder.StatusCode != 201 {
+			t.Fatalf("Expected primary to answer for the key, got %+v, %v", holder, err)
+		}
+		if len(mr.Keys()) != 0 {
+			t.Errorf("Expected the cache reservation to be released, got %v", mr.Keys())
+		}
+	})
+
+	t.Run("cache unavailable", func(t *testing.T) {
+		mr, client := newRedis(t)
+		primary := idempotency.NewMemoryStore()
+		store := idempotency.NewTieredStore(idempotency.NewRedisStore(client), primary)
+		mr.Close()
+
+		record := newRecord("idem_1")
+		if holder, err := store.Reserve(ctx, record, time.Minute); holder != nil || err != nil {
+			t.Fatalf("Expected reservation to fall back to the primary, got %+v, %v", holder, err)
+		}
+		if err := store.Complete(ctx, record); err != nil {
+			t.Fatalf("Failed to complete: %v", err)
+		}
+		if got, err := store.Get(ctx, "merchant_1", "key_1"); err != nil || got == nil || got.State != idempotency.StateCompleted {
+			t.Errorf("Expected completed record from the primary, got %+v, %v", got, err)
+		}
+	})
+}